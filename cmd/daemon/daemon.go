@@ -0,0 +1,67 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/skycoin/hardware-wallet-go/src/daemon"
+	skyWallet "github.com/skycoin/hardware-wallet-go/src/skywallet"
+)
+
+func main() {
+	addr := flag.String("addr", "127.0.0.1:9510", "Address to listen on, keep it on localhost.")
+	deviceType := flag.String("deviceType", os.Getenv("DEVICE_TYPE"), "Device type to send instructions to, hardware wallet (USB) or emulator.")
+	device := flag.String("device", os.Getenv("DEVICE"), "Path, device ID or label of the wallet to use when several are attached.")
+	pendingTimeout := flag.Duration("pendingTimeout", daemon.DefaultPendingTimeout, "Time a pending request waits for an answer before the operation is cancelled.")
+	token := flag.String("token", os.Getenv("SKYCOIN_HW_DAEMON_TOKEN"), "Token required as \"Authorization: Bearer <token>\", a random one is printed if empty.")
+	allowedOrigins := flag.String("allowedOrigins", "", "Comma separated origins of the web pages allowed to use the api.")
+	allowedHosts := flag.String("allowedHosts", "", "Comma separated host names accepted besides localhost.")
+	flag.Parse()
+
+	if *token == "" {
+		b := make([]byte, 16)
+		if _, err := rand.Read(b); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		*token = hex.EncodeToString(b)
+		fmt.Printf("Token: %s\n", *token)
+	}
+
+	var options []skyWallet.Option
+	if *device != "" {
+		options = append(options, skyWallet.WithDevice(*device))
+	}
+
+	dev := skyWallet.NewDevice(skyWallet.DeviceTypeFromString(*deviceType), options...)
+	defer dev.Close()
+
+	server := daemon.New(dev, daemon.Config{
+		PendingTimeout: *pendingTimeout,
+		AllowedHosts:   splitList(*allowedHosts),
+		AllowedOrigins: splitList(*allowedOrigins),
+		Token:          *token,
+	})
+
+	fmt.Printf("Listening on http://%s\n", *addr)
+	if err := http.ListenAndServe(*addr, server.Handler()); err != nil {
+		fmt.Println(err)
+		dev.Close()
+		os.Exit(1)
+	}
+}
+
+func splitList(list string) []string {
+	var values []string
+	for _, v := range strings.Split(list, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
@@ -1,12 +1,9 @@
 package cli
 
 import (
-	"fmt"
 	"os"
 	"runtime"
 
-	messages "github.com/skycoin/hardware-wallet-protob/go"
-
 	gcli "github.com/urfave/cli"
 
 	skyWallet "github.com/skycoin/hardware-wallet-go/src/skywallet"
@@ -40,84 +37,44 @@ func addressGenCmd() gcli.Command {
 			},
 		},
 		OnUsageError: onCommandUsageError(name),
-		Action: func(c *gcli.Context) {
+		Action: func(c *gcli.Context) error {
 			addressN := c.Int("addressN")
 			startIndex := c.Int("startIndex")
 			confirmAddress := c.Bool("confirmAddress")
 
-			device := skyWallet.NewDevice(skyWallet.DeviceTypeFromString(c.String("deviceType")))
+			device := skyWallet.NewDevice(skyWallet.DeviceTypeFromString(c.String("deviceType")), deviceOptions(c)...)
 			if device == nil {
-				return
+				return nil
 			}
 			defer device.Close()
 
+			setupVerify(c, device)
+
 			if os.Getenv("AUTO_PRESS_BUTTONS") == "1" && device.Driver.DeviceType() == skyWallet.DeviceTypeEmulator && runtime.GOOS == "linux" {
 				err := device.SetAutoPressButton(true, skyWallet.ButtonRight)
 				if err != nil {
-					log.Error(err)
-					return
+					return cliError(err)
 				}
 			}
 
-			var pinEnc string
 			msg, err := device.AddressGen(uint32(addressN), uint32(startIndex), confirmAddress)
 			if err != nil {
-				log.Error(err)
-				return
+				return cliError(err)
 			}
 
-			for msg.Kind != uint16(messages.MessageType_MessageType_ResponseSkycoinAddress) && msg.Kind != uint16(messages.MessageType_MessageType_Failure) {
-				if msg.Kind == uint16(messages.MessageType_MessageType_PinMatrixRequest) {
-					fmt.Printf("PinMatrixRequest response: ")
-					fmt.Scanln(&pinEnc)
-					pinAckResponse, err := device.PinMatrixAck(pinEnc)
-					if err != nil {
-						log.Error(err)
-						return
-					}
-					log.Infof("PinMatrixAck response: %s", pinAckResponse)
-					continue
-				}
-
-				if msg.Kind == uint16(messages.MessageType_MessageType_PassphraseRequest) {
-					var passphrase string
-					fmt.Printf("Input passphrase: ")
-					fmt.Scanln(&passphrase)
-					passphraseAckResponse, err := device.PassphraseAck(passphrase)
-					if err != nil {
-						log.Error(err)
-						return
-					}
-					log.Infof("PinMatrixAck response: %s", passphraseAckResponse)
-					continue
-				}
-
-				if msg.Kind == uint16(messages.MessageType_MessageType_ButtonRequest) {
-					msg, err = device.ButtonAck()
-					if err != nil {
-						log.Error(err)
-						return
-					}
-					continue
-				}
+			msg, err = skyWallet.RunFlow(device, msg, newPrompter(c))
+			if err != nil {
+				return cliError(err)
 			}
 
-			if msg.Kind == uint16(messages.MessageType_MessageType_ResponseSkycoinAddress) {
-				addresses, err := skyWallet.DecodeResponseSkycoinAddress(msg)
-				if err != nil {
-					log.Error(err)
-					return
-				}
-				fmt.Println(addresses)
-			} else {
-				failMsg, err := skyWallet.DecodeFailMsg(msg)
-				if err != nil {
-					log.Error(err)
-					return
-				}
-				fmt.Println("Failed with code: ", failMsg)
-				return
+			addresses, err := skyWallet.DecodeResponseSkycoinAddress(msg)
+			if err != nil {
+				return cliError(err)
 			}
+
+			return printResult(c, addressesResult{
+				Addresses: addresses,
+			}, addresses)
 		},
 	}
 }
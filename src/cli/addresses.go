@@ -0,0 +1,199 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	gcli "github.com/urfave/cli"
+
+	skyWallet "github.com/skycoin/hardware-wallet-go/src/skywallet"
+)
+
+// addressCacheResult is a wallet of the addresses sync and list commands result
+type addressCacheResult struct {
+	Name string `json:"name"`
+	*skyWallet.AddressCache
+}
+
+func addressesCmd() gcli.Command {
+	name := "addresses"
+	return gcli.Command{
+		Name:        name,
+		Usage:       "Keep a watch-only cache of the wallet addresses",
+		Description: "",
+		Subcommands: []gcli.Command{
+			addressesSyncCmd(),
+			addressesListCmd(),
+		},
+		OnUsageError: onCommandUsageError(name),
+	}
+}
+
+func addressesSyncCmd() gcli.Command {
+	name := "sync"
+	return gcli.Command{
+		Name:        name,
+		Usage:       "Derive the wallet addresses missing in the cache",
+		Description: "With --gapLimit the addresses are scanned until gapLimit consecutive addresses have no transactions in the node given by --node.",
+		Flags: []gcli.Flag{
+			gcli.IntFlag{
+				Name:  "addressN",
+				Value: skyWallet.DefaultAddressBatchSize,
+				Usage: "Number of addresses to keep in the cache.",
+			},
+			gcli.IntFlag{
+				Name:  "gapLimit",
+				Usage: "Discover the used addresses, stopping after gapLimit unused addresses.",
+			},
+			gcli.StringFlag{
+				Name:   "node",
+				Usage:  "Skycoin node api url used to check the history of the addresses, e.g. http://127.0.0.1:6420",
+				EnvVar: "SKYCOIN_NODE",
+			},
+			cacheDirFlag(),
+			gcli.StringFlag{
+				Name:   "deviceType",
+				Usage:  "Device type to send instructions to, hardware wallet (USB) or emulator.",
+				EnvVar: "DEVICE_TYPE",
+			},
+		},
+		OnUsageError: onCommandUsageError(name),
+		Action: func(c *gcli.Context) error {
+			addressN := c.Int("addressN")
+			gapLimit := c.Int("gapLimit")
+			node := c.String("node")
+			if addressN < 0 || gapLimit < 0 {
+				return cliError(errors.New("addressN and gapLimit should not be negative"))
+			}
+			if gapLimit > 0 && node == "" {
+				return cliError(errors.New("--node is required to discover the used addresses"))
+			}
+
+			device := skyWallet.NewDevice(skyWallet.DeviceTypeFromString(c.String("deviceType")), deviceOptions(c)...)
+			if device == nil {
+				return nil
+			}
+			defer device.Close()
+
+			setupVerify(c, device)
+
+			device.SetPrompter(newPrompter(c))
+			session, err := device.OpenSession()
+			if err != nil {
+				return cliError(err)
+			}
+			defer session.Close()
+
+			book := skyWallet.NewAddressBook(c.String("cacheDir"))
+			cache, err := book.Sync(context.Background(), session, uint32(addressN))
+			if err != nil {
+				return cliError(err)
+			}
+
+			if gapLimit > 0 {
+				cache, err = book.Discover(context.Background(), session, uint32(gapLimit), nodeHistory(node))
+				if err != nil {
+					return cliError(err)
+				}
+			}
+
+			return printResult(c, addressCacheResult{
+				Name:         cache.Name(),
+				AddressCache: cache,
+			}, cache.Addresses)
+		},
+	}
+}
+
+func addressesListCmd() gcli.Command {
+	name := "list"
+	return gcli.Command{
+		Name:        name,
+		Usage:       "List the cached addresses without connecting to the device",
+		Description: "",
+		Flags: []gcli.Flag{
+			gcli.StringFlag{
+				Name:  "deviceID",
+				Usage: "List the wallets of the device with this ID only.",
+			},
+			cacheDirFlag(),
+		},
+		OnUsageError: onCommandUsageError(name),
+		Action: func(c *gcli.Context) error {
+			book := skyWallet.NewAddressBook(c.String("cacheDir"))
+			caches, err := book.List(c.String("deviceID"))
+			if err != nil {
+				return cliError(err)
+			}
+
+			results := make([]addressCacheResult, len(caches))
+			var text strings.Builder
+			for i, cache := range caches {
+				results[i] = addressCacheResult{
+					Name:         cache.Name(),
+					AddressCache: cache,
+				}
+				if i > 0 {
+					text.WriteString("\n")
+				}
+				fmt.Fprintf(&text, "%s (%d addresses, %d used)\n", cache.Name(), len(cache.Addresses), cache.Used)
+				for _, address := range cache.Addresses {
+					fmt.Fprintln(&text, address)
+				}
+			}
+
+			return printResult(c, results, strings.TrimSuffix(text.String(), "\n"))
+		},
+	}
+}
+
+func cacheDirFlag() gcli.StringFlag {
+	return gcli.StringFlag{
+		Name:   "cacheDir",
+		Value:  defaultAddressCacheDir(),
+		Usage:  "Directory of the address cache.",
+		EnvVar: "ADDRESS_CACHE_DIR",
+	}
+}
+
+func defaultAddressCacheDir() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = "."
+	}
+	return filepath.Join(dir, "skycoin-hw-wallet", "addresses")
+}
+
+// nodeHistory returns a callback reporting whether an address has transactions in the skycoin node at node
+func nodeHistory(node string) func(cipher.Address) (bool, error) {
+	client := &http.Client{
+		Timeout: 30 * time.Second,
+	}
+
+	return func(address cipher.Address) (bool, error) {
+		resp, err := client.Get(strings.TrimSuffix(node, "/") + "/api/v1/transactions?addrs=" + url.QueryEscape(address.String()))
+		if err != nil {
+			return false, err
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			return false, fmt.Errorf("node returned %s for address %s", resp.Status, address)
+		}
+
+		var transactions []json.RawMessage
+		if err := json.NewDecoder(resp.Body).Decode(&transactions); err != nil {
+			return false, err
+		}
+		return len(transactions) > 0, nil
+	}
+}
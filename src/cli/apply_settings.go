@@ -1,14 +1,11 @@
 package cli
 
 import (
-	"fmt"
 	"os"
 	"runtime"
 
 	gcli "github.com/urfave/cli"
 
-	messages "github.com/skycoin/hardware-wallet-protob/go"
-
 	skyWallet "github.com/skycoin/hardware-wallet-go/src/skywallet"
 )
 
@@ -39,79 +36,46 @@ func applySettingsCmd() gcli.Command {
 			},
 		},
 		OnUsageError: onCommandUsageError(name),
-		Action: func(c *gcli.Context) {
+		Action: func(c *gcli.Context) error {
 			passphrase := c.String("usePassphrase")
 			label := c.String("label")
 			language := c.String("language")
 
-			device := skyWallet.NewDevice(skyWallet.DeviceTypeFromString(c.String("deviceType")))
+			device := skyWallet.NewDevice(skyWallet.DeviceTypeFromString(c.String("deviceType")), deviceOptions(c)...)
 			if device == nil {
-				return
+				return nil
 			}
 			defer device.Close()
 
 			if os.Getenv("AUTO_PRESS_BUTTONS") == "1" && device.Driver.DeviceType() == skyWallet.DeviceTypeEmulator && runtime.GOOS == "linux" {
 				err := device.SetAutoPressButton(true, skyWallet.ButtonRight)
 				if err != nil {
-					log.Error(err)
-					return
+					return cliError(err)
 				}
 			}
 
 			usePassphrase, _err := parseBool(passphrase)
 			if _err != nil {
-				log.Errorln("Valid values for usePassphrase are true or false")
-				return
+				return gcli.NewExitError("Valid values for usePassphrase are true or false", exitCodeError)
 			}
 			msg, err := device.ApplySettings(usePassphrase, label, language)
 			if err != nil {
-				log.Error(err)
-				return
+				return cliError(err)
 			}
 
-			for msg.Kind != uint16(messages.MessageType_MessageType_Failure) && msg.Kind != uint16(messages.MessageType_MessageType_Success) {
-				if msg.Kind == uint16(messages.MessageType_MessageType_ButtonRequest) {
-					msg, err = device.ButtonAck()
-					if err != nil {
-						log.Error(err)
-						return
-					}
-					continue
-				}
-
-				if msg.Kind == uint16(messages.MessageType_MessageType_PinMatrixRequest) {
-					var pinEnc string
-					fmt.Printf("PinMatrixRequest response: ")
-					fmt.Scanln(&pinEnc)
-					pinAckResponse, err := device.PinMatrixAck(pinEnc)
-					if err != nil {
-						log.Error(err)
-						return
-					}
-					log.Infof("PinMatrixAck response: %s", pinAckResponse)
-					continue
-				}
+			msg, err = skyWallet.RunFlow(device, msg, newPrompter(c))
+			if err != nil {
+				return cliError(err)
 			}
 
-			if msg.Kind == uint16(messages.MessageType_MessageType_Failure) {
-				failMsg, err := skyWallet.DecodeFailMsg(msg)
-				if err != nil {
-					log.Error(err)
-					return
-				}
-				fmt.Println(failMsg)
-				return
+			successMsg, err := skyWallet.DecodeSuccessMsg(msg)
+			if err != nil {
+				return cliError(err)
 			}
 
-			if msg.Kind == uint16(messages.MessageType_MessageType_Success) {
-				successMsg, err := skyWallet.DecodeSuccessMsg(msg)
-				if err != nil {
-					log.Error(err)
-					return
-				}
-				fmt.Println(successMsg)
-				return
-			}
+			return printResult(c, messageResult{
+				Message: successMsg,
+			}, successMsg)
 		},
 	}
 }
@@ -1,14 +1,11 @@
 package cli
 
 import (
-	"fmt"
 	"os"
 	"runtime"
 
 	gcli "github.com/urfave/cli"
 
-	messages "github.com/skycoin/hardware-wallet-protob/go"
-
 	skyWallet "github.com/skycoin/hardware-wallet-go/src/skywallet"
 )
 
@@ -26,61 +23,38 @@ func backupCmd() gcli.Command {
 				EnvVar: "DEVICE_TYPE",
 			},
 		},
-		Action: func(c *gcli.Context) {
-			device := skyWallet.NewDevice(skyWallet.DeviceTypeFromString(c.String("deviceType")))
+		Action: func(c *gcli.Context) error {
+			device := skyWallet.NewDevice(skyWallet.DeviceTypeFromString(c.String("deviceType")), deviceOptions(c)...)
 			if device == nil {
-				return
+				return nil
 			}
 			defer device.Close()
 
 			if os.Getenv("AUTO_PRESS_BUTTONS") == "1" && device.Driver.DeviceType() == skyWallet.DeviceTypeEmulator && runtime.GOOS == "linux" {
 				err := device.SetAutoPressButton(true, skyWallet.ButtonRight)
 				if err != nil {
-					log.Error(err)
-					return
+					return cliError(err)
 				}
 			}
 
 			msg, err := device.Backup()
 			if err != nil {
-				log.Error(err)
-				return
-			}
-
-			if msg.Kind == uint16(messages.MessageType_MessageType_PinMatrixRequest) {
-				var pinEnc string
-				fmt.Printf("PinMatrixRequest response: ")
-				fmt.Scanln(&pinEnc)
-				msg, err := device.PinMatrixAck(pinEnc)
-				if err != nil {
-					log.Error(err)
-					return
-				}
-
-				for msg.Kind == uint16(messages.MessageType_MessageType_ButtonRequest) {
-					msg, err = device.ButtonAck()
-					if err != nil {
-						log.Error(err)
-						return
-					}
-				}
+				return cliError(err)
 			}
 
-			for msg.Kind == uint16(messages.MessageType_MessageType_ButtonRequest) {
-				msg, err = device.ButtonAck()
-				if err != nil {
-					log.Error(err)
-					return
-				}
+			msg, err = skyWallet.RunFlow(device, msg, newPrompter(c))
+			if err != nil {
+				return cliError(err)
 			}
 
-			responseMsg, err := skyWallet.DecodeSuccessOrFailMsg(msg)
+			responseMsg, err := skyWallet.DecodeSuccessMsg(msg)
 			if err != nil {
-				log.Error(err)
-				return
+				return cliError(err)
 			}
 
-			fmt.Println(responseMsg)
+			return printResult(c, messageResult{
+				Message: responseMsg,
+			}, responseMsg)
 		},
 	}
 }
@@ -1,7 +1,6 @@
 package cli
 
 import (
-	"fmt"
 	"os"
 	"runtime"
 
@@ -24,34 +23,33 @@ func cancelCmd() gcli.Command {
 				EnvVar: "DEVICE_TYPE",
 			},
 		},
-		Action: func(c *gcli.Context) {
-			device := skyWallet.NewDevice(skyWallet.DeviceTypeFromString(c.String("deviceType")))
+		Action: func(c *gcli.Context) error {
+			device := skyWallet.NewDevice(skyWallet.DeviceTypeFromString(c.String("deviceType")), deviceOptions(c)...)
 			if device == nil {
-				return
+				return nil
 			}
 			defer device.Close()
 
 			if os.Getenv("AUTO_PRESS_BUTTONS") == "1" && device.Driver.DeviceType() == skyWallet.DeviceTypeEmulator && runtime.GOOS == "linux" {
 				err := device.SetAutoPressButton(true, skyWallet.ButtonRight)
 				if err != nil {
-					log.Error(err)
-					return
+					return cliError(err)
 				}
 			}
 
 			msg, err := device.Cancel()
 			if err != nil {
-				log.Error(err)
-				return
+				return cliError(err)
 			}
 
 			responseMsg, err := skyWallet.DecodeSuccessOrFailMsg(msg)
 			if err != nil {
-				log.Error(err)
-				return
+				return cliError(err)
 			}
 
-			fmt.Println(responseMsg)
+			return printResult(c, messageResult{
+				Message: responseMsg,
+			}, responseMsg)
 		},
 	}
 }
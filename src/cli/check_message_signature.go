@@ -1,7 +1,6 @@
 package cli
 
 import (
-	"fmt"
 	"os"
 	"runtime"
 
@@ -36,38 +35,37 @@ func checkMessageSignatureCmd() gcli.Command {
 			},
 		},
 		OnUsageError: onCommandUsageError(name),
-		Action: func(c *gcli.Context) {
+		Action: func(c *gcli.Context) error {
 			message := c.String("message")
 			signature := c.String("signature")
 			address := c.String("address")
 
-			device := skyWallet.NewDevice(skyWallet.DeviceTypeFromString(c.String("deviceType")))
+			device := skyWallet.NewDevice(skyWallet.DeviceTypeFromString(c.String("deviceType")), deviceOptions(c)...)
 			if device == nil {
-				return
+				return nil
 			}
 			defer device.Close()
 
 			if os.Getenv("AUTO_PRESS_BUTTONS") == "1" && device.Driver.DeviceType() == skyWallet.DeviceTypeEmulator && runtime.GOOS == "linux" {
 				err := device.SetAutoPressButton(true, skyWallet.ButtonRight)
 				if err != nil {
-					log.Error(err)
-					return
+					return cliError(err)
 				}
 			}
 
 			msg, err := device.CheckMessageSignature(message, signature, address)
 			if err != nil {
-				log.Error(err)
-				return
+				return cliError(err)
 			}
 
-			responseMsg, err := skyWallet.DecodeSuccessOrFailMsg(msg)
+			responseMsg, err := skyWallet.DecodeSuccessMsg(msg)
 			if err != nil {
-				log.Error(err)
-				return
+				return cliError(err)
 			}
 
-			fmt.Println(responseMsg)
+			return printResult(c, messageResult{
+				Message: responseMsg,
+			}, responseMsg)
 		},
 	}
 }
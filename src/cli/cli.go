@@ -85,6 +85,7 @@ func NewApp() (*App, error) {
 		featuresCmd(),
 		generateMnemonicCmd(),
 		addressGenCmd(),
+		addressesCmd(),
 		firmwareUpdate(),
 		signMessageCmd(),
 		checkMessageSignatureCmd(),
@@ -98,12 +99,62 @@ func NewApp() (*App, error) {
 		getRawEntropyCmd(),
 		getMixedEntropyCmd(),
 		getUsbDetails(),
+		debugCmd(),
+	}
+
+	for i := range commands {
+		commands[i].Action = jsonErrors(commands[i].Action)
+		for j := range commands[i].Subcommands {
+			commands[i].Subcommands[j].Action = jsonErrors(commands[i].Subcommands[j].Action)
+		}
 	}
 
 	app.Name = "skycoin-hw-cli"
 	app.Version = Version
 	app.Usage = "the skycoin hardware wallet command line interface"
 	app.Commands = commands
+	app.Flags = []gcli.Flag{
+		gcli.StringFlag{
+			Name:   "device",
+			Usage:  "Path, device ID or label of the wallet to use when several are attached.",
+			EnvVar: "DEVICE",
+		},
+		gcli.BoolFlag{
+			Name:  "json",
+			Usage: "Print the command result or error as a json document, logs are sent to stderr.",
+		},
+		gcli.StringFlag{
+			Name:   "capture",
+			Usage:  "Append the usb reports exchanged with the wallet to this file, to report an issue.",
+			EnvVar: "CAPTURE",
+		},
+		gcli.StringFlag{
+			Name:   "entropySource",
+			Usage:  "File the host entropy requested by the wallet is read from, e.g. /dev/hwrng, instead of the operating system.",
+			EnvVar: "ENTROPY_SOURCE",
+		},
+		gcli.BoolFlag{
+			Name:  "verify",
+			Usage: "Verify the addresses and signatures returned by the wallet before printing them.",
+		},
+		gcli.StringFlag{
+			Name:  "expectedAddresses",
+			Usage: "File holding the wallet addresses from index 0 trusted by --verify, one per line. The wallet is asked for the addresses not listed.",
+		},
+	}
+	app.Before = func(c *gcli.Context) error {
+		if err := setupOutput(c); err != nil {
+			return err
+		}
+		if err := openCapture(c); err != nil {
+			return err
+		}
+		if err := readExpectedAddresses(c); err != nil {
+			return err
+		}
+		return openEntropySource(c)
+	}
+	app.After = closeFiles
 	app.EnableBashCompletion = true
 	app.OnUsageError = func(context *gcli.Context, err error, _ bool) error {
 		fmt.Fprintf(context.App.Writer, "Error: %v\n\n", err)
@@ -0,0 +1,300 @@
+package cli
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strings"
+	"time"
+	"unicode"
+
+	gcli "github.com/urfave/cli"
+
+	messages "github.com/skycoin/hardware-wallet-protob/go"
+
+	skyWallet "github.com/skycoin/hardware-wallet-go/src/skywallet"
+	"github.com/skycoin/hardware-wallet-go/src/skywallet/capture"
+	"github.com/skycoin/hardware-wallet-go/src/skywallet/wire"
+)
+
+// decodedMessage is an item of the debug decode command result
+type decodedMessage struct {
+	Time      *time.Time        `json:"time,omitempty"`
+	Direction capture.Direction `json:"direction,omitempty"`
+	Kind      string            `json:"kind"`
+	// Fields are the protobuf fields of the message by name, secrets are redacted
+	Fields map[string]interface{} `json:"fields,omitempty"`
+	// Error tells why the message could not be decoded
+	Error string `json:"error,omitempty"`
+}
+
+func debugCmd() gcli.Command {
+	name := "debug"
+	return gcli.Command{
+		Name:        name,
+		Usage:       "Protocol debugging tools",
+		Description: "",
+		Subcommands: []gcli.Command{
+			debugDecodeCmd(),
+		},
+		OnUsageError: onCommandUsageError(name),
+	}
+}
+
+func debugDecodeCmd() gcli.Command {
+	name := "decode"
+	return gcli.Command{
+		Name:        name,
+		Usage:       "Decode the messages of hex encoded usb reports or of a capture file",
+		ArgsUsage:   "[hex...]",
+		Description: "The reports are read from the arguments, or from stdin if there are none. PINs, passphrases and mnemonic words are redacted.",
+		Flags: []gcli.Flag{
+			gcli.StringFlag{
+				Name:  "f, file",
+				Usage: "Capture file recorded with the global --capture option.",
+			},
+		},
+		OnUsageError: onCommandUsageError(name),
+		Action: func(c *gcli.Context) error {
+			var decoded []decodedMessage
+			if path := c.String("file"); path != "" {
+				f, err := os.Open(path)
+				if err != nil {
+					return cliError(err)
+				}
+				defer f.Close()
+
+				reports, err := capture.Load(f)
+				if err != nil {
+					return cliError(err)
+				}
+
+				decoded, err = decodeCapture(reports)
+				if err != nil {
+					return cliError(err)
+				}
+			} else {
+				input := strings.Join(c.Args(), "")
+				if input == "" {
+					data, err := ioutil.ReadAll(os.Stdin)
+					if err != nil {
+						return cliError(err)
+					}
+					input = string(data)
+				}
+
+				data, err := hex.DecodeString(strings.Map(func(r rune) rune {
+					if unicode.IsSpace(r) {
+						return -1
+					}
+					return r
+				}, input))
+				if err != nil {
+					return cliError(err)
+				}
+
+				decoded, err = decodeReports(data)
+				if err != nil {
+					return cliError(err)
+				}
+			}
+
+			var text strings.Builder
+			for i, msg := range decoded {
+				if i > 0 {
+					text.WriteString("\n")
+				}
+				if msg.Time != nil {
+					fmt.Fprintf(&text, "%s %s ", msg.Time.Format(time.RFC3339Nano), msg.Direction)
+				}
+				text.WriteString(msg.Kind)
+				if msg.Error != "" {
+					fmt.Fprintf(&text, ": %s", msg.Error)
+				}
+				text.WriteString("\n")
+				if len(msg.Fields) > 0 {
+					fields, err := json.MarshalIndent(msg.Fields, "", "    ")
+					if err != nil {
+						return cliError(err)
+					}
+					fmt.Fprintf(&text, "%s\n", fields)
+				}
+			}
+
+			return printResult(c, decoded, strings.TrimSuffix(text.String(), "\n"))
+		},
+	}
+}
+
+// decodeReports decodes the messages of a stream of 64 bytes reports
+func decodeReports(data []byte) ([]decodedMessage, error) {
+	var reports [][]byte
+	for len(data) > 0 {
+		n := 64
+		if len(data) < n {
+			n = len(data)
+		}
+		reports = append(reports, data[:n])
+		data = data[n:]
+	}
+
+	var decoded []decodedMessage
+	for i, report := range reports {
+		if capture.Kind(report) == "" {
+			continue
+		}
+
+		msg, err := readMessage(reports[i:])
+		if err != nil {
+			return nil, fmt.Errorf("report %d: %v", i, err)
+		}
+		decoded = append(decoded, decodeMessage(*msg))
+	}
+
+	if len(decoded) == 0 {
+		return nil, errors.New("no message found, messages start with a ?## report")
+	}
+	return decoded, nil
+}
+
+// decodeCapture decodes the messages of a capture, each message is reassembled
+// from the reports of its direction following its first report
+func decodeCapture(reports []capture.Report) ([]decodedMessage, error) {
+	streams := make(map[capture.Direction][][]byte)
+	// position of each report in the stream of its direction
+	positions := make([]int, len(reports))
+	for i, report := range reports {
+		data, err := report.Bytes()
+		if err != nil {
+			return nil, err
+		}
+		positions[i] = len(streams[report.Direction])
+		streams[report.Direction] = append(streams[report.Direction], data)
+	}
+
+	var decoded []decodedMessage
+	for i, report := range reports {
+		if report.Kind == "" {
+			continue
+		}
+
+		msg, err := readMessage(streams[report.Direction][positions[i]:])
+		if err != nil {
+			return nil, fmt.Errorf("capture report %d: %v", i, err)
+		}
+
+		d := decodeMessage(*msg)
+		d.Time = &reports[i].Time
+		d.Direction = report.Direction
+		decoded = append(decoded, d)
+	}
+	return decoded, nil
+}
+
+// readMessage reassembles the message starting at the first report
+func readMessage(reports [][]byte) (*wire.Message, error) {
+	msg, err := wire.ReadFrom(&reportReader{
+		reports: reports,
+	})
+	if err == io.EOF {
+		return nil, errors.New("truncated message")
+	}
+	return msg, err
+}
+
+// reportReader returns a report per Read
+type reportReader struct {
+	reports [][]byte
+}
+
+func (r *reportReader) Read(p []byte) (int, error) {
+	if len(r.reports) == 0 {
+		return 0, io.EOF
+	}
+	n := copy(p, r.reports[0])
+	r.reports = r.reports[1:]
+	return n, nil
+}
+
+// decodeMessage decodes the fields of msg and redacts its secrets
+func decodeMessage(msg wire.Message) decodedMessage {
+	kind := messages.MessageType(msg.Kind)
+	decoded := decodedMessage{
+		Kind: kind.String(),
+	}
+
+	m, err := skyWallet.DecodeMessage(msg)
+	if err != nil {
+		decoded.Error = err.Error()
+		return decoded
+	}
+
+	decoded.Fields = protoFields(reflect.ValueOf(m).Elem())
+	for _, name := range skyWallet.SecretFields(kind) {
+		if _, ok := decoded.Fields[name]; ok {
+			decoded.Fields[name] = skyWallet.Redacted
+		}
+	}
+	return decoded
+}
+
+// protoFields returns the set fields of a protobuf message struct by protobuf name
+func protoFields(v reflect.Value) map[string]interface{} {
+	fields := make(map[string]interface{})
+	for i := 0; i < v.NumField(); i++ {
+		tag := v.Type().Field(i).Tag.Get("protobuf")
+		if tag == "" {
+			continue
+		}
+
+		f := v.Field(i)
+		if (f.Kind() == reflect.Ptr || f.Kind() == reflect.Slice) && f.IsNil() {
+			continue
+		}
+		fields[protoName(tag)] = protoValue(f)
+	}
+	return fields
+}
+
+// protoValue converts a protobuf field value, bytes are hex encoded and enums are named
+func protoValue(v reflect.Value) interface{} {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			return nil
+		}
+		if v.Elem().Kind() == reflect.Struct {
+			return protoFields(v.Elem())
+		}
+		return protoValue(v.Elem())
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return hex.EncodeToString(v.Bytes())
+		}
+		items := make([]interface{}, v.Len())
+		for i := range items {
+			items[i] = protoValue(v.Index(i))
+		}
+		return items
+	case reflect.Int32:
+		if enum, ok := v.Interface().(fmt.Stringer); ok {
+			return enum.String()
+		}
+	}
+	return v.Interface()
+}
+
+// protoName returns the field name of a protobuf struct tag, e.g. word_count for "varint,2,opt,name=word_count,json=wordCount"
+func protoName(tag string) string {
+	for _, part := range strings.Split(tag, ",") {
+		if strings.HasPrefix(part, "name=") {
+			return strings.TrimPrefix(part, "name=")
+		}
+	}
+	return tag
+}
@@ -0,0 +1,85 @@
+package cli
+
+import (
+	"errors"
+
+	skyWallet "github.com/skycoin/hardware-wallet-go/src/skywallet"
+)
+
+// Exit codes of the cli commands, one per class of failure sent by the device
+const (
+	// exitCodeError is used for errors not sent by the device
+	exitCodeError = 1
+	// exitCodeCancelled is used if the action or the PIN entry was cancelled
+	exitCodeCancelled = 2
+	// exitCodePin is used if the PIN is wrong or does not match its confirmation
+	exitCodePin = 3
+	// exitCodeNotInitialized is used if the device has no seed configured
+	exitCodeNotInitialized = 4
+	// exitCodeInvalidData is used if the device rejected the request data
+	exitCodeInvalidData = 5
+	// exitCodeUnexpected is used if the device did not expect the message sent
+	exitCodeUnexpected = 6
+	// exitCodeFirmware is used if the firmware failed processing the request
+	exitCodeFirmware = 7
+	// exitCodeNoDevice is used if no device is connected or none matches --device
+	exitCodeNoDevice = 8
+)
+
+// commandError is a command error exiting with the code matching its class
+type commandError struct {
+	err  error
+	code int
+}
+
+func (e commandError) Error() string {
+	return e.err.Error()
+}
+
+// ExitCode implements gcli.ExitCoder
+func (e commandError) ExitCode() int {
+	return e.code
+}
+
+func (e commandError) Unwrap() error {
+	return e.err
+}
+
+// cliError wraps err so the command exits with the code matching its class
+func cliError(err error) error {
+	return commandError{
+		err:  err,
+		code: exitCode(err),
+	}
+}
+
+func exitCode(err error) int {
+	switch {
+	case errors.Is(err, skyWallet.ErrActionCancelled),
+		errors.Is(err, skyWallet.ErrPinCancelled):
+		return exitCodeCancelled
+	case errors.Is(err, skyWallet.ErrPinInvalid),
+		errors.Is(err, skyWallet.ErrPinMismatch):
+		return exitCodePin
+	case errors.Is(err, skyWallet.ErrNotInitialized):
+		return exitCodeNotInitialized
+	case errors.Is(err, skyWallet.ErrDataError),
+		errors.Is(err, skyWallet.ErrInvalidSignature),
+		errors.Is(err, skyWallet.ErrNotEnoughFunds),
+		errors.Is(err, skyWallet.ErrAddressGeneration):
+		return exitCodeInvalidData
+	case errors.Is(err, skyWallet.ErrUnexpectedMessage),
+		errors.Is(err, skyWallet.ErrButtonExpected),
+		errors.Is(err, skyWallet.ErrPinExpected):
+		return exitCodeUnexpected
+	case errors.Is(err, skyWallet.ErrProcessError),
+		errors.Is(err, skyWallet.ErrFirmwarePanic),
+		errors.Is(err, skyWallet.ErrFirmwareError):
+		return exitCodeFirmware
+	case errors.Is(err, skyWallet.ErrNoDeviceConnected),
+		errors.Is(err, skyWallet.ErrDeviceNotFound):
+		return exitCodeNoDevice
+	default:
+		return exitCodeError
+	}
+}
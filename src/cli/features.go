@@ -2,15 +2,11 @@ package cli
 
 import (
 	"encoding/json"
-	"fmt"
 	"os"
 	"runtime"
 
-	"github.com/gogo/protobuf/proto"
 	gcli "github.com/urfave/cli"
 
-	messages "github.com/skycoin/hardware-wallet-protob/go"
-
 	skyWallet "github.com/skycoin/hardware-wallet-go/src/skywallet"
 )
 
@@ -28,59 +24,41 @@ func featuresCmd() gcli.Command {
 				EnvVar: "DEVICE_TYPE",
 			},
 		},
-		Action: func(c *gcli.Context) {
-			device := skyWallet.NewDevice(skyWallet.DeviceTypeFromString(c.String("deviceType")))
+		Action: func(c *gcli.Context) error {
+			device := skyWallet.NewDevice(skyWallet.DeviceTypeFromString(c.String("deviceType")), deviceOptions(c)...)
 			if device == nil {
-				return
+				return nil
 			}
 			defer device.Close()
 
 			if os.Getenv("AUTO_PRESS_BUTTONS") == "1" && device.Driver.DeviceType() == skyWallet.DeviceTypeEmulator && runtime.GOOS == "linux" {
 				err := device.SetAutoPressButton(true, skyWallet.ButtonRight)
 				if err != nil {
-					log.Error(err)
-					return
+					return cliError(err)
 				}
 			}
 
-			msg, err := device.GetFeatures()
+			features, err := device.Features()
 			if err != nil {
-				log.Error(err)
-				return
+				return cliError(err)
 			}
 
-			switch msg.Kind {
-			case uint16(messages.MessageType_MessageType_Features):
-				features := &messages.Features{}
-				err = proto.Unmarshal(msg.Data, features)
-				if err != nil {
-					log.Error(err)
-					return
-				}
-
-				enc := json.NewEncoder(os.Stdout)
-				if err = enc.Encode(features); err != nil {
-					log.Errorln(err)
-					return
-				}
-				ff := skyWallet.NewFirmwareFeatures(uint64(*features.FirmwareFeatures))
-				if err := ff.Unmarshal(); err != nil {
-					log.Errorln(err)
-					return
-				}
-				log.Printf("\n\nFirmware features:\n%s", ff)
-			// TODO: figure out if this method can even return success or failure msg.
-			case uint16(messages.MessageType_MessageType_Failure), uint16(messages.MessageType_MessageType_Success):
-				msgData, err := skyWallet.DecodeSuccessOrFailMsg(msg)
-				if err != nil {
-					log.Error(err)
-					return
-				}
+			if jsonOutput(c) {
+				return printJSON(jsonResult{
+					Data: features,
+				})
+			}
 
-				fmt.Println(msgData)
-			default:
-				log.Errorf("received unexpected message type: %s", messages.MessageType(msg.Kind))
+			enc := json.NewEncoder(os.Stdout)
+			if err = enc.Encode(features); err != nil {
+				return cliError(err)
+			}
+			ff := skyWallet.NewFirmwareFeatures(uint64(features.GetFirmwareFeatures()))
+			if err := ff.Unmarshal(); err != nil {
+				return cliError(err)
 			}
+			log.Printf("\n\nFirmware features:\n%s", ff)
+			return nil
 		},
 	}
 }
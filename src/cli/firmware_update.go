@@ -1,47 +1,145 @@
 package cli
 
 import (
-	"crypto/sha256"
 	"fmt"
+	"io"
 	"io/ioutil"
 
 	gcli "github.com/urfave/cli"
 
 	skyWallet "github.com/skycoin/hardware-wallet-go/src/skywallet"
+	"github.com/skycoin/hardware-wallet-go/src/skywallet/firmware"
 )
 
+// firmwareResult is the result of the firmwareUpdate command
+type firmwareResult struct {
+	Message string `json:"message"`
+	// Version is the version given by --imageVersion
+	Version    *firmware.Version `json:"version,omitempty"`
+	CodeLength uint32            `json:"code_length"`
+	KeyIndices []uint8           `json:"key_indices"`
+	Signed     bool              `json:"signed"`
+	Hash       string            `json:"hash"`
+	Warnings   []string          `json:"warnings,omitempty"`
+}
+
 func firmwareUpdate() gcli.Command {
 	name := "firmwareUpdate"
 	return gcli.Command{
 		Name:        name,
 		Usage:       "Update device's firmware.",
-		Description: "",
+		Description: "If the device is not in bootloader mode the command waits for it to be plugged while pressing both buttons, then for the device to restart with the new firmware.",
 		Flags: []gcli.Flag{
 			gcli.StringFlag{
 				Name:  "f, file",
 				Usage: "path to the firmware .bin file",
 			},
+			gcli.StringFlag{
+				Name:  "imageVersion",
+				Usage: "Version the firmware is released under, e.g. 1.7.0, older versions than the installed one are refused. The image does not hold its version.",
+			},
+			gcli.BoolFlag{
+				Name:  "allowDowngrade",
+				Usage: "Upload a firmware older than the installed one.",
+			},
+			gcli.BoolFlag{
+				Name:  "allowUnsigned",
+				Usage: "Upload a firmware that is not signed, refused anyway on memory protected devices.",
+			},
 		},
 		OnUsageError: onCommandUsageError(name),
-		Action: func(c *gcli.Context) {
-			device := skyWallet.NewDevice(skyWallet.DeviceTypeUSB)
+		Action: func(c *gcli.Context) error {
+			device := skyWallet.NewDevice(skyWallet.DeviceTypeUSB, deviceOptions(c)...)
 			if device == nil {
-				return
+				return nil
 			}
 			defer device.Close()
 
 			filePath := c.String("file")
-			fmt.Printf("File : %s\n", filePath)
-			firmware, err := ioutil.ReadFile(filePath)
+			fmt.Fprintf(messageWriter(c), "File : %s\n", filePath)
+			data, err := ioutil.ReadFile(filePath)
 			if err != nil {
-				panic(err)
+				return cliError(err)
 			}
-			fmt.Printf("Hash: %x\n", sha256.Sum256(firmware[0x100:]))
-			err = device.FirmwareUpload(firmware, sha256.Sum256(firmware[0x100:]))
+
+			img, err := firmware.Parse(data)
 			if err != nil {
-				log.Error(err)
-				return
+				return cliError(err)
+			}
+
+			var version firmware.Version
+			if v := c.String("imageVersion"); v != "" {
+				version, err = firmware.ParseVersion(v)
+				if err != nil {
+					return cliError(err)
+				}
+			}
+
+			hash := img.Hash()
+			result := firmwareResult{
+				Message:    "Firmware uploaded",
+				CodeLength: img.CodeLength,
+				KeyIndices: img.KeyIndices[:],
+				Signed:     img.Signed(),
+				Hash:       fmt.Sprintf("%x", hash),
+			}
+			if !version.IsZero() {
+				result.Version = &version
+				fmt.Fprintf(messageWriter(c), "Version: %s\n", version)
 			}
+			fmt.Fprintf(messageWriter(c), "Code length: %d\n", result.CodeLength)
+			fmt.Fprintf(messageWriter(c), "Key indices: %v\n", result.KeyIndices)
+			fmt.Fprintf(messageWriter(c), "Signed: %t\n", result.Signed)
+			fmt.Fprintf(messageWriter(c), "Hash: %s\n", result.Hash)
+
+			device.SetFirmwareProgress(firmwareProgress(messageWriter(c)))
+			result.Warnings, err = device.Reflash(img, firmware.Policy{
+				AllowDowngrade: c.Bool("allowDowngrade"),
+				AllowUnsigned:  c.Bool("allowUnsigned"),
+				ImageVersion:   version,
+			})
+			if err != nil {
+				return cliError(err)
+			}
+			for _, warning := range result.Warnings {
+				fmt.Fprintf(messageWriter(c), "Warning: %s\n", warning)
+			}
+
+			if jsonOutput(c) {
+				return printJSON(jsonResult{
+					Data: result,
+				})
+			}
+			return nil
 		},
 	}
 }
+
+// firmwareProgress prints the firmware upload stages to w, the transfer is rendered as a progress bar
+func firmwareProgress(w io.Writer) skyWallet.FirmwareProgressFunc {
+	var bar *skyWallet.Progbar
+	return func(p skyWallet.FirmwareProgress) {
+		switch p.Stage {
+		case skyWallet.FirmwareStageReplug:
+			fmt.Fprintln(w, "Unplug the device, then plug it while pressing both buttons to enter bootloader mode")
+		case skyWallet.FirmwareStageErase:
+			fmt.Fprintln(w, "Erasing the installed firmware")
+		case skyWallet.FirmwareStageTransfer:
+			if bar == nil {
+				fmt.Fprintln(w, "Uploading the firmware")
+				bar = skyWallet.NewProgbar(p.Total)
+			}
+			if p.Written == p.Total {
+				bar.PrintComplete()
+			} else {
+				bar.PrintProg(p.Written)
+			}
+		case skyWallet.FirmwareStageConfirm:
+			fmt.Fprintln(w, "Confirm on the device that the fingerprint matches the hash")
+		case skyWallet.FirmwareStageReboot:
+			fmt.Fprintln(w, "Firmware installed, the device reboots")
+		case skyWallet.FirmwareStageReconnect:
+			fmt.Fprintln(w, "Waiting for the device to restart with the new firmware")
+		}
+	}
+}
@@ -4,6 +4,7 @@ import (
 	gcli "github.com/urfave/cli"
 
 	skyWallet "github.com/skycoin/hardware-wallet-go/src/skywallet"
+	"github.com/skycoin/hardware-wallet-go/src/skywallet/entropy"
 )
 
 func getMixedEntropyCmd() gcli.Command {
@@ -11,25 +12,37 @@ func getMixedEntropyCmd() gcli.Command {
 	return gcli.Command{
 		Name:  name,
 		Usage: "Get device internal mixed entropy and write it down to a file",
-		Action: func(c *gcli.Context) {
+		Action: func(c *gcli.Context) error {
 			entropyBytes := uint32(c.Int("entropyBytes"))
 			outFile := c.String("outFile")
 			if len(outFile) == 0 {
-				log.Error("outFile is mandatory")
-				return
+				return gcli.NewExitError("outFile is mandatory", exitCodeError)
+			}
+			if outFile == "-" && jsonOutput(c) {
+				return gcli.NewExitError("outFile must be a file path in json mode", exitCodeError)
+			}
+			format := c.String("format")
+			if err := setupEntropyOutput(outFile, format); err != nil {
+				return err
 			}
 
-			device := skyWallet.NewDevice(skyWallet.DeviceTypeFromString(c.String("deviceType")))
+			device := skyWallet.NewDevice(skyWallet.DeviceTypeFromString(c.String("deviceType")), deviceOptions(c)...)
 			if device == nil {
-				return
+				return nil
 			}
 			defer device.Close()
 
+			analyzer := entropyAnalyzer(c, device)
+
 			log.Infoln("Getting mixed entropy from device")
-			if err := device.SaveDeviceEntropyInFile(outFile, entropyBytes, skyWallet.MessageDeviceGetMixedEntropy); err != nil {
-				log.Error(err)
-				return
+			if err := saveEntropy(device, outFile, format, entropyBytes, skyWallet.MessageDeviceGetMixedEntropy); err != nil {
+				return cliError(err)
 			}
+
+			return printEntropyResult(c, entropyResult{
+				OutFile:      outFile,
+				EntropyBytes: entropyBytes,
+			}, analyzer)
 		},
 		OnUsageError: onCommandUsageError(name),
 		Subcommands:  nil,
@@ -44,6 +57,20 @@ func getMixedEntropyCmd() gcli.Command {
 				Usage: `File path to write out the mixed entropy buffers, a "-" set the file to stdout.`,
 				Value: "-",
 			},
+			gcli.StringFlag{
+				Name:  "format",
+				Usage: `Encoding of the entropy written to stdout, raw, hex or base64. The logs and the progress are written to stderr.`,
+				Value: "raw",
+			},
+			gcli.BoolFlag{
+				Name:  "analyze",
+				Usage: "Run randomness health checks on the entropy as it is received and print a pass/fail report.",
+			},
+			gcli.Float64Flag{
+				Name:  "minEntropy",
+				Value: entropy.DefaultMinEntropy,
+				Usage: "Min-entropy in bits per byte claimed for the device, it sets the cutoffs of the --analyze health checks.",
+			},
 			gcli.StringFlag{
 				Name:   "deviceType",
 				Usage:  "Device type to send instructions to, hardware wallet (USB) or emulator.",
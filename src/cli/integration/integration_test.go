@@ -423,6 +423,7 @@ func TestGenerateMnemonic(t *testing.T) {
 		args           []string
 		isUsageError   bool
 		expectedOutput string
+		exitStatus     string
 	}{
 		{
 			name:           "generateMnemonic -wc 12",
@@ -441,6 +442,7 @@ func TestGenerateMnemonic(t *testing.T) {
 			args:           []string{"generateMnemonic", "--wordCount", "15"},
 			expectedOutput: "word count must be 12 or 24",
 			isUsageError:   true,
+			exitStatus:     "exit status 1",
 		},
 	}
 
@@ -454,7 +456,7 @@ func TestGenerateMnemonic(t *testing.T) {
 
 			output, err := execCommandCombinedOutput(tc.args...)
 			if err != nil {
-				require.Equal(t, err, "exit status 1")
+				require.EqualError(t, err, tc.exitStatus)
 			}
 
 			require.Contains(t, string(output), tc.expectedOutput)
@@ -581,6 +583,7 @@ func TestSetMnemonic(t *testing.T) {
 		name           string
 		args           []string
 		expectedOutput string
+		exitStatus     string
 	}{
 		{
 			name: "setMnemonic 12",
@@ -601,6 +604,7 @@ func TestSetMnemonic(t *testing.T) {
 			args: []string{"setMnemonic", "--mnemonic",
 				"dress fee animal silly multiply demand casino gold pipe matrix latin badge umbrella orbit safe cover glove one dash chicken play obey"},
 			expectedOutput: "Mnemonic with wrong checksum provided",
+			exitStatus:     "exit status 5",
 		},
 	}
 
@@ -614,7 +618,7 @@ func TestSetMnemonic(t *testing.T) {
 
 			output, err := execCommandCombinedOutput(tc.args...)
 			if err != nil {
-				require.Equal(t, err, "exit status 1")
+				require.EqualError(t, err, tc.exitStatus)
 			}
 
 			require.Contains(t, string(output), tc.expectedOutput)
@@ -0,0 +1,180 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/skycoin/skycoin/src/util/logging"
+	gcli "github.com/urfave/cli"
+
+	skyWallet "github.com/skycoin/hardware-wallet-go/src/skywallet"
+	"github.com/skycoin/hardware-wallet-go/src/skywallet/entropy"
+)
+
+// jsonResult is the document written to stdout by the commands in json mode
+type jsonResult struct {
+	Data  interface{} `json:"data,omitempty"`
+	Error *jsonError  `json:"error,omitempty"`
+}
+
+// jsonError describes the error of a command in json mode
+type jsonError struct {
+	Message string `json:"message"`
+	// Failure is the failure code sent by the device, e.g. Failure_PinInvalid
+	Failure string `json:"failure,omitempty"`
+	// ExitCode is the exit code of the command
+	ExitCode int `json:"exit_code"`
+}
+
+// messageResult is the result of the commands answered with a message by the device
+type messageResult struct {
+	Message string `json:"message"`
+}
+
+// addressesResult is the result of the addressGen command
+type addressesResult struct {
+	Addresses []string `json:"addresses"`
+}
+
+// signatureResult is the result of the signMessage command
+type signatureResult struct {
+	Signature string `json:"signature"`
+}
+
+// signaturesResult is the result of the transactionSign command
+type signaturesResult struct {
+	Signatures []string `json:"signatures"`
+}
+
+// entropyResult is the result of the getRawEntropy and getMixedEntropy commands
+type entropyResult struct {
+	OutFile      string `json:"out_file"`
+	EntropyBytes uint32 `json:"entropy_bytes"`
+	// Report is the result of the health checks run with --analyze
+	Report *entropy.Report `json:"report,omitempty"`
+}
+
+// usbDeviceResult is an item of the getUsbDetails command result
+type usbDeviceResult struct {
+	Path      string `json:"path"`
+	VendorID  int    `json:"vendor_id"`
+	ProductID int    `json:"product_id"`
+	// Mode is a best-effort hint told by the product id, see skywallet.InfoMode
+	Mode string `json:"mode"`
+}
+
+// jsonOutput reports whether the global --json flag is set
+func jsonOutput(c *gcli.Context) bool {
+	return c.GlobalBool("json")
+}
+
+// messageWriter returns where commands print the messages that are not part of their result,
+// in json mode stdout only holds the json document
+func messageWriter(c *gcli.Context) io.Writer {
+	if jsonOutput(c) {
+		return os.Stderr
+	}
+	return os.Stdout
+}
+
+// setupOutput sends the logs to stderr in json mode
+func setupOutput(c *gcli.Context) error {
+	if jsonOutput(c) {
+		logging.SetOutputTo(os.Stderr)
+	}
+	return nil
+}
+
+// printResult writes result as a json document in json mode, otherwise it prints text
+func printResult(c *gcli.Context, result interface{}, text interface{}) error {
+	if !jsonOutput(c) {
+		fmt.Println(text)
+		return nil
+	}
+
+	return printJSON(jsonResult{
+		Data: result,
+	})
+}
+
+// printEntropyResult prints the result of the entropy commands with the report of analyzer if set,
+// the report is printed to stderr if the entropy is written to stdout
+func printEntropyResult(c *gcli.Context, result entropyResult, analyzer *entropy.Analyzer) error {
+	if analyzer == nil {
+		if jsonOutput(c) {
+			return printJSON(jsonResult{
+				Data: result,
+			})
+		}
+		return nil
+	}
+
+	report := analyzer.Report()
+	result.Report = &report
+
+	status := "PASS"
+	if !report.Passed {
+		status = "FAIL"
+	}
+	var text strings.Builder
+	fmt.Fprintf(&text, "Entropy health checks: %s (%d bytes, min-entropy %g bits per byte)", status, report.Bytes, report.MinEntropy)
+	for _, r := range report.Results {
+		fmt.Fprintf(&text, "\n%s", r)
+	}
+	if result.OutFile == "-" {
+		// stdout only holds the entropy
+		fmt.Fprintln(os.Stderr, text.String())
+		return nil
+	}
+	return printResult(c, result, text.String())
+}
+
+func printJSON(result jsonResult) error {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "    ")
+	if err := enc.Encode(result); err != nil {
+		return gcli.NewExitError(err, exitCodeError)
+	}
+	return nil
+}
+
+// jsonErrors wraps action so that in json mode its error is written as a json document to stdout
+func jsonErrors(action interface{}) interface{} {
+	fn, ok := action.(func(*gcli.Context) error)
+	if !ok {
+		return action
+	}
+
+	return func(c *gcli.Context) error {
+		err := fn(c)
+		if err == nil || !jsonOutput(c) {
+			return err
+		}
+
+		result := &jsonError{
+			Message:  err.Error(),
+			ExitCode: exitCodeError,
+		}
+
+		var exitErr gcli.ExitCoder
+		if errors.As(err, &exitErr) {
+			result.ExitCode = exitErr.ExitCode()
+		}
+
+		var failure *skyWallet.FailureError
+		if errors.As(err, &failure) {
+			result.Failure = failure.Code.String()
+		}
+
+		if err := printJSON(jsonResult{Error: result}); err != nil {
+			return err
+		}
+
+		// the error was written already, only the exit code is left
+		return gcli.NewExitError("", result.ExitCode)
+	}
+}
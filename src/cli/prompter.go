@@ -0,0 +1,48 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+
+	gcli "github.com/urfave/cli"
+
+	messages "github.com/skycoin/hardware-wallet-protob/go"
+)
+
+// stdinPrompter reads the user input requested by the device from stdin
+type stdinPrompter struct {
+	// out is where the prompts are printed
+	out io.Writer
+}
+
+// newPrompter returns a stdinPrompter printing the prompts to the command message writer
+func newPrompter(c *gcli.Context) stdinPrompter {
+	return stdinPrompter{
+		out: messageWriter(c),
+	}
+}
+
+func (p stdinPrompter) PinMatrix(pinType messages.PinMatrixRequestType) (string, error) {
+	var pinEnc string
+	fmt.Fprintf(p.out, "PinMatrixRequest response: ")
+	fmt.Scanln(&pinEnc)
+	return pinEnc, nil
+}
+
+func (p stdinPrompter) Passphrase() (string, error) {
+	var passphrase string
+	fmt.Fprintf(p.out, "Input passphrase: ")
+	fmt.Scanln(&passphrase)
+	return passphrase, nil
+}
+
+func (p stdinPrompter) Word() (string, error) {
+	var word string
+	fmt.Fprintf(p.out, "Word: ")
+	fmt.Scanln(&word)
+	return word, nil
+}
+
+func (p stdinPrompter) ButtonConfirm(code messages.ButtonRequestType) error {
+	return nil
+}
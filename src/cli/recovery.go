@@ -1,14 +1,11 @@
 package cli
 
 import (
-	"fmt"
 	"os"
 	"runtime"
 
 	gcli "github.com/urfave/cli"
 
-	messages "github.com/skycoin/hardware-wallet-protob/go"
-
 	skyWallet "github.com/skycoin/hardware-wallet-go/src/skywallet"
 )
 
@@ -39,71 +36,45 @@ func recoveryCmd() gcli.Command {
 			},
 		},
 		OnUsageError: onCommandUsageError(name),
-		Action: func(c *gcli.Context) {
-			device := skyWallet.NewDevice(skyWallet.DeviceTypeFromString(c.String("deviceType")))
+		Action: func(c *gcli.Context) error {
+			device := skyWallet.NewDevice(skyWallet.DeviceTypeFromString(c.String("deviceType")), deviceOptions(c)...)
 			if device == nil {
-				return
+				return nil
 			}
 			defer device.Close()
 
 			if os.Getenv("AUTO_PRESS_BUTTONS") == "1" && device.Driver.DeviceType() == skyWallet.DeviceTypeEmulator && runtime.GOOS == "linux" {
 				err := device.SetAutoPressButton(true, skyWallet.ButtonRight)
 				if err != nil {
-					log.Error(err)
-					return
+					return cliError(err)
 				}
 			}
 
 			passphrase := c.String("usePassphrase")
 			usePassphrase, _err := parseBool(passphrase)
 			if _err != nil {
-				log.Errorln("Valid values for usePassphrase are true or false")
-				return
+				return gcli.NewExitError("Valid values for usePassphrase are true or false", exitCodeError)
 			}
 			dryRun := c.Bool("dryRun")
 			wordCount := uint32(c.Uint64("wordCount"))
 			msg, err := device.Recovery(wordCount, usePassphrase, dryRun)
 			if err != nil {
-				log.Error(err)
-				return
-			}
-
-			if msg.Kind == uint16(messages.MessageType_MessageType_ButtonRequest) {
-				msg, err = device.ButtonAck()
-				if err != nil {
-					log.Error(err)
-					return
-				}
-			}
-
-			for msg.Kind == uint16(messages.MessageType_MessageType_WordRequest) {
-				var word string
-				fmt.Printf("Word: ")
-				fmt.Scanln(&word)
-				msg, err = device.WordAck(word)
-				if err != nil {
-					log.Error(err.Error())
-					os.Exit(1)
-					return
-				}
+				return cliError(err)
 			}
 
-			if msg.Kind == uint16(messages.MessageType_MessageType_ButtonRequest) {
-				// Send ButtonAck
-				msg, err = device.ButtonAck()
-				if err != nil {
-					log.Error(err)
-					return
-				}
+			msg, err = skyWallet.RunFlow(device, msg, newPrompter(c))
+			if err != nil {
+				return cliError(err)
 			}
 
-			responseMsg, err := skyWallet.DecodeSuccessOrFailMsg(msg)
+			responseMsg, err := skyWallet.DecodeSuccessMsg(msg)
 			if err != nil {
-				log.Error(err)
-				return
+				return cliError(err)
 			}
 
-			fmt.Println(responseMsg)
+			return printResult(c, messageResult{
+				Message: responseMsg,
+			}, responseMsg)
 		},
 	}
 }
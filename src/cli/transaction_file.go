@@ -0,0 +1,222 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/skycoin/skycoin/src/cipher"
+
+	messages "github.com/skycoin/hardware-wallet-protob/go"
+
+	"github.com/skycoin/hardware-wallet-go/src/skywallet/transaction"
+)
+
+// transactionFile is the json format of the transactions signed by the transactionSign command,
+// documented in cmd/cli/README.md. It differs from the json of the Skycoin node api so unknown
+// fields are rejected rather than ignored.
+type transactionFile struct {
+	Type      uint8                   `json:"type"`
+	InnerHash string                  `json:"inner_hash,omitempty"`
+	Sigs      []string                `json:"sigs,omitempty"`
+	Inputs    []transactionFileInput  `json:"inputs"`
+	Outputs   []transactionFileOutput `json:"outputs"`
+}
+
+// transactionFileInput is an input of a transactionFile
+type transactionFileInput struct {
+	// Hash is the hash of the unspent output spent by the input
+	Hash string `json:"hash"`
+	// AddressIndex is the index in the wallet of the address owning the unspent output
+	AddressIndex uint32 `json:"address_index"`
+}
+
+// transactionFileOutput is an output of a transactionFile
+type transactionFileOutput struct {
+	Address string `json:"address"`
+	Coins   uint64 `json:"coins"`
+	Hours   uint64 `json:"hours"`
+	// AddressIndex is the index in the wallet of a change address
+	AddressIndex *uint32 `json:"address_index,omitempty"`
+}
+
+// signedTransactionResult is the result of the transactionSign command reading a transaction file
+type signedTransactionResult struct {
+	TxID               string           `json:"txid"`
+	Transaction        *transactionFile `json:"transaction,omitempty"`
+	EncodedTransaction string           `json:"encoded_transaction"`
+}
+
+// unsignedTransaction is a transaction read from a file with the wallet indexes
+// of the addresses required by the device
+type unsignedTransaction struct {
+	tx            *transaction.Transaction
+	inputIndexes  []uint32
+	outputIndexes []*uint32
+	// file is set if the transaction was read as json
+	file *transactionFile
+}
+
+// readTransactionFile reads a json or a hex encoded transaction from path, "-" reads stdin.
+// The wallet indexes of hex encoded transactions are given by inputIndexes and outputIndexes.
+func readTransactionFile(path string, inputIndexes, outputIndexes []int) (*unsignedTransaction, error) {
+	var data []byte
+	var err error
+	if path == "-" {
+		data, err = ioutil.ReadAll(os.Stdin)
+	} else {
+		data, err = ioutil.ReadFile(path)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	data = bytes.TrimSpace(data)
+	if bytes.HasPrefix(data, []byte("{")) {
+		var file transactionFile
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&file); err != nil {
+			return nil, fmt.Errorf("invalid transaction file: %v", err)
+		}
+		return file.unsignedTransaction()
+	}
+
+	tx, err := transaction.DeserializeHex(string(data))
+	if err != nil {
+		return nil, err
+	}
+	if len(inputIndexes) != len(tx.In) {
+		return nil, errors.New("Every input of the transaction should have an inputIndex")
+	}
+
+	utx := &unsignedTransaction{
+		tx:            tx,
+		inputIndexes:  make([]uint32, len(tx.In)),
+		outputIndexes: make([]*uint32, len(tx.Out)),
+	}
+	for i, index := range inputIndexes {
+		utx.inputIndexes[i] = uint32(index)
+	}
+	for i := 0; i < len(outputIndexes) && i < len(tx.Out); i++ {
+		utx.outputIndexes[i] = proto.Uint32(uint32(outputIndexes[i]))
+	}
+
+	return utx, nil
+}
+
+func (f *transactionFile) unsignedTransaction() (*unsignedTransaction, error) {
+	utx := &unsignedTransaction{
+		tx: &transaction.Transaction{
+			Type: f.Type,
+			In:   make([]cipher.SHA256, len(f.Inputs)),
+			Out:  make([]transaction.Output, len(f.Outputs)),
+		},
+		inputIndexes:  make([]uint32, len(f.Inputs)),
+		outputIndexes: make([]*uint32, len(f.Outputs)),
+		file:          f,
+	}
+
+	for i, in := range f.Inputs {
+		hash, err := cipher.SHA256FromHex(in.Hash)
+		if err != nil {
+			return nil, fmt.Errorf("invalid input hash %q: %v", in.Hash, err)
+		}
+		utx.tx.In[i] = hash
+		utx.inputIndexes[i] = in.AddressIndex
+	}
+
+	for i, out := range f.Outputs {
+		address, err := cipher.DecodeBase58Address(out.Address)
+		if err != nil {
+			return nil, fmt.Errorf("invalid output address %q: %v", out.Address, err)
+		}
+		utx.tx.Out[i] = transaction.Output{
+			Address: address,
+			Coins:   out.Coins,
+			Hours:   out.Hours,
+		}
+		utx.outputIndexes[i] = out.AddressIndex
+	}
+
+	utx.tx.Sigs = make([]cipher.Sig, len(f.Inputs))
+	utx.tx.UpdateHeader()
+
+	return utx, nil
+}
+
+// messages maps the transaction to the inputs and outputs sent to the device
+func (utx *unsignedTransaction) messages() ([]*messages.SkycoinTransactionInput, []*messages.SkycoinTransactionOutput) {
+	inputs := make([]*messages.SkycoinTransactionInput, len(utx.tx.In))
+	for i, in := range utx.tx.In {
+		inputs[i] = &messages.SkycoinTransactionInput{
+			HashIn: proto.String(in.Hex()),
+			Index:  proto.Uint32(utx.inputIndexes[i]),
+		}
+	}
+
+	outputs := make([]*messages.SkycoinTransactionOutput, len(utx.tx.Out))
+	for i, out := range utx.tx.Out {
+		outputs[i] = &messages.SkycoinTransactionOutput{
+			Address:      proto.String(out.Address.String()),
+			Coin:         proto.Uint64(out.Coins),
+			Hour:         proto.Uint64(out.Hours),
+			AddressIndex: utx.outputIndexes[i],
+		}
+	}
+
+	return inputs, outputs
+}
+
+// sign fills in the signatures returned by the device
+func (utx *unsignedTransaction) sign(signatures []string) (*signedTransactionResult, error) {
+	sigs := make([]cipher.Sig, len(signatures))
+	for i, signature := range signatures {
+		sig, err := cipher.SigFromHex(signature)
+		if err != nil {
+			return nil, err
+		}
+		sigs[i] = sig
+	}
+
+	if err := utx.tx.SetSignatures(sigs); err != nil {
+		return nil, err
+	}
+
+	result := &signedTransactionResult{
+		TxID:               utx.tx.Hash().Hex(),
+		EncodedTransaction: utx.tx.SerializeHex(),
+	}
+
+	if utx.file != nil {
+		file := *utx.file
+		file.InnerHash = utx.tx.InnerHash.Hex()
+		file.Sigs = signatures
+		result.Transaction = &file
+	}
+
+	return result, nil
+}
+
+// write writes the signed transaction to path in the format it was read
+func (r *signedTransactionResult) write(path string) error {
+	data := []byte(r.EncodedTransaction + "\n")
+	if r.Transaction != nil {
+		var err error
+		data, err = json.MarshalIndent(r.Transaction, "", "    ")
+		if err != nil {
+			return err
+		}
+		data = append(data, '\n')
+	}
+
+	if path == "-" {
+		_, err := os.Stdout.Write(data)
+		return err
+	}
+	return ioutil.WriteFile(path, data, 0600)
+}
@@ -0,0 +1,240 @@
+package cli
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/hardware-wallet-go/src/skywallet/transaction"
+)
+
+const (
+	testInputHash = "181bd5656115172fe81451fae4fb56498a97744d89702e73da75ba91ed5200f9"
+	testAddress   = "K9TzLrgqz7uXn3QJHGxmzdRByAzH33J2ot"
+)
+
+func testUnsignedTransaction() *transaction.Transaction {
+	tx := &transaction.Transaction{
+		In: []cipher.SHA256{cipher.MustSHA256FromHex(testInputHash)},
+		Out: []transaction.Output{
+			{
+				Address: cipher.MustDecodeBase58Address(testAddress),
+				Coins:   100000,
+				Hours:   2,
+			},
+		},
+		Sigs: make([]cipher.Sig, 1),
+	}
+	tx.UpdateHeader()
+	return tx
+}
+
+// writeTestFile writes data to a file of dir and returns its path
+func writeTestFile(t *testing.T, dir, data string) string {
+	f, err := ioutil.TempFile(dir, "transaction")
+	require.NoError(t, err)
+	defer f.Close()
+	_, err = f.WriteString(data)
+	require.NoError(t, err)
+	return f.Name()
+}
+
+func TestReadTransactionFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "transaction_file")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	hex := testUnsignedTransaction().SerializeHex()
+
+	cases := []struct {
+		name          string
+		data          string
+		inputIndexes  []int
+		outputIndexes []int
+		err           string
+		addressIndex  uint32
+		changeIndex   *uint32
+	}{
+		{
+			name:         "json",
+			data:         `{"inputs": [{"hash": "` + testInputHash + `", "address_index": 3}], "outputs": [{"address": "` + testAddress + `", "coins": 100000, "hours": 2}]}`,
+			addressIndex: 3,
+		},
+		{
+			name:         "json change output",
+			data:         `{"inputs": [{"hash": "` + testInputHash + `", "address_index": 3}], "outputs": [{"address": "` + testAddress + `", "coins": 100000, "hours": 2, "address_index": 5}]}`,
+			addressIndex: 3,
+			changeIndex:  newUint32(5),
+		},
+		{
+			name:         "hex",
+			data:         hex + "\n",
+			inputIndexes: []int{4},
+			addressIndex: 4,
+		},
+		{
+			name:          "hex change output",
+			data:          hex,
+			inputIndexes:  []int{4},
+			outputIndexes: []int{6},
+			addressIndex:  4,
+			changeIndex:   newUint32(6),
+		},
+		{
+			name: "hex without inputIndex",
+			data: hex,
+			err:  "Every input of the transaction should have an inputIndex",
+		},
+		{
+			name:         "hex with too many inputIndex",
+			data:         hex,
+			inputIndexes: []int{0, 1},
+			err:          "Every input of the transaction should have an inputIndex",
+		},
+		{
+			name: "invalid hex",
+			data: "zz",
+			err:  "encoding/hex: invalid byte: U+007A 'z'",
+		},
+		{
+			name:         "truncated hex",
+			data:         hex[:len(hex)-2],
+			inputIndexes: []int{0},
+			err:          transaction.ErrInvalidLength.Error(),
+		},
+		{
+			name: "bad input hash",
+			data: `{"inputs": [{"hash": "00", "address_index": 0}], "outputs": [{"address": "` + testAddress + `", "coins": 1, "hours": 1}]}`,
+			err:  `invalid input hash "00"`,
+		},
+		{
+			name: "bad output address",
+			data: `{"inputs": [{"hash": "` + testInputHash + `", "address_index": 0}], "outputs": [{"address": "abc", "coins": 1, "hours": 1}]}`,
+			err:  `invalid output address "abc"`,
+		},
+		{
+			name: "skycoin node json",
+			data: `{"inputs": [{"uxid": "` + testInputHash + `"}], "outputs": [{"address": "` + testAddress + `", "coins": "0.100000", "hours": "2"}]}`,
+			err:  `invalid transaction file: json: unknown field "uxid"`,
+		},
+		{
+			name: "string coins",
+			data: `{"inputs": [{"hash": "` + testInputHash + `", "address_index": 0}], "outputs": [{"address": "` + testAddress + `", "coins": "0.100000", "hours": 2}]}`,
+			err:  "invalid transaction file: json: cannot unmarshal string",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			path := writeTestFile(t, dir, tc.data)
+			utx, err := readTransactionFile(path, tc.inputIndexes, tc.outputIndexes)
+			if tc.err != "" {
+				require.Error(t, err)
+				require.True(t, strings.HasPrefix(err.Error(), tc.err), err.Error())
+				return
+			}
+			require.NoError(t, err)
+
+			require.Equal(t, testUnsignedTransaction(), utx.tx)
+			inputs, outputs := utx.messages()
+			require.Len(t, inputs, 1)
+			require.Equal(t, testInputHash, inputs[0].GetHashIn())
+			require.Equal(t, tc.addressIndex, inputs[0].GetIndex())
+			require.Len(t, outputs, 1)
+			require.Equal(t, testAddress, outputs[0].GetAddress())
+			require.Equal(t, uint64(100000), outputs[0].GetCoin())
+			require.Equal(t, uint64(2), outputs[0].GetHour())
+			require.Equal(t, tc.changeIndex, outputs[0].AddressIndex)
+		})
+	}
+}
+
+func TestSignTransactionFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "transaction_file")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	pubKey, secKey := cipher.GenerateKeyPair()
+	sig := cipher.MustSignHash(testUnsignedTransaction().SignedHash(0), secKey)
+
+	cases := []struct {
+		name         string
+		data         string
+		inputIndexes []int
+		json         bool
+	}{
+		{
+			name:         "hex",
+			data:         testUnsignedTransaction().SerializeHex(),
+			inputIndexes: []int{0},
+		},
+		{
+			name: "json",
+			data: `{"inputs": [{"hash": "` + testInputHash + `", "address_index": 0}], "outputs": [{"address": "` + testAddress + `", "coins": 100000, "hours": 2}]}`,
+			json: true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			utx, err := readTransactionFile(writeTestFile(t, dir, tc.data), tc.inputIndexes, nil)
+			require.NoError(t, err)
+
+			result, err := utx.sign([]string{sig.Hex()})
+			require.NoError(t, err)
+
+			// the encoded transaction is the signed Skycoin transaction
+			signed, err := transaction.DeserializeHex(result.EncodedTransaction)
+			require.NoError(t, err)
+			require.Equal(t, []cipher.Sig{sig}, signed.Sigs)
+			require.Equal(t, testUnsignedTransaction().InnerHash, signed.InnerHash)
+			require.Equal(t, signed.Hash().Hex(), result.TxID)
+			require.NoError(t, cipher.VerifyPubKeySignedHash(pubKey, signed.Sigs[0], signed.SignedHash(0)))
+
+			// the signed transaction is written in the format it was read
+			path := filepath.Join(dir, tc.name+".signed")
+			require.NoError(t, result.write(path))
+			data, err := ioutil.ReadFile(path)
+			require.NoError(t, err)
+
+			if !tc.json {
+				require.Nil(t, result.Transaction)
+				decoded, err := transaction.DeserializeHex(strings.TrimSpace(string(data)))
+				require.NoError(t, err)
+				require.Equal(t, signed, decoded)
+				return
+			}
+
+			var file transactionFile
+			require.NoError(t, json.Unmarshal(data, &file))
+			require.Equal(t, result.Transaction, &file)
+			require.Equal(t, []string{sig.Hex()}, file.Sigs)
+			require.Equal(t, signed.InnerHash.Hex(), file.InnerHash)
+
+			// the written json is read back as the same unsigned transaction
+			utx, err = readTransactionFile(path, nil, nil)
+			require.NoError(t, err)
+			require.Equal(t, testUnsignedTransaction(), utx.tx)
+		})
+	}
+
+	utx, err := readTransactionFile(writeTestFile(t, dir, testUnsignedTransaction().SerializeHex()), []int{0}, nil)
+	require.NoError(t, err)
+
+	// one signature is expected per input
+	_, err = utx.sign(nil)
+	require.Equal(t, transaction.ErrSignatureCount, err)
+
+	_, err = utx.sign([]string{"00"})
+	require.Error(t, err)
+}
+
+func newUint32(v uint32) *uint32 {
+	return &v
+}
@@ -1,7 +1,6 @@
 package cli
 
 import (
-	"fmt"
 	"os"
 	"runtime"
 
@@ -21,13 +20,22 @@ func transactionSignCmd() gcli.Command {
 		Usage:       "Ask the device to sign a transaction using the provided information.",
 		Description: "",
 		Flags: []gcli.Flag{
+			gcli.StringFlag{
+				Name:  "file",
+				Usage: `Unsigned transaction file, as json or as hex encoded Skycoin transaction, a "-" reads it from stdin.`,
+			},
+			gcli.StringFlag{
+				Name:  "outFile",
+				Usage: `File path to write out the signed transaction, a "-" set the file to stdout.`,
+				Value: "-",
+			},
 			gcli.StringSliceFlag{
 				Name:  "inputHash",
 				Usage: "Hash of the Input of the transaction we expect the device to sign",
 			},
 			gcli.IntSliceFlag{
 				Name:  "inputIndex",
-				Usage: "Index of the input in the wallet",
+				Usage: "Index of the input in the wallet, one per input of a hex encoded --file",
 			},
 			gcli.StringSliceFlag{
 				Name:  "outputAddress",
@@ -43,7 +51,7 @@ func transactionSignCmd() gcli.Command {
 			},
 			gcli.IntSliceFlag{
 				Name:  "addressIndex",
-				Usage: "If the address is a return address tell its index in the wallet",
+				Usage: "If the address is a return address tell its index in the wallet, also used for the outputs of a hex encoded --file",
 			},
 			gcli.StringFlag{
 				Name:   "deviceType",
@@ -52,7 +60,7 @@ func transactionSignCmd() gcli.Command {
 			},
 		},
 		OnUsageError: onCommandUsageError(name),
-		Action: func(c *gcli.Context) {
+		Action: func(c *gcli.Context) error {
 			inputs := c.StringSlice("inputHash")
 			inputIndex := c.IntSlice("inputIndex")
 			outputs := c.StringSlice("outputAddress")
@@ -60,27 +68,30 @@ func transactionSignCmd() gcli.Command {
 			hours := c.Int64Slice("hour")
 			addressIndex := c.IntSlice("addressIndex")
 
-			device := skyWallet.NewDevice(skyWallet.DeviceTypeFromString(c.String("deviceType")))
+			device := skyWallet.NewDevice(skyWallet.DeviceTypeFromString(c.String("deviceType")), deviceOptions(c)...)
 			if device == nil {
-				return
+				return nil
 			}
 			defer device.Close()
 
+			setupVerify(c, device)
+
 			if os.Getenv("AUTO_PRESS_BUTTONS") == "1" && device.Driver.DeviceType() == skyWallet.DeviceTypeEmulator && runtime.GOOS == "linux" {
 				err := device.SetAutoPressButton(true, skyWallet.ButtonRight)
 				if err != nil {
-					log.Error(err)
-					return
+					return cliError(err)
 				}
 			}
 
+			if file := c.String("file"); file != "" {
+				return signTransactionFile(c, device, file)
+			}
+
 			if len(inputs) != len(inputIndex) {
-				fmt.Println("Every given input hash should have the an inputIndex")
-				return
+				return gcli.NewExitError("Every given input hash should have the an inputIndex", exitCodeError)
 			}
 			if len(outputs) != len(coins) || len(outputs) != len(hours) {
-				fmt.Println("Every given output should have a coin and hour value")
-				return
+				return gcli.NewExitError("Every given output should have a coin and hour value", exitCodeError)
 			}
 
 			var transactionInputs []*messages.SkycoinTransactionInput
@@ -104,61 +115,68 @@ func transactionSignCmd() gcli.Command {
 
 			msg, err := device.TransactionSign(transactionInputs, transactionOutputs)
 			if err != nil {
-				log.Error(err)
-				return
+				return cliError(err)
 			}
 
-			for {
-				switch msg.Kind {
-				case uint16(messages.MessageType_MessageType_ResponseTransactionSign):
-					signatures, err := skyWallet.DecodeResponseTransactionSign(msg)
-					if err != nil {
-						log.Error(err)
-						return
-					}
-					fmt.Println(signatures)
-					return
-				case uint16(messages.MessageType_MessageType_Success):
-					fmt.Println("Should end with ResponseTransactionSign request")
-					return
-				case uint16(messages.MessageType_MessageType_ButtonRequest):
-					msg, err = device.ButtonAck()
-					if err != nil {
-						log.Error(err)
-						return
-					}
-				case uint16(messages.MessageType_MessageType_PassphraseRequest):
-					var passphrase string
-					fmt.Printf("Input passphrase: ")
-					fmt.Scanln(&passphrase)
-					msg, err = device.PassphraseAck(passphrase)
-					if err != nil {
-						log.Error(err)
-						return
-					}
-				case uint16(messages.MessageType_MessageType_PinMatrixRequest):
-					var pinEnc string
-					fmt.Printf("PinMatrixRequest response: ")
-					fmt.Scanln(&pinEnc)
-					msg, err = device.PinMatrixAck(pinEnc)
-					if err != nil {
-						log.Error(err)
-						return
-					}
-				case uint16(messages.MessageType_MessageType_Failure):
-					failMsg, err := skyWallet.DecodeFailMsg(msg)
-					if err != nil {
-						log.Error(err)
-						return
-					}
-
-					fmt.Printf("Failed with message: %s\n", failMsg)
-					return
-				default:
-					log.Errorf("received unexpected message type: %s", messages.MessageType(msg.Kind))
-					return
-				}
+			msg, err = skyWallet.RunFlow(device, msg, newPrompter(c))
+			if err != nil {
+				return cliError(err)
+			}
+
+			signatures, err := skyWallet.DecodeResponseTransactionSign(msg)
+			if err != nil {
+				return cliError(err)
 			}
+
+			return printResult(c, signaturesResult{
+				Signatures: signatures,
+			}, signatures)
 		},
 	}
 }
+
+// signTransactionFile signs the transaction read from file and writes it back with the signatures filled in
+func signTransactionFile(c *gcli.Context, device *skyWallet.Device, file string) error {
+	utx, err := readTransactionFile(file, c.IntSlice("inputIndex"), c.IntSlice("addressIndex"))
+	if err != nil {
+		return gcli.NewExitError(err, exitCodeError)
+	}
+
+	msg, err := device.TransactionSign(utx.messages())
+	if err != nil {
+		return cliError(err)
+	}
+
+	msg, err = skyWallet.RunFlow(device, msg, newPrompter(c))
+	if err != nil {
+		return cliError(err)
+	}
+
+	signatures, err := skyWallet.DecodeResponseTransactionSign(msg)
+	if err != nil {
+		return cliError(err)
+	}
+
+	result, err := utx.sign(signatures)
+	if err != nil {
+		return cliError(err)
+	}
+
+	outFile := c.String("outFile")
+	if outFile == "-" && jsonOutput(c) {
+		return printJSON(jsonResult{
+			Data: result,
+		})
+	}
+
+	if err := result.write(outFile); err != nil {
+		return cliError(err)
+	}
+
+	if outFile != "-" {
+		return printResult(c, messageResult{
+			Message: "Signed transaction written to " + outFile,
+		}, "Signed transaction written to "+outFile)
+	}
+	return nil
+}
@@ -20,17 +20,33 @@ func getUsbDetails() gcli.Command {
 				EnvVar: "DEVICE_TYPE",
 			},
 		},
-		Action: func(c *gcli.Context) {
+		Action: func(c *gcli.Context) error {
 			device := skyWallet.NewDevice(skyWallet.DeviceTypeFromString(c.String("deviceType")))
 			if device == nil {
-				return
+				return nil
 			}
 			defer device.Close()
 
 			infos, err := device.GetUsbInfo()
 			if err != nil {
-				log.Errorln(err)
+				return cliError(err)
 			}
+
+			if jsonOutput(c) {
+				devices := make([]usbDeviceResult, len(infos))
+				for i, info := range infos {
+					devices[i] = usbDeviceResult{
+						Path:      info.Path,
+						VendorID:  info.VendorID,
+						ProductID: info.ProductID,
+						Mode:      skyWallet.InfoMode(info).String(),
+					}
+				}
+				return printJSON(jsonResult{
+					Data: devices,
+				})
+			}
+
 			for infoIdx := range infos {
 				log.Infoln("-----------------------------------------")
 				if infos[infoIdx].VendorID == skyWallet.SkycoinVendorID {
@@ -39,8 +55,13 @@ func getUsbDetails() gcli.Command {
 				if infos[infoIdx].ProductID == skyWallet.SkycoinHwProductID {
 					log.Printf("%-13d%-5s%s", infos[infoIdx].ProductID, "==>", "Hardware Wallet")
 				}
+				if infos[infoIdx].ProductID == skyWallet.SkycoinBootloaderProductID {
+					log.Printf("%-13d%-5s%s", infos[infoIdx].ProductID, "==>", "Hardware Wallet bootloader")
+				}
+				log.Printf("%-13s%-5s%s", "Mode", "==>", skyWallet.InfoMode(infos[infoIdx]))
 				log.Printf("%-13s%-5s%s", "Device path", "==>", infos[infoIdx].Path)
 			}
+			return nil
 		},
 	}
 }
@@ -1,7 +1,21 @@
 package cli
 
 import (
+	"encoding/base64"
+	"encoding/hex"
 	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/util/logging"
+	gcli "github.com/urfave/cli"
+
+	skyWallet "github.com/skycoin/hardware-wallet-go/src/skywallet"
+	"github.com/skycoin/hardware-wallet-go/src/skywallet/entropy"
 )
 
 func parseBool(s string) (*bool, error) {
@@ -18,3 +32,172 @@ func parseBool(s string) (*bool, error) {
 	}
 	return &b, nil
 }
+
+// captureFile is the file given by the global --capture flag, nil if not set
+var captureFile *os.File
+
+// openCapture opens the file given by the global --capture flag, the reports are appended to it
+func openCapture(c *gcli.Context) error {
+	path := c.GlobalString("capture")
+	if path == "" {
+		return nil
+	}
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return gcli.NewExitError(err, exitCodeError)
+	}
+	captureFile = f
+	return nil
+}
+
+// entropySourceFile is the file given by the global --entropySource flag, nil if not set
+var entropySourceFile *os.File
+
+// openEntropySource opens the file given by the global --entropySource flag
+func openEntropySource(c *gcli.Context) error {
+	path := c.GlobalString("entropySource")
+	if path == "" {
+		return nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return gcli.NewExitError(err, exitCodeError)
+	}
+	entropySourceFile = f
+	return nil
+}
+
+// closeFiles closes the files opened for the global --capture and --entropySource flags
+func closeFiles(c *gcli.Context) error {
+	var firstErr error
+	for _, f := range []*os.File{captureFile, entropySourceFile} {
+		if f == nil {
+			continue
+		}
+		if err := f.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	captureFile = nil
+	entropySourceFile = nil
+
+	if firstErr != nil {
+		return gcli.NewExitError(firstErr, exitCodeError)
+	}
+	return nil
+}
+
+// expectedAddresses are the addresses of the file given by the global --expectedAddresses flag
+var expectedAddresses []cipher.Address
+
+// readExpectedAddresses reads the file given by the global --expectedAddresses flag, one address per line
+func readExpectedAddresses(c *gcli.Context) error {
+	path := c.GlobalString("expectedAddresses")
+	if path == "" {
+		return nil
+	}
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return gcli.NewExitError(err, exitCodeError)
+	}
+
+	for _, line := range strings.Fields(string(b)) {
+		address, err := cipher.DecodeBase58Address(line)
+		if err != nil {
+			return gcli.NewExitError(fmt.Sprintf("invalid expected address %q: %v", line, err), exitCodeError)
+		}
+		expectedAddresses = append(expectedAddresses, address)
+	}
+	return nil
+}
+
+// setupVerify enables the verification of the device answers if the global --verify flag is set
+func setupVerify(c *gcli.Context, device *skyWallet.Device) {
+	if !c.GlobalBool("verify") {
+		return
+	}
+
+	device.SetVerify(true)
+	device.SetExpectedAddresses(expectedAddresses)
+}
+
+// deviceOptions returns the options selecting the wallet given by the global --device flag,
+// recording the session to the global --capture file and reading the host entropy from
+// the global --entropySource file
+func deviceOptions(c *gcli.Context) []skyWallet.Option {
+	var options []skyWallet.Option
+	if device := c.GlobalString("device"); device != "" {
+		options = append(options, skyWallet.WithDevice(device))
+	}
+	if captureFile != nil {
+		options = append(options, skyWallet.WithCapture(captureFile))
+	}
+	if entropySourceFile != nil {
+		options = append(options, skyWallet.WithEntropySource(entropySourceFile))
+	}
+	return options
+}
+
+// entropyAnalyzer sets an analyzer on device if the --analyze flag is set
+func entropyAnalyzer(c *gcli.Context, device *skyWallet.Device) *entropy.Analyzer {
+	if !c.Bool("analyze") {
+		return nil
+	}
+
+	analyzer := entropy.NewAnalyzer(c.Float64("minEntropy"))
+	device.SetEntropyAnalyzer(analyzer)
+	return analyzer
+}
+
+// setupEntropyOutput checks the format of the entropy written to outFile,
+// the logs are sent to stderr if the entropy is written to stdout
+func setupEntropyOutput(outFile, format string) error {
+	switch format {
+	case "raw":
+	case "hex", "base64":
+		if outFile != "-" {
+			return gcli.NewExitError("format "+format+" requires outFile to be stdout", exitCodeError)
+		}
+	default:
+		return gcli.NewExitError("invalid format "+format+", valid formats are raw, hex and base64", exitCodeError)
+	}
+
+	if outFile == "-" {
+		logging.SetOutputTo(os.Stderr)
+	}
+	return nil
+}
+
+// saveEntropy saves the entropy to outFile, stdout gets the entropy encoded in format
+func saveEntropy(device *skyWallet.Device, outFile, format string, entropyBytes uint32, getEntropyMsgBuilder func(entropyBytes uint32) ([][64]byte, error)) error {
+	if outFile != "-" {
+		return device.SaveDeviceEntropyInFile(outFile, entropyBytes, getEntropyMsgBuilder)
+	}
+
+	var w io.Writer = os.Stdout
+	var encoder io.WriteCloser
+	switch format {
+	case "hex":
+		w = hex.NewEncoder(os.Stdout)
+	case "base64":
+		encoder = base64.NewEncoder(base64.StdEncoding, os.Stdout)
+		w = encoder
+	}
+
+	if err := device.SaveDeviceEntropy(w, entropyBytes, getEntropyMsgBuilder); err != nil {
+		return err
+	}
+
+	if encoder != nil {
+		if err := encoder.Close(); err != nil {
+			return err
+		}
+	}
+	if format != "raw" {
+		fmt.Println()
+	}
+	return nil
+}
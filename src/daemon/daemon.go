@@ -0,0 +1,432 @@
+/*
+Package daemon exposes the skywallet Devicer api over a localhost HTTP+JSON api.
+
+Every device operation is a POST request to /api/v1/<operation>. When the
+device needs user input (PIN matrix, passphrase, recovery word or button
+confirmation) the operation is suspended and answered with a pending request
+resource. The client answers it with POST /api/v1/pending/<id> or cancels the
+operation with DELETE /api/v1/pending/<id>. Each answer is replied with the
+next pending request or with the operation result.
+
+The operations run one at a time, the device is locked through a
+skywallet.Session until the operation ends.
+
+The api is meant to be reached from the local machine only. Requests are refused
+unless their Host header names the loopback interface or one of Config.AllowedHosts,
+which defeats DNS rebinding, and unless their Origin header, set by browsers, is one
+of Config.AllowedOrigins, which are answered with CORS headers. POST bodies must be
+sent as application/json, which browsers do not send cross origin without a CORS
+preflight. If Config.Token is set it is required as a bearer token.
+*/
+package daemon
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"mime"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/skycoin/skycoin/src/util/logging"
+
+	"github.com/skycoin/hardware-wallet-go/src/skywallet"
+	"github.com/skycoin/hardware-wallet-go/src/skywallet/firmware"
+)
+
+var (
+	log = logging.MustGetLogger("daemon")
+)
+
+const (
+	apiPrefix      = "/api/v1/"
+	pendingPrefix  = apiPrefix + "pending/"
+	maxRequestSize = 2 << 20 // firmware images are sent base64 encoded
+
+	// DefaultPendingTimeout is the default time a pending request waits for an answer
+	DefaultPendingTimeout = 5 * time.Minute
+
+	// corsMaxAge is the time in seconds browsers may cache the answer to a CORS preflight request
+	corsMaxAge = "600"
+)
+
+// loopbackHosts are the Host headers always accepted
+var loopbackHosts = []string{"localhost", "127.0.0.1", "::1"}
+
+var (
+	// ErrPendingTimeout is returned if a pending request was not answered in time
+	ErrPendingTimeout = errors.New("pending request not answered in time")
+	// ErrPendingCancelled is returned if the client cancelled a pending request
+	ErrPendingCancelled = errors.New("pending request cancelled")
+)
+
+// Config configures the daemon
+type Config struct {
+	// PendingTimeout is the time a pending request waits for an answer
+	// before the operation is cancelled, defaults to DefaultPendingTimeout
+	PendingTimeout time.Duration
+	// AllowedHosts are the host names accepted in the Host header besides the loopback ones
+	AllowedHosts []string
+	// AllowedOrigins are the origins of the web pages allowed to use the api,
+	// e.g. https://wallet.example.com. Browser requests from other origins are refused.
+	AllowedOrigins []string
+	// Token is required in the Authorization header of every request as "Bearer <token>" if set
+	Token string
+}
+
+// Server serves the Devicer api over HTTP+JSON
+type Server struct {
+	device skywallet.Devicer
+	config Config
+
+	mu           sync.Mutex
+	interactions map[string]*interaction
+	// firmwareProgress is the last progress event of the running or last firmware upload
+	firmwareProgress *skywallet.FirmwareProgress
+}
+
+// New creates a server sending the requests to device
+func New(device skywallet.Devicer, config Config) *Server {
+	if config.PendingTimeout <= 0 {
+		config.PendingTimeout = DefaultPendingTimeout
+	}
+
+	s := &Server{
+		device:       device,
+		config:       config,
+		interactions: make(map[string]*interaction),
+	}
+	device.SetFirmwareProgress(s.setFirmwareProgress)
+	return s
+}
+
+// Handler returns the http handler serving the api
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc(apiPrefix+"available", s.available)
+	mux.HandleFunc(apiPrefix+"usbInfo", s.usbInfo)
+	mux.HandleFunc(apiPrefix+"firmwareProgress", s.getFirmwareProgress)
+	mux.HandleFunc(pendingPrefix, s.pending)
+	for name, op := range operations {
+		mux.HandleFunc(apiPrefix+name, s.operation(op))
+	}
+
+	return s.guard(mux)
+}
+
+// guard refuses the requests not coming from the local machine or an allowed web page,
+// see the package documentation
+func (s *Server) guard(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !s.allowedHost(r.Host) {
+			writeError(w, http.StatusForbidden, errors.New("host not allowed"))
+			return
+		}
+
+		if origin := r.Header.Get("Origin"); origin != "" {
+			if !contains(s.config.AllowedOrigins, origin) {
+				writeError(w, http.StatusForbidden, errors.New("origin not allowed"))
+				return
+			}
+
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+			w.Header().Add("Vary", "Origin")
+			if r.Method == http.MethodOptions {
+				w.Header().Set("Access-Control-Allow-Methods", "GET, POST, DELETE")
+				w.Header().Set("Access-Control-Allow-Headers", "Authorization, Content-Type")
+				w.Header().Set("Access-Control-Max-Age", corsMaxAge)
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+		}
+
+		if s.config.Token != "" {
+			auth := []byte(r.Header.Get("Authorization"))
+			if subtle.ConstantTimeCompare(auth, []byte("Bearer "+s.config.Token)) != 1 {
+				writeError(w, http.StatusUnauthorized, errors.New("invalid or missing token"))
+				return
+			}
+		}
+
+		if r.Method == http.MethodPost {
+			mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+			if err != nil || mediaType != "application/json" {
+				writeError(w, http.StatusUnsupportedMediaType, errors.New("content type must be application/json"))
+				return
+			}
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// allowedHost checks the host of a Host header is a loopback one or an allowed one
+func (s *Server) allowedHost(hostport string) bool {
+	host, _, err := net.SplitHostPort(hostport)
+	if err != nil {
+		// no port
+		host = strings.TrimSuffix(strings.TrimPrefix(hostport, "["), "]")
+	}
+	host = strings.ToLower(host)
+	return contains(loopbackHosts, host) || contains(s.config.AllowedHosts, host)
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// Response is the body of every api response
+type Response struct {
+	// Data is the result of a finished operation
+	Data interface{} `json:"data,omitempty"`
+	// Pending is the device request waiting for the client answer
+	Pending *PendingRequest `json:"pending,omitempty"`
+	// Error is set if the request failed
+	Error *Error `json:"error,omitempty"`
+}
+
+// Error describes a failed request
+type Error struct {
+	Message string `json:"message"`
+	// Failure is the failure code sent by the device, e.g. Failure_PinInvalid
+	Failure string `json:"failure,omitempty"`
+}
+
+// AvailableResponse is the result of the available request
+type AvailableResponse struct {
+	Available bool `json:"available"`
+}
+
+func (s *Server) available(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, Response{
+		Data: AvailableResponse{
+			Available: s.device.Available(),
+		},
+	})
+}
+
+// UsbDevice describes a wallet attached through usb
+type UsbDevice struct {
+	Path      string `json:"path"`
+	VendorID  int    `json:"vendor_id"`
+	ProductID int    `json:"product_id"`
+	// Mode is a best-effort hint told by the usb ids, FIRMWARE or BOOTLOADER, see skywallet.InfoMode
+	Mode string `json:"mode"`
+}
+
+// UsbInfoResponse is the result of the usbInfo request
+type UsbInfoResponse struct {
+	Devices []UsbDevice `json:"devices"`
+}
+
+func (s *Server) usbInfo(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+		return
+	}
+
+	infos, err := s.device.GetUsbInfo()
+	if err != nil {
+		writeError(w, errorStatus(err), err)
+		return
+	}
+
+	devices := make([]UsbDevice, len(infos))
+	for i, info := range infos {
+		devices[i] = UsbDevice{
+			Path:      info.Path,
+			VendorID:  info.VendorID,
+			ProductID: info.ProductID,
+			Mode:      skywallet.InfoMode(info).String(),
+		}
+	}
+
+	writeJSON(w, http.StatusOK, Response{
+		Data: UsbInfoResponse{
+			Devices: devices,
+		},
+	})
+}
+
+// operation starts op with the arguments decoded from the request body
+func (s *Server) operation(op operation) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+			return
+		}
+
+		run, err := op(http.MaxBytesReader(w, r.Body, maxRequestSize))
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		it := s.start(run)
+		s.reply(w, it)
+	}
+}
+
+// pending serves the pending request resources
+func (s *Server) pending(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimPrefix(r.URL.Path, pendingPrefix)
+
+	s.mu.Lock()
+	it, ok := s.interactions[id]
+	s.mu.Unlock()
+	if !ok {
+		writeError(w, http.StatusNotFound, errors.New("pending request not found"))
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		req := it.current()
+		if req == nil {
+			writeError(w, http.StatusConflict, errors.New("no pending request to answer"))
+			return
+		}
+		writeJSON(w, http.StatusAccepted, Response{
+			Pending: req,
+		})
+	case http.MethodPost:
+		var answer Answer
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestSize)).Decode(&answer); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		if !it.answer(answer) {
+			writeError(w, http.StatusConflict, errors.New("no pending request to answer"))
+			return
+		}
+		s.reply(w, it)
+	case http.MethodDelete:
+		if !it.answer(Answer{cancel: true}) {
+			writeError(w, http.StatusConflict, errors.New("no pending request to cancel"))
+			return
+		}
+		s.reply(w, it)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+	}
+}
+
+func (s *Server) setFirmwareProgress(p skywallet.FirmwareProgress) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.firmwareProgress = &p
+}
+
+// getFirmwareProgress replies the last progress event of the running or last firmware upload
+func (s *Server) getFirmwareProgress(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+		return
+	}
+
+	var resp Response
+	s.mu.Lock()
+	if s.firmwareProgress != nil {
+		resp.Data = *s.firmwareProgress
+	}
+	s.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// start runs the operation in the background
+func (s *Server) start(run runFunc) *interaction {
+	it := newInteraction(s.config.PendingTimeout)
+
+	s.mu.Lock()
+	s.interactions[it.id] = it
+	s.mu.Unlock()
+
+	go func() {
+		data, err := it.run(s.device, run)
+
+		// the pending request resource is gone once the result is replied
+		s.mu.Lock()
+		delete(s.interactions, it.id)
+		s.mu.Unlock()
+
+		it.done <- result{data: data, err: err}
+	}()
+
+	return it
+}
+
+// reply waits for the next pending request or the operation result and writes it
+func (s *Server) reply(w http.ResponseWriter, it *interaction) {
+	req, data, err := it.next()
+	switch {
+	case req != nil:
+		writeJSON(w, http.StatusAccepted, Response{
+			Pending: req,
+		})
+	case err != nil:
+		writeError(w, errorStatus(err), err)
+	default:
+		writeJSON(w, http.StatusOK, Response{
+			Data: data,
+		})
+	}
+}
+
+func errorStatus(err error) int {
+	var failure *skywallet.FailureError
+	var downgrade *firmware.DowngradeError
+	switch {
+	case errors.As(err, &failure):
+		return http.StatusUnprocessableEntity
+	case errors.As(err, &downgrade),
+		errors.Is(err, firmware.ErrUnsigned),
+		errors.Is(err, firmware.ErrNotBootloaderMode):
+		return http.StatusConflict
+	case errors.Is(err, skywallet.ErrNoDeviceConnected),
+		errors.Is(err, skywallet.ErrDeviceNotFound):
+		return http.StatusServiceUnavailable
+	case errors.Is(err, ErrPendingCancelled),
+		errors.Is(err, ErrPendingTimeout):
+		return http.StatusRequestTimeout
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	apiErr := &Error{
+		Message: err.Error(),
+	}
+
+	var failure *skywallet.FailureError
+	if errors.As(err, &failure) {
+		apiErr.Failure = failure.Code.String()
+	}
+
+	writeJSON(w, status, Response{
+		Error: apiErr,
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, resp Response) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		log.Errorf("failed to write response: %v", err)
+	}
+}
@@ -0,0 +1,250 @@
+package daemon
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/hardware-wallet-go/src/skywallet"
+	"github.com/skycoin/hardware-wallet-go/src/skywallet/emulator"
+)
+
+const (
+	testMnemonic = "cloud flower upset remain green metal below cup stem infant art thank"
+	testAddress  = "2EU3JbveHdkxW6z5tdhbbB2kRAWvXC2pLzw"
+)
+
+func newTestDaemon(options emulator.Options) *Server {
+	bus := emulator.InitBus(options)
+	driver := skywallet.NewDriverWithBus(skywallet.DeviceTypeEmulator, bus)
+	return New(skywallet.NewDeviceWithDriver(driver), Config{})
+}
+
+func newTestServer(options emulator.Options) *httptest.Server {
+	return httptest.NewServer(newTestDaemon(options).Handler())
+}
+
+// rawResponse is a Response keeping the data undecoded
+type rawResponse struct {
+	Data    json.RawMessage `json:"data"`
+	Pending *PendingRequest `json:"pending"`
+	Error   *Error          `json:"error"`
+}
+
+func do(t *testing.T, method, url string, body interface{}) (int, rawResponse) {
+	var buf bytes.Buffer
+	if body != nil {
+		require.NoError(t, json.NewEncoder(&buf).Encode(body))
+	}
+
+	req, err := http.NewRequest(method, url, &buf)
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	return doRequest(t, req)
+}
+
+func doRequest(t *testing.T, req *http.Request) (int, rawResponse) {
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	var r rawResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&r))
+	return resp.StatusCode, r
+}
+
+func TestAvailable(t *testing.T) {
+	server := newTestServer(emulator.Options{})
+	defer server.Close()
+
+	status, resp := do(t, http.MethodGet, server.URL+"/api/v1/available", nil)
+	require.Equal(t, http.StatusOK, status)
+	// only physical devices are reported as available
+	require.JSONEq(t, `{"available":false}`, string(resp.Data))
+}
+
+func TestGuard(t *testing.T) {
+	daemon := New(skywallet.NewDeviceWithDriver(skywallet.NewDriverWithBus(skywallet.DeviceTypeEmulator, emulator.InitBus(emulator.Options{}))), Config{
+		AllowedOrigins: []string{"https://wallet.example.com"},
+		Token:          "secret",
+	})
+	server := httptest.NewServer(daemon.Handler())
+	defer server.Close()
+
+	newRequest := func(method, path, contentType string) *http.Request {
+		req, err := http.NewRequest(method, server.URL+path, bytes.NewBufferString("{}"))
+		require.NoError(t, err)
+		req.Header.Set("Authorization", "Bearer secret")
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+		return req
+	}
+
+	// dns rebinding
+	req := newRequest(http.MethodGet, "/api/v1/available", "")
+	req.Host = "evil.example.com"
+	status, resp := doRequest(t, req)
+	require.Equal(t, http.StatusForbidden, status)
+	require.Equal(t, "host not allowed", resp.Error.Message)
+
+	req = newRequest(http.MethodGet, "/api/v1/available", "")
+	req.Header.Set("Origin", "https://evil.example.com")
+	status, resp = doRequest(t, req)
+	require.Equal(t, http.StatusForbidden, status)
+	require.Equal(t, "origin not allowed", resp.Error.Message)
+
+	// preflight requests carry no credentials
+	req = newRequest(http.MethodOptions, "/api/v1/connected", "")
+	req.Header.Del("Authorization")
+	req.Header.Set("Origin", "https://wallet.example.com")
+	res, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	res.Body.Close()
+	require.Equal(t, http.StatusNoContent, res.StatusCode)
+	require.Equal(t, "https://wallet.example.com", res.Header.Get("Access-Control-Allow-Origin"))
+	require.Contains(t, res.Header.Get("Access-Control-Allow-Headers"), "Authorization")
+
+	req = newRequest(http.MethodGet, "/api/v1/available", "")
+	req.Header.Del("Authorization")
+	status, resp = doRequest(t, req)
+	require.Equal(t, http.StatusUnauthorized, status)
+	require.Equal(t, "invalid or missing token", resp.Error.Message)
+
+	req = newRequest(http.MethodGet, "/api/v1/available", "")
+	req.Header.Set("Authorization", "Bearer guess")
+	status, _ = doRequest(t, req)
+	require.Equal(t, http.StatusUnauthorized, status)
+
+	// simple cross origin requests can't set a json content type without a preflight
+	status, resp = doRequest(t, newRequest(http.MethodPost, "/api/v1/connected", "text/plain"))
+	require.Equal(t, http.StatusUnsupportedMediaType, status)
+	require.Equal(t, "content type must be application/json", resp.Error.Message)
+
+	req = newRequest(http.MethodPost, "/api/v1/connected", "application/json; charset=utf-8")
+	req.Header.Set("Origin", "https://wallet.example.com")
+	status, resp = doRequest(t, req)
+	require.Equal(t, http.StatusOK, status)
+	require.JSONEq(t, `{"connected":true}`, string(resp.Data))
+}
+
+func TestOperations(t *testing.T) {
+	server := newTestServer(emulator.Options{Mnemonic: testMnemonic})
+	defer server.Close()
+
+	status, resp := do(t, http.MethodPost, server.URL+"/api/v1/connected", nil)
+	require.Equal(t, http.StatusOK, status)
+	require.JSONEq(t, `{"connected":true}`, string(resp.Data))
+
+	status, resp = do(t, http.MethodPost, server.URL+"/api/v1/mode", nil)
+	require.Equal(t, http.StatusOK, status)
+	require.JSONEq(t, `{"mode":"FIRMWARE"}`, string(resp.Data))
+
+	for _, op := range []string{"getRawEntropy", "getMixedEntropy"} {
+		status, resp = do(t, http.MethodPost, server.URL+"/api/v1/"+op, EntropyRequest{EntropyBytes: 100})
+		require.Equal(t, http.StatusOK, status)
+		var data EntropyResponse
+		require.NoError(t, json.Unmarshal(resp.Data, &data))
+		require.Len(t, data.Entropy, 100)
+
+		status, _ = do(t, http.MethodPost, server.URL+"/api/v1/"+op, EntropyRequest{})
+		require.Equal(t, http.StatusBadRequest, status)
+		status, _ = do(t, http.MethodPost, server.URL+"/api/v1/"+op, EntropyRequest{EntropyBytes: maxEntropyBytes + 1})
+		require.Equal(t, http.StatusBadRequest, status)
+	}
+
+	// the Failure answering a Cancel is its result
+	status, resp = do(t, http.MethodPost, server.URL+"/api/v1/cancel", nil)
+	require.Equal(t, http.StatusOK, status)
+	require.JSONEq(t, `{"message":"Action cancelled by user"}`, string(resp.Data))
+
+	// usb details are only known for physical devices
+	status, resp = do(t, http.MethodGet, server.URL+"/api/v1/usbInfo", nil)
+	require.Equal(t, http.StatusInternalServerError, status)
+	require.NotEmpty(t, resp.Error.Message)
+}
+
+func TestAddressGen(t *testing.T) {
+	server := newTestServer(emulator.Options{Mnemonic: testMnemonic})
+	defer server.Close()
+
+	status, resp := do(t, http.MethodPost, server.URL+"/api/v1/addressGen", AddressGenRequest{AddressN: 1})
+	require.Equal(t, http.StatusOK, status)
+
+	var data AddressGenResponse
+	require.NoError(t, json.Unmarshal(resp.Data, &data))
+	require.Equal(t, []string{testAddress}, data.Addresses)
+
+	status, resp = do(t, http.MethodPost, server.URL+"/api/v1/addressGen", AddressGenRequest{})
+	require.Equal(t, http.StatusBadRequest, status)
+	require.Equal(t, skywallet.ErrAddressNZero.Error(), resp.Error.Message)
+}
+
+func TestPendingPin(t *testing.T) {
+	server := newTestServer(emulator.Options{Mnemonic: testMnemonic, PIN: "1234"})
+	defer server.Close()
+
+	status, resp := do(t, http.MethodPost, server.URL+"/api/v1/addressGen", AddressGenRequest{AddressN: 1})
+	require.Equal(t, http.StatusAccepted, status)
+	require.Equal(t, PendingPinMatrix, resp.Pending.Type)
+	require.Equal(t, "PinMatrixRequestType_Current", resp.Pending.PinMatrixType)
+
+	pendingURL := server.URL + "/api/v1/pending/" + resp.Pending.ID
+	status, get := do(t, http.MethodGet, pendingURL, nil)
+	require.Equal(t, http.StatusAccepted, status)
+	require.Equal(t, resp.Pending, get.Pending)
+
+	status, resp = do(t, http.MethodPost, pendingURL, Answer{Pin: "4321"})
+	require.Equal(t, http.StatusUnprocessableEntity, status)
+	require.Equal(t, "Failure_PinInvalid", resp.Error.Failure)
+
+	status, resp = do(t, http.MethodPost, server.URL+"/api/v1/addressGen", AddressGenRequest{AddressN: 1})
+	require.Equal(t, http.StatusAccepted, status)
+
+	status, resp = do(t, http.MethodPost, server.URL+"/api/v1/pending/"+resp.Pending.ID, Answer{Pin: "1234"})
+	require.Equal(t, http.StatusOK, status)
+	var data AddressGenResponse
+	require.NoError(t, json.Unmarshal(resp.Data, &data))
+	require.Equal(t, []string{testAddress}, data.Addresses)
+}
+
+func TestPendingCancel(t *testing.T) {
+	server := newTestServer(emulator.Options{Mnemonic: testMnemonic, PassphraseProtection: true})
+	defer server.Close()
+
+	status, resp := do(t, http.MethodPost, server.URL+"/api/v1/addressGen", AddressGenRequest{AddressN: 1})
+	require.Equal(t, http.StatusAccepted, status)
+	require.Equal(t, PendingPassphrase, resp.Pending.Type)
+
+	pendingURL := server.URL + "/api/v1/pending/" + resp.Pending.ID
+	status, resp = do(t, http.MethodDelete, pendingURL, nil)
+	require.Equal(t, http.StatusRequestTimeout, status)
+	require.Equal(t, ErrPendingCancelled.Error(), resp.Error.Message)
+
+	status, _ = do(t, http.MethodPost, pendingURL, Answer{})
+	require.Equal(t, http.StatusNotFound, status)
+}
+
+func TestFirmwareProgress(t *testing.T) {
+	daemon := newTestDaemon(emulator.Options{})
+	server := httptest.NewServer(daemon.Handler())
+	defer server.Close()
+
+	status, resp := do(t, http.MethodGet, server.URL+"/api/v1/firmwareProgress", nil)
+	require.Equal(t, http.StatusOK, status)
+	require.Empty(t, resp.Data)
+
+	// firmware uploads need a physical device, the events are sent as the device would
+	daemon.setFirmwareProgress(skywallet.FirmwareProgress{
+		Stage:   skywallet.FirmwareStageTransfer,
+		Written: 512,
+		Total:   1024,
+	})
+	status, resp = do(t, http.MethodGet, server.URL+"/api/v1/firmwareProgress", nil)
+	require.Equal(t, http.StatusOK, status)
+	require.JSONEq(t, `{"stage":"transfer","written":512,"total":1024}`, string(resp.Data))
+}
@@ -0,0 +1,201 @@
+package daemon
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	messages "github.com/skycoin/hardware-wallet-protob/go"
+
+	"github.com/skycoin/hardware-wallet-go/src/skywallet"
+)
+
+// Types of PendingRequest
+const (
+	PendingPinMatrix  = "pin_matrix"
+	PendingPassphrase = "passphrase"
+	PendingWord       = "word"
+	PendingButton     = "button"
+)
+
+// PendingRequest is a device request waiting for the client answer
+type PendingRequest struct {
+	ID   string `json:"id"`
+	Type string `json:"type"`
+	// PinMatrixType is set for pin_matrix requests, e.g. PinMatrixRequestType_Current
+	PinMatrixType string `json:"pin_matrix_type,omitempty"`
+	// ButtonType is set for button requests, e.g. ButtonRequest_ProtectCall
+	ButtonType string `json:"button_type,omitempty"`
+}
+
+// Answer is the client answer to a PendingRequest.
+// Button requests are answered with an empty object once the user is
+// told to confirm the action on the device.
+type Answer struct {
+	// Pin encoded as positions in the matrix shown by the device
+	Pin        string `json:"pin,omitempty"`
+	Passphrase string `json:"passphrase,omitempty"`
+	Word       string `json:"word,omitempty"`
+
+	cancel bool
+}
+
+// result is the outcome of an operation
+type result struct {
+	data interface{}
+	err  error
+}
+
+// interaction is an operation running in the background,
+// it implements skywallet.Prompter forwarding the device requests to the client
+type interaction struct {
+	id      string
+	timeout time.Duration
+
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	requests chan *PendingRequest
+	answers  chan Answer
+	done     chan result
+
+	mu      sync.Mutex
+	pending *PendingRequest
+}
+
+func newInteraction(timeout time.Duration) *interaction {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &interaction{
+		id:       newID(),
+		timeout:  timeout,
+		ctx:      ctx,
+		cancel:   cancel,
+		requests: make(chan *PendingRequest),
+		answers:  make(chan Answer),
+		done:     make(chan result, 1),
+	}
+}
+
+func newID() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		log.Panic(err)
+	}
+	return hex.EncodeToString(b[:])
+}
+
+// run opens a session on device and runs the operation
+func (it *interaction) run(device skywallet.Devicer, run runFunc) (interface{}, error) {
+	defer it.cancel()
+
+	session, err := device.OpenSession()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := run(it.ctx, session, it)
+	if err := session.Close(); err != nil && err != skywallet.ErrSessionClosed {
+		log.Errorf("failed to close session: %v", err)
+	}
+
+	return data, err
+}
+
+// next waits for the next pending request or the operation result
+func (it *interaction) next() (*PendingRequest, interface{}, error) {
+	select {
+	case req := <-it.requests:
+		it.mu.Lock()
+		it.pending = req
+		it.mu.Unlock()
+		return req, nil, nil
+	case res := <-it.done:
+		return nil, res.data, res.err
+	}
+}
+
+// current returns the pending request waiting for an answer, nil if none
+func (it *interaction) current() *PendingRequest {
+	it.mu.Lock()
+	defer it.mu.Unlock()
+	return it.pending
+}
+
+// answer hands the answer to the operation, it returns false if no request was pending
+func (it *interaction) answer(answer Answer) bool {
+	it.mu.Lock()
+	pending := it.pending
+	it.pending = nil
+	it.mu.Unlock()
+
+	if pending == nil {
+		return false
+	}
+
+	select {
+	case it.answers <- answer:
+		return true
+	case <-it.ctx.Done():
+		// the operation gave up waiting, next returns its result
+		return true
+	}
+}
+
+// prompt sends req to the client and waits for the answer
+func (it *interaction) prompt(req PendingRequest) (Answer, error) {
+	req.ID = it.id
+
+	timer := time.NewTimer(it.timeout)
+	defer timer.Stop()
+
+	select {
+	case it.requests <- &req:
+	case <-timer.C:
+		return Answer{}, ErrPendingTimeout
+	}
+
+	select {
+	case answer := <-it.answers:
+		if answer.cancel {
+			return Answer{}, ErrPendingCancelled
+		}
+		return answer, nil
+	case <-timer.C:
+		it.mu.Lock()
+		it.pending = nil
+		it.mu.Unlock()
+		return Answer{}, ErrPendingTimeout
+	}
+}
+
+func (it *interaction) PinMatrix(pinType messages.PinMatrixRequestType) (string, error) {
+	answer, err := it.prompt(PendingRequest{
+		Type:          PendingPinMatrix,
+		PinMatrixType: pinType.String(),
+	})
+	return answer.Pin, err
+}
+
+func (it *interaction) Passphrase() (string, error) {
+	answer, err := it.prompt(PendingRequest{
+		Type: PendingPassphrase,
+	})
+	return answer.Passphrase, err
+}
+
+func (it *interaction) Word() (string, error) {
+	answer, err := it.prompt(PendingRequest{
+		Type: PendingWord,
+	})
+	return answer.Word, err
+}
+
+func (it *interaction) ButtonConfirm(code messages.ButtonRequestType) error {
+	_, err := it.prompt(PendingRequest{
+		Type:       PendingButton,
+		ButtonType: code.String(),
+	})
+	return err
+}
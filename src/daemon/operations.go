@@ -0,0 +1,459 @@
+package daemon
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	messages "github.com/skycoin/hardware-wallet-protob/go"
+
+	"github.com/skycoin/hardware-wallet-go/src/skywallet"
+	"github.com/skycoin/hardware-wallet-go/src/skywallet/firmware"
+	"github.com/skycoin/hardware-wallet-go/src/skywallet/wire"
+)
+
+// runFunc runs an operation on session, prompter forwards the device requests to the client
+type runFunc func(ctx context.Context, session *skywallet.Session, prompter skywallet.Prompter) (interface{}, error)
+
+// operation decodes the operation arguments from body and returns the function running it
+type operation func(body io.Reader) (runFunc, error)
+
+// maxEntropyBytes is the most entropy returned by the getRawEntropy and getMixedEntropy operations
+const maxEntropyBytes = 1 << 20
+
+// operations are the Devicer functions run through a session. The acknowledgements like
+// PinMatrixAck are sent by answering pending requests, the typed variants like GetAddresses
+// return the same data as their operation, Available and GetUsbInfo are GET requests, and
+// Reflash is not exposed as it waits for the wallet to be replugged.
+var operations = map[string]operation{
+	"addressGen":            addressGen,
+	"applySettings":         applySettings,
+	"backup":                backup,
+	"cancel":                cancel,
+	"checkMessageSignature": checkMessageSignature,
+	"changePin":             changePin,
+	"connected":             connected,
+	"features":              features,
+	"firmwareUpload":        firmwareUpload,
+	"generateMnemonic":      generateMnemonic,
+	"getMixedEntropy":       entropyOperation(skywallet.MessageDeviceGetMixedEntropy),
+	"getRawEntropy":         entropyOperation(skywallet.MessageDeviceGetRawEntropy),
+	"mode":                  mode,
+	"recovery":              recovery,
+	"setMnemonic":           setMnemonic,
+	"signMessage":           signMessage,
+	"transactionSign":       transactionSign,
+	"wipe":                  wipe,
+}
+
+// SuccessResponse is the result of the operations answered with a Success message
+type SuccessResponse struct {
+	Message string `json:"message"`
+}
+
+// AddressGenRequest are the arguments of the addressGen operation
+type AddressGenRequest struct {
+	AddressN       uint32 `json:"address_n"`
+	StartIndex     uint32 `json:"start_index"`
+	ConfirmAddress bool   `json:"confirm_address"`
+}
+
+// AddressGenResponse is the result of the addressGen operation
+type AddressGenResponse struct {
+	Addresses []string `json:"addresses"`
+}
+
+// ApplySettingsRequest are the arguments of the applySettings operation
+type ApplySettingsRequest struct {
+	UsePassphrase *bool  `json:"use_passphrase,omitempty"`
+	Label         string `json:"label"`
+	Language      string `json:"language"`
+}
+
+// CheckMessageSignatureRequest are the arguments of the checkMessageSignature operation
+type CheckMessageSignatureRequest struct {
+	Message   string `json:"message"`
+	Signature string `json:"signature"`
+	Address   string `json:"address"`
+}
+
+// ChangePinRequest are the arguments of the changePin operation
+type ChangePinRequest struct {
+	RemovePin *bool `json:"remove_pin,omitempty"`
+}
+
+// FirmwareUploadRequest are the arguments of the firmwareUpload operation
+type FirmwareUploadRequest struct {
+	// Firmware is the firmware image, base64 encoded in the json body
+	Firmware []byte `json:"firmware"`
+	// ImageVersion is the version the firmware is released under, e.g. 1.7.0, the image does not hold it
+	ImageVersion   string `json:"image_version"`
+	AllowDowngrade bool   `json:"allow_downgrade"`
+	AllowUnsigned  bool   `json:"allow_unsigned"`
+}
+
+// ConnectedResponse is the result of the connected operation
+type ConnectedResponse struct {
+	Connected bool `json:"connected"`
+}
+
+// EntropyRequest are the arguments of the getRawEntropy and getMixedEntropy operations
+type EntropyRequest struct {
+	EntropyBytes uint32 `json:"entropy_bytes"`
+}
+
+// EntropyResponse is the result of the getRawEntropy and getMixedEntropy operations
+type EntropyResponse struct {
+	// Entropy is base64 encoded in the json body
+	Entropy []byte `json:"entropy"`
+}
+
+// ModeResponse is the result of the mode operation
+type ModeResponse struct {
+	// Mode is FIRMWARE or BOOTLOADER
+	Mode string `json:"mode"`
+}
+
+// GenerateMnemonicRequest are the arguments of the generateMnemonic operation
+type GenerateMnemonicRequest struct {
+	WordCount     uint32 `json:"word_count"`
+	UsePassphrase bool   `json:"use_passphrase"`
+}
+
+// RecoveryRequest are the arguments of the recovery operation
+type RecoveryRequest struct {
+	WordCount     uint32 `json:"word_count"`
+	UsePassphrase *bool  `json:"use_passphrase,omitempty"`
+	DryRun        bool   `json:"dry_run"`
+}
+
+// SetMnemonicRequest are the arguments of the setMnemonic operation
+type SetMnemonicRequest struct {
+	Mnemonic string `json:"mnemonic"`
+}
+
+// SignMessageRequest are the arguments of the signMessage operation
+type SignMessageRequest struct {
+	AddressN int    `json:"address_n"`
+	Message  string `json:"message"`
+}
+
+// SignMessageResponse is the result of the signMessage operation
+type SignMessageResponse struct {
+	Signature string `json:"signature"`
+}
+
+// TransactionSignRequest are the arguments of the transactionSign operation
+type TransactionSignRequest struct {
+	Inputs  []*messages.SkycoinTransactionInput  `json:"inputs"`
+	Outputs []*messages.SkycoinTransactionOutput `json:"outputs"`
+}
+
+// TransactionSignResponse is the result of the transactionSign operation
+type TransactionSignResponse struct {
+	Signatures []string `json:"signatures"`
+}
+
+// decode decodes the json body into args, an empty body leaves args unchanged
+func decode(body io.Reader, args interface{}) error {
+	if err := json.NewDecoder(body).Decode(args); err != nil && err != io.EOF {
+		return err
+	}
+	return nil
+}
+
+// runFlow answers the device requests following msg until the device sends a terminal message
+func runFlow(ctx context.Context, session *skywallet.Session, prompter skywallet.Prompter, msg wire.Message, err error) (wire.Message, error) {
+	if err != nil {
+		return wire.Message{}, err
+	}
+	return skywallet.RunFlowContext(ctx, session, msg, prompter)
+}
+
+// success returns the SuccessResponse of the operations answered with a Success message
+func success(msg wire.Message, err error) (interface{}, error) {
+	if err != nil {
+		return nil, err
+	}
+
+	message, err := skywallet.DecodeSuccessMsg(msg)
+	if err != nil {
+		return nil, err
+	}
+
+	return SuccessResponse{
+		Message: message,
+	}, nil
+}
+
+func addressGen(body io.Reader) (runFunc, error) {
+	var args AddressGenRequest
+	if err := decode(body, &args); err != nil {
+		return nil, err
+	}
+	if args.AddressN == 0 {
+		return nil, skywallet.ErrAddressNZero
+	}
+
+	return func(ctx context.Context, session *skywallet.Session, prompter skywallet.Prompter) (interface{}, error) {
+		msg, err := session.AddressGenContext(ctx, args.AddressN, args.StartIndex, args.ConfirmAddress)
+		msg, err = runFlow(ctx, session, prompter, msg, err)
+		if err != nil {
+			return nil, err
+		}
+
+		addresses, err := skywallet.DecodeResponseSkycoinAddress(msg)
+		if err != nil {
+			return nil, err
+		}
+
+		return AddressGenResponse{
+			Addresses: addresses,
+		}, nil
+	}, nil
+}
+
+func applySettings(body io.Reader) (runFunc, error) {
+	var args ApplySettingsRequest
+	if err := decode(body, &args); err != nil {
+		return nil, err
+	}
+
+	return func(ctx context.Context, session *skywallet.Session, prompter skywallet.Prompter) (interface{}, error) {
+		msg, err := session.ApplySettingsContext(ctx, args.UsePassphrase, args.Label, args.Language)
+		return success(runFlow(ctx, session, prompter, msg, err))
+	}, nil
+}
+
+func backup(body io.Reader) (runFunc, error) {
+	return func(ctx context.Context, session *skywallet.Session, prompter skywallet.Prompter) (interface{}, error) {
+		msg, err := session.BackupContext(ctx)
+		return success(runFlow(ctx, session, prompter, msg, err))
+	}, nil
+}
+
+func cancel(body io.Reader) (runFunc, error) {
+	return func(ctx context.Context, session *skywallet.Session, prompter skywallet.Prompter) (interface{}, error) {
+		msg, err := session.CancelContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		// the device answers a Cancel with a Failure
+		message, err := skywallet.DecodeSuccessOrFailMsg(msg)
+		if err != nil {
+			return nil, err
+		}
+
+		return SuccessResponse{
+			Message: message,
+		}, nil
+	}, nil
+}
+
+func checkMessageSignature(body io.Reader) (runFunc, error) {
+	var args CheckMessageSignatureRequest
+	if err := decode(body, &args); err != nil {
+		return nil, err
+	}
+
+	return func(ctx context.Context, session *skywallet.Session, prompter skywallet.Prompter) (interface{}, error) {
+		msg, err := session.CheckMessageSignatureContext(ctx, args.Message, args.Signature, args.Address)
+		return success(runFlow(ctx, session, prompter, msg, err))
+	}, nil
+}
+
+func changePin(body io.Reader) (runFunc, error) {
+	var args ChangePinRequest
+	if err := decode(body, &args); err != nil {
+		return nil, err
+	}
+
+	return func(ctx context.Context, session *skywallet.Session, prompter skywallet.Prompter) (interface{}, error) {
+		msg, err := session.ChangePinContext(ctx, args.RemovePin)
+		return success(runFlow(ctx, session, prompter, msg, err))
+	}, nil
+}
+
+func connected(body io.Reader) (runFunc, error) {
+	return func(ctx context.Context, session *skywallet.Session, prompter skywallet.Prompter) (interface{}, error) {
+		return ConnectedResponse{
+			Connected: session.Connected(),
+		}, nil
+	}, nil
+}
+
+// entropyOperation returns the operation reading entropy from the device through the requests built by getEntropyMsgBuilder
+func entropyOperation(getEntropyMsgBuilder func(entropyBytes uint32) ([][64]byte, error)) operation {
+	return func(body io.Reader) (runFunc, error) {
+		var args EntropyRequest
+		if err := decode(body, &args); err != nil {
+			return nil, err
+		}
+		if args.EntropyBytes == 0 || args.EntropyBytes > maxEntropyBytes {
+			return nil, fmt.Errorf("entropy_bytes must be between 1 and %d", maxEntropyBytes)
+		}
+
+		return func(ctx context.Context, session *skywallet.Session, prompter skywallet.Prompter) (interface{}, error) {
+			entropy := make([]byte, args.EntropyBytes)
+			if _, err := io.ReadFull(session.NewEntropyReader(ctx, getEntropyMsgBuilder), entropy); err != nil {
+				return nil, err
+			}
+			return EntropyResponse{
+				Entropy: entropy,
+			}, nil
+		}, nil
+	}
+}
+
+func features(body io.Reader) (runFunc, error) {
+	return func(ctx context.Context, session *skywallet.Session, prompter skywallet.Prompter) (interface{}, error) {
+		return session.FeaturesContext(ctx)
+	}, nil
+}
+
+func firmwareUpload(body io.Reader) (runFunc, error) {
+	var args FirmwareUploadRequest
+	if err := decode(body, &args); err != nil {
+		return nil, err
+	}
+	img, err := firmware.Parse(args.Firmware)
+	if err != nil {
+		return nil, err
+	}
+	var version firmware.Version
+	if args.ImageVersion != "" {
+		version, err = firmware.ParseVersion(args.ImageVersion)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return func(ctx context.Context, session *skywallet.Session, prompter skywallet.Prompter) (interface{}, error) {
+		features, err := session.FeaturesContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := img.Validate(features, firmware.Policy{
+			AllowDowngrade: args.AllowDowngrade,
+			AllowUnsigned:  args.AllowUnsigned,
+			ImageVersion:   version,
+		}); err != nil {
+			return nil, err
+		}
+
+		if err := session.FirmwareUploadContext(ctx, img.Bytes(), img.Hash()); err != nil {
+			return nil, err
+		}
+		return SuccessResponse{
+			Message: "Firmware uploaded",
+		}, nil
+	}, nil
+}
+
+func generateMnemonic(body io.Reader) (runFunc, error) {
+	var args GenerateMnemonicRequest
+	if err := decode(body, &args); err != nil {
+		return nil, err
+	}
+
+	return func(ctx context.Context, session *skywallet.Session, prompter skywallet.Prompter) (interface{}, error) {
+		msg, err := session.GenerateMnemonicContext(ctx, args.WordCount, args.UsePassphrase)
+		return success(runFlow(ctx, session, prompter, msg, err))
+	}, nil
+}
+
+func mode(body io.Reader) (runFunc, error) {
+	return func(ctx context.Context, session *skywallet.Session, prompter skywallet.Prompter) (interface{}, error) {
+		features, err := session.FeaturesContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return ModeResponse{
+			Mode: skywallet.FeaturesMode(features).String(),
+		}, nil
+	}, nil
+}
+
+func recovery(body io.Reader) (runFunc, error) {
+	var args RecoveryRequest
+	if err := decode(body, &args); err != nil {
+		return nil, err
+	}
+
+	return func(ctx context.Context, session *skywallet.Session, prompter skywallet.Prompter) (interface{}, error) {
+		msg, err := session.RecoveryContext(ctx, args.WordCount, args.UsePassphrase, args.DryRun)
+		return success(runFlow(ctx, session, prompter, msg, err))
+	}, nil
+}
+
+func setMnemonic(body io.Reader) (runFunc, error) {
+	var args SetMnemonicRequest
+	if err := decode(body, &args); err != nil {
+		return nil, err
+	}
+
+	return func(ctx context.Context, session *skywallet.Session, prompter skywallet.Prompter) (interface{}, error) {
+		msg, err := session.SetMnemonicContext(ctx, args.Mnemonic)
+		return success(runFlow(ctx, session, prompter, msg, err))
+	}, nil
+}
+
+func signMessage(body io.Reader) (runFunc, error) {
+	var args SignMessageRequest
+	if err := decode(body, &args); err != nil {
+		return nil, err
+	}
+
+	return func(ctx context.Context, session *skywallet.Session, prompter skywallet.Prompter) (interface{}, error) {
+		msg, err := session.SignMessageContext(ctx, args.AddressN, args.Message)
+		msg, err = runFlow(ctx, session, prompter, msg, err)
+		if err != nil {
+			return nil, err
+		}
+
+		signature, err := skywallet.DecodeResponseSkycoinSignMessage(msg)
+		if err != nil {
+			return nil, err
+		}
+
+		return SignMessageResponse{
+			Signature: signature,
+		}, nil
+	}, nil
+}
+
+func transactionSign(body io.Reader) (runFunc, error) {
+	var args TransactionSignRequest
+	if err := decode(body, &args); err != nil {
+		return nil, err
+	}
+	if len(args.Inputs) > skywallet.MaxTransactionInputs || len(args.Outputs) > skywallet.MaxTransactionOutputs {
+		return nil, skywallet.ErrTransactionTooLarge
+	}
+
+	return func(ctx context.Context, session *skywallet.Session, prompter skywallet.Prompter) (interface{}, error) {
+		msg, err := session.TransactionSignContext(ctx, args.Inputs, args.Outputs)
+		msg, err = runFlow(ctx, session, prompter, msg, err)
+		if err != nil {
+			return nil, err
+		}
+
+		signatures, err := skywallet.DecodeResponseTransactionSign(msg)
+		if err != nil {
+			return nil, err
+		}
+
+		return TransactionSignResponse{
+			Signatures: signatures,
+		}, nil
+	}, nil
+}
+
+func wipe(body io.Reader) (runFunc, error) {
+	return func(ctx context.Context, session *skywallet.Session, prompter skywallet.Prompter) (interface{}, error) {
+		msg, err := session.WipeContext(ctx)
+		return success(runFlow(ctx, session, prompter, msg, err))
+	}, nil
+}
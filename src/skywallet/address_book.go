@@ -0,0 +1,232 @@
+package skywallet
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/skycoin/skycoin/src/cipher"
+)
+
+// DefaultAddressBatchSize is the number of addresses derived per AddressGen request by an AddressBook
+const DefaultAddressBatchSize = 20
+
+// ErrGapLimitZero is returned if the gap limit of an address discovery is 0
+var ErrGapLimitZero = errors.New("gap limit should be greater than 0")
+
+// AddressCache is the list of addresses of a wallet cached by an AddressBook
+type AddressCache struct {
+	DeviceID             string `json:"device_id"`
+	PassphraseProtection bool   `json:"passphrase_protection"`
+	// Addresses are the addresses of the wallet from index 0
+	Addresses []string `json:"addresses"`
+	// Used is the number of addresses up to the last one with history found by AddressBook.Discover
+	Used int `json:"used"`
+
+	path string
+}
+
+// Name returns the name of the cache file, without extension
+func (c *AddressCache) Name() string {
+	return strings.TrimSuffix(filepath.Base(c.path), ".json")
+}
+
+// AddressBook derives the addresses of a wallet in batches and caches them on disk,
+// so that they can be listed without asking the device again.
+// Caches are keyed by the device ID and, for passphrase protected devices,
+// by the first address of the wallet opened by the passphrase.
+type AddressBook struct {
+	// Dir is the directory holding the cache files
+	Dir string
+	// BatchSize is the number of addresses derived per AddressGen request, DefaultAddressBatchSize if 0
+	BatchSize uint32
+}
+
+// NewAddressBook returns an AddressBook caching the addresses in dir
+func NewAddressBook(dir string) *AddressBook {
+	return &AddressBook{
+		Dir:       dir,
+		BatchSize: DefaultAddressBatchSize,
+	}
+}
+
+// Sync derives the first n addresses of the wallet in s that are not cached yet
+func (b *AddressBook) Sync(ctx context.Context, s *Session, n uint32) (*AddressCache, error) {
+	cache, err := b.open(ctx, s)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := b.derive(ctx, s, cache, int(n)); err != nil {
+		return nil, err
+	}
+
+	return cache, b.save(cache)
+}
+
+// Discover scans the addresses of the wallet in s from index 0 until gapLimit consecutive
+// addresses have no history according to hasHistory. The Used field of the returned
+// cache is the number of addresses up to the last one with history.
+func (b *AddressBook) Discover(ctx context.Context, s *Session, gapLimit uint32, hasHistory func(cipher.Address) (bool, error)) (*AddressCache, error) {
+	if gapLimit == 0 {
+		return nil, ErrGapLimitZero
+	}
+
+	cache, err := b.open(ctx, s)
+	if err != nil {
+		return nil, err
+	}
+
+	cache.Used = 0
+	for i, gap := 0, uint32(0); gap < gapLimit; i++ {
+		if i >= len(cache.Addresses) {
+			if err := b.derive(ctx, s, cache, i+int(b.batchSize())); err != nil {
+				return nil, err
+			}
+		}
+
+		address, err := cipher.DecodeBase58Address(cache.Addresses[i])
+		if err != nil {
+			return nil, err
+		}
+
+		used, err := hasHistory(address)
+		if err != nil {
+			return nil, err
+		}
+
+		if used {
+			cache.Used = i + 1
+			gap = 0
+		} else {
+			gap++
+		}
+	}
+
+	return cache, b.save(cache)
+}
+
+// List returns the cached wallets of the device with the given ID, of every device if deviceID is empty
+func (b *AddressBook) List(deviceID string) ([]*AddressCache, error) {
+	paths, err := filepath.Glob(filepath.Join(b.Dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	var caches []*AddressCache
+	for _, path := range paths {
+		cache, err := loadAddressCache(path)
+		if err != nil {
+			return nil, err
+		}
+		if deviceID == "" || cache.DeviceID == deviceID {
+			caches = append(caches, cache)
+		}
+	}
+
+	return caches, nil
+}
+
+func (b *AddressBook) batchSize() uint32 {
+	if b.BatchSize == 0 {
+		return DefaultAddressBatchSize
+	}
+	return b.BatchSize
+}
+
+// open loads the cache of the wallet in s
+func (b *AddressBook) open(ctx context.Context, s *Session) (*AddressCache, error) {
+	features, err := s.FeaturesContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !features.GetInitialized() {
+		return nil, ErrNotInitialized
+	}
+
+	name := features.GetDeviceId()
+	var first cipher.Address
+	if features.GetPassphraseProtection() {
+		// every passphrase opens a different wallet, told apart by its first address
+		addresses, err := s.GetAddressesContext(ctx, 1, 0, false)
+		if err != nil {
+			return nil, err
+		}
+		first = addresses[0]
+		name += "-" + cipher.SumSHA256([]byte(first.String())).Hex()[:16]
+	}
+
+	path := filepath.Join(b.Dir, name+".json")
+	cache, err := loadAddressCache(path)
+	switch {
+	case os.IsNotExist(err):
+		cache = &AddressCache{
+			DeviceID:             features.GetDeviceId(),
+			PassphraseProtection: features.GetPassphraseProtection(),
+			path:                 path,
+		}
+		if !first.Null() {
+			cache.Addresses = []string{first.String()}
+		}
+	case err != nil:
+		return nil, err
+	}
+
+	return cache, nil
+}
+
+// derive asks the device for the addresses missing in cache up to n
+func (b *AddressBook) derive(ctx context.Context, s *Session, cache *AddressCache, n int) error {
+	for len(cache.Addresses) < n {
+		count := uint32(n - len(cache.Addresses))
+		if count > b.batchSize() {
+			count = b.batchSize()
+		}
+
+		addresses, err := s.GetAddressesContext(ctx, count, uint32(len(cache.Addresses)), false)
+		if err != nil {
+			return err
+		}
+		for _, address := range addresses {
+			cache.Addresses = append(cache.Addresses, address.String())
+		}
+	}
+	return nil
+}
+
+func (b *AddressBook) save(cache *AddressCache) error {
+	if err := os.MkdirAll(b.Dir, 0700); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(cache, "", "    ")
+	if err != nil {
+		return err
+	}
+
+	// write to a temporary file first so that an interrupted sync keeps the previous cache
+	tmp := cache.path + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, cache.path)
+}
+
+func loadAddressCache(path string) (*AddressCache, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	cache := &AddressCache{
+		path: path,
+	}
+	if err := json.Unmarshal(data, cache); err != nil {
+		return nil, err
+	}
+	return cache, nil
+}
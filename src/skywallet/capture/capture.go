@@ -0,0 +1,105 @@
+/*
+Package capture records the usb reports exchanged with a wallet and replays
+them.
+
+A capture is a json lines file, one Report per line in the order the reports
+went through the connection:
+
+	{"time":"2020-05-04T10:21:03.125+02:00","direction":"write","kind":"MessageType_GenerateMnemonic","data":"3f2323..."}
+	{"time":"2020-05-04T10:21:03.141+02:00","direction":"read","kind":"MessageType_EntropyRequest","data":"3f2323..."}
+
+Sessions are recorded by wrapping a usb.Device with NewRecorder or a usb.Bus
+with NewRecordingBus, e.g. through skywallet.WithCapture or the --capture
+option of the cli. A ReplayBus serves a capture back to the host, which allows
+turning a session with a real device into a regression test.
+*/
+package capture
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	messages "github.com/skycoin/hardware-wallet-protob/go"
+)
+
+const (
+	headerLen = 9
+	repMarker = '?'
+	repMagic  = '#'
+)
+
+// Direction tells whether a report was sent to the device or received from it
+type Direction string
+
+const (
+	// Write the report was sent by the host to the device
+	Write Direction = "write"
+	// Read the report was received by the host from the device
+	Read Direction = "read"
+)
+
+// Report is a usb report exchanged with the device
+type Report struct {
+	Time      time.Time `json:"time"`
+	Direction Direction `json:"direction"`
+	// Kind is the message type of a report starting a message, empty for the continuation reports
+	Kind string `json:"kind,omitempty"`
+	// Data is the hex encoded report
+	Data string `json:"data"`
+}
+
+// NewReport returns the report of data sent in direction at t
+func NewReport(t time.Time, direction Direction, data []byte) Report {
+	return Report{
+		Time:      t,
+		Direction: direction,
+		Kind:      Kind(data),
+		Data:      hex.EncodeToString(data),
+	}
+}
+
+// Bytes returns the decoded report data
+func (r Report) Bytes() ([]byte, error) {
+	return hex.DecodeString(r.Data)
+}
+
+// Kind returns the message type of a report starting a message, an empty string for other reports
+func Kind(data []byte) string {
+	if len(data) < headerLen || data[0] != repMarker || data[1] != repMagic || data[2] != repMagic {
+		return ""
+	}
+	return messages.MessageType(binary.BigEndian.Uint16(data[3:])).String()
+}
+
+// Load reads a capture
+func Load(r io.Reader) ([]Report, error) {
+	var reports []Report
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		var report Report
+		if err := json.Unmarshal(scanner.Bytes(), &report); err != nil {
+			return nil, fmt.Errorf("capture line %d: %v", line, err)
+		}
+		if report.Direction != Write && report.Direction != Read {
+			return nil, fmt.Errorf("capture line %d: invalid direction %q", line, report.Direction)
+		}
+		if _, err := report.Bytes(); err != nil {
+			return nil, fmt.Errorf("capture line %d: %v", line, err)
+		}
+		reports = append(reports, report)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return reports, nil
+}
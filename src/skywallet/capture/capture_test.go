@@ -0,0 +1,183 @@
+package capture
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	messages "github.com/skycoin/hardware-wallet-protob/go"
+
+	"github.com/skycoin/hardware-wallet-go/src/skywallet/usb"
+	"github.com/skycoin/hardware-wallet-go/src/skywallet/wire"
+)
+
+// testDevice answers every message written with the replies, one reply per message
+type testDevice struct {
+	replies []wire.Message
+	pending [][]byte
+	closed  bool
+}
+
+func (d *testDevice) Write(p []byte) (int, error) {
+	if Kind(p) != "" && len(d.replies) > 0 {
+		var buf bytes.Buffer
+		if _, err := d.replies[0].WriteTo(&buf); err != nil {
+			return 0, err
+		}
+		d.replies = d.replies[1:]
+		for buf.Len() > 0 {
+			d.pending = append(d.pending, buf.Next(64))
+		}
+	}
+	return len(p), nil
+}
+
+func (d *testDevice) Read(p []byte) (int, error) {
+	if len(d.pending) == 0 {
+		return 0, usb.ErrClosedDevice
+	}
+	n := copy(p, d.pending[0])
+	d.pending = d.pending[1:]
+	return n, nil
+}
+
+func (d *testDevice) Close(disconnected bool) error {
+	d.closed = true
+	return nil
+}
+
+func writeMessage(t *testing.T, dev usb.Device, kind messages.MessageType, data []byte) {
+	msg := wire.Message{Kind: uint16(kind), Data: data}
+	_, err := msg.WriteTo(dev)
+	require.NoError(t, err)
+}
+
+func requireReadMessage(t *testing.T, dev usb.Device, kind messages.MessageType) {
+	msg, err := wire.ReadFrom(dev)
+	require.NoError(t, err)
+	require.Equal(t, kind.String(), messages.MessageType(msg.Kind).String())
+}
+
+// record runs a session writing a message of two reports answered by a Success
+func record(t *testing.T) []Report {
+	var capture bytes.Buffer
+	dev := &testDevice{
+		replies: []wire.Message{{Kind: uint16(messages.MessageType_MessageType_Success)}},
+	}
+	recorder := NewRecorder(dev, &capture)
+
+	writeMessage(t, recorder, messages.MessageType_MessageType_EntropyAck, make([]byte, 100))
+	requireReadMessage(t, recorder, messages.MessageType_MessageType_Success)
+	require.NoError(t, recorder.Close(false))
+	require.True(t, dev.closed)
+	require.NoError(t, recorder.Err())
+
+	reports, err := Load(&capture)
+	require.NoError(t, err)
+	return reports
+}
+
+func TestRecorder(t *testing.T) {
+	reports := record(t)
+	require.Len(t, reports, 3)
+
+	require.Equal(t, Write, reports[0].Direction)
+	require.Equal(t, "MessageType_EntropyAck", reports[0].Kind)
+	require.Equal(t, Write, reports[1].Direction)
+	require.Empty(t, reports[1].Kind)
+	require.Equal(t, Read, reports[2].Direction)
+	require.Equal(t, "MessageType_Success", reports[2].Kind)
+
+	data, err := reports[1].Bytes()
+	require.NoError(t, err)
+	require.Len(t, data, 64)
+	require.False(t, reports[0].Time.IsZero())
+}
+
+func TestLoad(t *testing.T) {
+	_, err := Load(bytes.NewBufferString(`{"direction":"sideways","data":""}`))
+	require.EqualError(t, err, `capture line 1: invalid direction "sideways"`)
+
+	_, err = Load(bytes.NewBufferString(`{"direction":"read","data":"zz"}`))
+	require.Error(t, err)
+
+	reports, err := Load(bytes.NewBufferString("\n{\"direction\":\"read\",\"data\":\"3f\"}\n"))
+	require.NoError(t, err)
+	require.Len(t, reports, 1)
+}
+
+func TestReplayBus(t *testing.T) {
+	bus := NewReplayBus(record(t))
+	require.Equal(t, 3, bus.Remaining())
+
+	infos, err := bus.Enumerate(usb.VendorT1, usb.ProductT1Firmware)
+	require.NoError(t, err)
+	require.Len(t, infos, 1)
+	infos, err = bus.Enumerate(usb.VendorT2, 0)
+	require.NoError(t, err)
+	require.Empty(t, infos)
+
+	dev, err := bus.Connect(ReplayPath)
+	require.NoError(t, err)
+
+	// the written data may differ from the capture, not the message type
+	writeMessage(t, dev, messages.MessageType_MessageType_EntropyAck, bytes.Repeat([]byte{1}, 100))
+	requireReadMessage(t, dev, messages.MessageType_MessageType_Success)
+	require.Zero(t, bus.Remaining())
+
+	_, err = dev.Read(make([]byte, 64))
+	require.Equal(t, ErrCaptureEnd, err)
+
+	require.NoError(t, dev.Close(false))
+	_, err = dev.Write(make([]byte, 64))
+	require.Equal(t, usb.ErrClosedDevice, err)
+}
+
+func TestReplayBusMismatch(t *testing.T) {
+	bus := NewReplayBus(record(t))
+	dev, err := bus.Connect(ReplayPath)
+	require.NoError(t, err)
+
+	msg := wire.Message{Kind: uint16(messages.MessageType_MessageType_Initialize)}
+	_, err = msg.WriteTo(dev)
+	var mismatch *MismatchError
+	require.True(t, errors.As(err, &mismatch))
+	require.Equal(t, 0, mismatch.Index)
+	require.Equal(t, "MessageType_EntropyAck", mismatch.Expected)
+	require.Equal(t, "MessageType_Initialize", mismatch.Written)
+}
+
+func TestReplayBusReadOrder(t *testing.T) {
+	bus := NewReplayBus(record(t))
+	bus.Timeout = 10 * time.Millisecond
+	dev, err := bus.Connect(ReplayPath)
+	require.NoError(t, err)
+
+	// the reply is not served before the request is written
+	_, err = dev.Read(make([]byte, 64))
+	require.Equal(t, ErrReplayTimeout, err)
+
+	// a read waits for the request written concurrently, like the EntropyAck of sendToDevice
+	bus.Timeout = time.Second
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		requireReadMessage(t, dev, messages.MessageType_MessageType_Success)
+	}()
+	writeMessage(t, dev, messages.MessageType_MessageType_EntropyAck, make([]byte, 100))
+	<-done
+
+	// closing the device unblocks a waiting read
+	bus = NewReplayBus(record(t))
+	dev, err = bus.Connect(ReplayPath)
+	require.NoError(t, err)
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		dev.Close(false)
+	}()
+	_, err = dev.Read(make([]byte, 64))
+	require.Equal(t, usb.ErrClosedDevice, err)
+}
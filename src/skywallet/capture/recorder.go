@@ -0,0 +1,130 @@
+package capture
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/skycoin/hardware-wallet-go/src/skywallet/usb"
+)
+
+// writer appends reports to a capture, it is shared by the recorders of a RecordingBus
+type writer struct {
+	sync.Mutex
+	enc *json.Encoder
+	err error
+}
+
+func newWriter(w io.Writer) *writer {
+	return &writer{
+		enc: json.NewEncoder(w),
+	}
+}
+
+// record appends a report, after a failure the following reports are dropped
+func (w *writer) record(direction Direction, data []byte) {
+	w.Lock()
+	defer w.Unlock()
+
+	if w.err != nil {
+		return
+	}
+	w.err = w.enc.Encode(NewReport(time.Now(), direction, data))
+}
+
+func (w *writer) Err() error {
+	w.Lock()
+	defer w.Unlock()
+	return w.err
+}
+
+// Recorder is a usb.Device recording the reports exchanged with the wrapped device
+type Recorder struct {
+	dev     usb.Device
+	capture *writer
+}
+
+// NewRecorder returns a usb.Device recording the reports exchanged with dev to w
+func NewRecorder(dev usb.Device, w io.Writer) *Recorder {
+	return &Recorder{
+		dev:     dev,
+		capture: newWriter(w),
+	}
+}
+
+// Read reads a report from the device and records it
+func (r *Recorder) Read(p []byte) (int, error) {
+	n, err := r.dev.Read(p)
+	if n > 0 {
+		r.capture.record(Read, p[:n])
+	}
+	return n, err
+}
+
+// Write writes a report to the device and records it
+func (r *Recorder) Write(p []byte) (int, error) {
+	n, err := r.dev.Write(p)
+	if n > 0 {
+		r.capture.record(Write, p[:n])
+	}
+	return n, err
+}
+
+// Close closes the device
+func (r *Recorder) Close(disconnected bool) error {
+	return r.dev.Close(disconnected)
+}
+
+// Err returns the error that stopped the recording, if any
+func (r *Recorder) Err() error {
+	return r.capture.Err()
+}
+
+// RecordingBus is a usb.Bus recording the reports exchanged with the devices it connects to
+type RecordingBus struct {
+	bus     usb.Bus
+	capture *writer
+}
+
+// NewRecordingBus returns a usb.Bus recording the reports exchanged with the devices of bus to w.
+// The reports of successive connections are appended to the same capture.
+func NewRecordingBus(bus usb.Bus, w io.Writer) *RecordingBus {
+	return &RecordingBus{
+		bus:     bus,
+		capture: newWriter(w),
+	}
+}
+
+// Enumerate returns the devices of the wrapped bus
+func (b *RecordingBus) Enumerate(vendorID, productID uint16) ([]usb.Info, error) {
+	return b.bus.Enumerate(vendorID, productID)
+}
+
+// Connect connects to a device of the wrapped bus and records its reports
+func (b *RecordingBus) Connect(path string) (usb.Device, error) {
+	dev, err := b.bus.Connect(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Recorder{
+		dev:     dev,
+		capture: b.capture,
+	}, nil
+}
+
+// Has returns true if path belongs to the wrapped bus
+func (b *RecordingBus) Has(path string) bool {
+	return b.bus.Has(path)
+}
+
+// Close closes the wrapped bus
+func (b *RecordingBus) Close() {
+	b.bus.Close()
+}
+
+// Err returns the error that stopped the recording, if any
+func (b *RecordingBus) Err() error {
+	return b.capture.Err()
+}
@@ -0,0 +1,247 @@
+package capture
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/skycoin/hardware-wallet-go/src/skywallet/usb"
+)
+
+const (
+	// ReplayPath is the path of the device served by a ReplayBus
+	ReplayPath = "replay"
+
+	// DefaultReplayTimeout is the default ReplayBus.Timeout
+	DefaultReplayTimeout = time.Second
+)
+
+var (
+	// ErrCaptureEnd is returned if the host reads or writes more reports than recorded
+	ErrCaptureEnd = errors.New("no more reports in the capture")
+	// ErrReplayTimeout is returned if a read waits too long for the writes recorded before it
+	ErrReplayTimeout = errors.New("timeout waiting for the host to write the recorded reports")
+)
+
+// MismatchError is returned if the host writes a report starting a different message than recorded
+type MismatchError struct {
+	// Index is the index of the expected report in the capture
+	Index    int
+	Expected string
+	Written  string
+}
+
+func (e *MismatchError) Error() string {
+	return fmt.Sprintf("capture report %d: host wrote %s, expected %s", e.Index, describeKind(e.Written), describeKind(e.Expected))
+}
+
+func describeKind(kind string) string {
+	if kind == "" {
+		return "a continuation report"
+	}
+	return kind
+}
+
+// ReplayBus is a usb.Bus serving a capture back to the host through a single device at ReplayPath.
+//
+// Reads return the recorded reports of the device in order, once the host wrote the reports
+// recorded before them. Writes are checked against the recorded reports of the host by message
+// type only, the data written by the host, e.g. its entropy, may differ from the capture.
+// The successive connections to the device share the position in the capture.
+type ReplayBus struct {
+	// Timeout is how long a read waits for the host to write the reports recorded before it
+	Timeout time.Duration
+
+	mu      sync.Mutex
+	reports []Report
+	// next is the index of the next report of each direction
+	next map[Direction]int
+	// written is closed and renewed after every write
+	written chan struct{}
+}
+
+// NewReplayBus returns a bus replaying reports
+func NewReplayBus(reports []Report) *ReplayBus {
+	return &ReplayBus{
+		Timeout: DefaultReplayTimeout,
+		reports: reports,
+		next: map[Direction]int{
+			Write: 0,
+			Read:  0,
+		},
+		written: make(chan struct{}),
+	}
+}
+
+// Remaining returns the number of reports not replayed yet
+func (b *ReplayBus) Remaining() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	remaining := 0
+	for _, direction := range []Direction{Write, Read} {
+		for i := b.next[direction]; i < len(b.reports); i++ {
+			if b.reports[i].Direction == direction {
+				remaining++
+			}
+		}
+	}
+	return remaining
+}
+
+// Enumerate returns the replayed device if it matches the given vendor and product ids
+func (b *ReplayBus) Enumerate(vendorID, productID uint16) ([]usb.Info, error) {
+	if vendorID != 0 && vendorID != usb.VendorT1 {
+		return nil, nil
+	}
+	if productID != 0 && productID != usb.ProductT1Firmware {
+		return nil, nil
+	}
+
+	return []usb.Info{
+		{
+			Path:      ReplayPath,
+			VendorID:  usb.VendorT1,
+			ProductID: usb.ProductT1Firmware,
+			Type:      usb.TypeT1Hid,
+		},
+	}, nil
+}
+
+// Has returns true if path is ReplayPath
+func (b *ReplayBus) Has(path string) bool {
+	return path == ReplayPath
+}
+
+// Connect opens a new handle to the replayed device
+func (b *ReplayBus) Connect(path string) (usb.Device, error) {
+	if path != ReplayPath {
+		return nil, usb.ErrNotFound
+	}
+
+	return &replayDevice{
+		bus:    b,
+		closed: make(chan struct{}),
+	}, nil
+}
+
+// Close closes the bus
+func (b *ReplayBus) Close() {
+	// nothing
+}
+
+// seek returns the index of the next report in direction, -1 if there is none.
+// b.mu must be held.
+func (b *ReplayBus) seek(direction Direction) int {
+	for i := b.next[direction]; i < len(b.reports); i++ {
+		if b.reports[i].Direction == direction {
+			return i
+		}
+	}
+	return -1
+}
+
+func (b *ReplayBus) write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	i := b.seek(Write)
+	if i < 0 {
+		return 0, ErrCaptureEnd
+	}
+	if kind := Kind(p); kind != b.reports[i].Kind {
+		return 0, &MismatchError{
+			Index:    i,
+			Expected: b.reports[i].Kind,
+			Written:  kind,
+		}
+	}
+
+	b.next[Write] = i + 1
+	close(b.written)
+	b.written = make(chan struct{})
+	return len(p), nil
+}
+
+func (b *ReplayBus) read(p []byte, closed <-chan struct{}) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	timeout := time.NewTimer(b.Timeout)
+	defer timeout.Stop()
+
+	var i int
+	for {
+		i = b.seek(Read)
+		if i < 0 {
+			return 0, ErrCaptureEnd
+		}
+		// the device answers once it received the reports written before
+		if w := b.seek(Write); w < 0 || w > i {
+			break
+		}
+
+		written := b.written
+		b.mu.Unlock()
+		select {
+		case <-written:
+		case <-closed:
+			b.mu.Lock()
+			return 0, usb.ErrClosedDevice
+		case <-timeout.C:
+			b.mu.Lock()
+			return 0, ErrReplayTimeout
+		}
+		b.mu.Lock()
+	}
+
+	data, err := b.reports[i].Bytes()
+	if err != nil {
+		return 0, err
+	}
+
+	b.next[Read] = i + 1
+	return copy(p, data), nil
+}
+
+// replayDevice is a connection handle to the device of a ReplayBus
+type replayDevice struct {
+	bus *ReplayBus
+
+	closeOnce sync.Once
+	closed    chan struct{}
+}
+
+// Read returns the next recorded report of the device
+func (d *replayDevice) Read(p []byte) (int, error) {
+	if d.isClosed() {
+		return 0, usb.ErrClosedDevice
+	}
+	return d.bus.read(p, d.closed)
+}
+
+// Write checks p against the next recorded report of the host
+func (d *replayDevice) Write(p []byte) (int, error) {
+	if d.isClosed() {
+		return 0, usb.ErrClosedDevice
+	}
+	return d.bus.write(p)
+}
+
+// Close closes the handle, a blocked Read returns usb.ErrClosedDevice
+func (d *replayDevice) Close(disconnected bool) error {
+	d.closeOnce.Do(func() {
+		close(d.closed)
+	})
+	return nil
+}
+
+func (d *replayDevice) isClosed() bool {
+	select {
+	case <-d.closed:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,183 @@
+/*
+Package emulator implements an in-process software wallet speaking the
+skywallet wire protocol.
+
+The emulator is exposed as a usb.Bus so it can be plugged into a
+skywallet.Driver in place of the USB or UDP buses. This allows running the
+whole Devicer api in unit tests without a physical device or the firmware
+emulator binary.
+*/
+package emulator
+
+import (
+	"encoding/binary"
+	"sync/atomic"
+
+	"github.com/skycoin/hardware-wallet-go/src/skywallet/usb"
+)
+
+const (
+	emulatorPath = "swemu"
+
+	reportLen  = 64
+	headerLen  = 9
+	repMarker  = '?'
+	repMagic   = '#'
+	buttonLeft = 0
+)
+
+// buttonPressPrefix is the header of the button press simulation message
+// sent by skywallet.Device.SimulateButtonPress
+var buttonPressPrefix = []byte{0, 1, 2, 3, 4}
+
+// Bus is a usb.Bus exposing a single software wallet
+type Bus struct {
+	path   string
+	wallet *Wallet
+
+	detached int32 // atomic
+}
+
+// InitBus creates a bus with a software wallet initialized from options
+func InitBus(options Options) *Bus {
+	path := options.Path
+	if path == "" {
+		path = emulatorPath
+	}
+
+	return &Bus{
+		path:   path,
+		wallet: NewWallet(options),
+	}
+}
+
+// Wallet returns the software wallet served by the bus
+func (b *Bus) Wallet() *Wallet {
+	return b.wallet
+}
+
+// SetAttached simulates plugging the software wallet in or removing it, it is attached by default
+func (b *Bus) SetAttached(attached bool) {
+	var detached int32
+	if !attached {
+		detached = 1
+	}
+	atomic.StoreInt32(&b.detached, detached)
+}
+
+func (b *Bus) attached() bool {
+	return atomic.LoadInt32(&b.detached) == 0
+}
+
+// Enumerate returns the software wallet if it is attached and matches the given vendor and product ids
+func (b *Bus) Enumerate(vendorID, productID uint16) ([]usb.Info, error) {
+	if !b.attached() {
+		return nil, nil
+	}
+	if vendorID != 0 && vendorID != usb.VendorT1 {
+		return nil, nil
+	}
+	if productID != 0 && productID != usb.ProductT1Firmware {
+		return nil, nil
+	}
+
+	return []usb.Info{
+		{
+			Path:      b.path,
+			VendorID:  usb.VendorT1,
+			ProductID: usb.ProductT1Firmware,
+			Type:      usb.TypeEmulator,
+		},
+	}, nil
+}
+
+// Has returns true if path belongs to this bus
+func (b *Bus) Has(path string) bool {
+	return path == b.path
+}
+
+// Connect opens a new handle to the software wallet
+func (b *Bus) Connect(path string) (usb.Device, error) {
+	if path != b.path || !b.attached() {
+		return nil, usb.ErrNotFound
+	}
+
+	return &Device{
+		wallet: b.wallet,
+	}, nil
+}
+
+// Close closes the bus
+func (b *Bus) Close() {
+	// nothing
+}
+
+// Device is a connection handle to the software wallet
+type Device struct {
+	wallet *Wallet
+
+	closed int32 // atomic
+
+	// message being reassembled from the written reports
+	kind uint16
+	size uint32
+	data []byte
+	busy bool
+}
+
+// Close closes the handle, any blocked Read returns usb.ErrClosedDevice
+func (d *Device) Close(disconnected bool) error {
+	atomic.StoreInt32(&d.closed, 1)
+	d.wallet.wakeUp()
+	return nil
+}
+
+func (d *Device) isClosed() bool {
+	return atomic.LoadInt32(&d.closed) == 1
+}
+
+// Write feeds a report to the software wallet
+func (d *Device) Write(buf []byte) (int, error) {
+	if d.isClosed() {
+		return 0, usb.ErrClosedDevice
+	}
+
+	if len(buf) == len(buttonPressPrefix)+1 && string(buf[:len(buttonPressPrefix)]) == string(buttonPressPrefix) {
+		d.wallet.pressButton(int(buf[len(buttonPressPrefix)]))
+		return len(buf), nil
+	}
+
+	if len(buf) == 0 || buf[0] != repMarker {
+		return len(buf), nil
+	}
+
+	if !d.busy {
+		if len(buf) < headerLen || buf[1] != repMagic || buf[2] != repMagic {
+			// skip reports not starting a message
+			return len(buf), nil
+		}
+		d.kind = binary.BigEndian.Uint16(buf[3:])
+		d.size = binary.BigEndian.Uint32(buf[5:])
+		d.data = append([]byte{}, buf[headerLen:]...)
+		d.busy = true
+	} else {
+		d.data = append(d.data, buf[1:]...)
+	}
+
+	if uint32(len(d.data)) >= d.size {
+		d.busy = false
+		d.wallet.handle(d.kind, d.data[:d.size])
+	}
+
+	return len(buf), nil
+}
+
+// Read blocks until the software wallet has a report to deliver
+func (d *Device) Read(buf []byte) (int, error) {
+	rep, ok := d.wallet.nextReport(d.isClosed)
+	if !ok {
+		return 0, usb.ErrClosedDevice
+	}
+
+	return copy(buf, rep[:]), nil
+}
@@ -0,0 +1,944 @@
+package emulator
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/stretchr/testify/require"
+
+	messages "github.com/skycoin/hardware-wallet-protob/go"
+
+	"github.com/skycoin/hardware-wallet-go/src/skywallet"
+	"github.com/skycoin/hardware-wallet-go/src/skywallet/entropy"
+	"github.com/skycoin/hardware-wallet-go/src/skywallet/transaction"
+	"github.com/skycoin/hardware-wallet-go/src/skywallet/usb"
+	"github.com/skycoin/hardware-wallet-go/src/skywallet/wire"
+)
+
+const (
+	testMnemonic = "cloud flower upset remain green metal below cup stem infant art thank"
+	testAddress  = "2EU3JbveHdkxW6z5tdhbbB2kRAWvXC2pLzw"
+)
+
+func newTestDevice(options Options) (*skywallet.Device, *Wallet) {
+	bus := InitBus(options)
+	driver := skywallet.NewDriverWithBus(skywallet.DeviceTypeEmulator, bus)
+	return skywallet.NewDeviceWithDriver(driver), bus.Wallet()
+}
+
+func requireKind(t *testing.T, msg wire.Message, kind messages.MessageType) {
+	require.Equal(t, kind.String(), messages.MessageType(msg.Kind).String())
+}
+
+func requireFailure(t *testing.T, err error, code messages.FailureType) {
+	var failure *skywallet.FailureError
+	require.True(t, errors.As(err, &failure), "%v is not a FailureError", err)
+	require.Equal(t, code, failure.Code)
+}
+
+func TestFeatures(t *testing.T) {
+	device, _ := newTestDevice(Options{Seed: []byte("features"), Label: "emulator"})
+
+	msg, err := device.GetFeatures()
+	require.NoError(t, err)
+	requireKind(t, msg, messages.MessageType_MessageType_Features)
+
+	var features messages.Features
+	require.NoError(t, proto.Unmarshal(msg.Data, &features))
+	require.Equal(t, "emulator", features.GetLabel())
+	require.False(t, features.GetInitialized())
+	require.Len(t, features.GetDeviceId(), 24)
+
+	ff := skywallet.NewFirmwareFeatures(uint64(features.GetFirmwareFeatures())).(*skywallet.FirmwareFeatures)
+	require.NoError(t, ff.Unmarshal())
+	require.True(t, ff.IsEmulator)
+	require.True(t, ff.IsGetEntropyEnabled)
+
+	// same seed, same device
+	other, _ := newTestDevice(Options{Seed: []byte("features")})
+	msg, err = other.GetFeatures()
+	require.NoError(t, err)
+	var otherFeatures messages.Features
+	require.NoError(t, proto.Unmarshal(msg.Data, &otherFeatures))
+	require.Equal(t, features.GetDeviceId(), otherFeatures.GetDeviceId())
+}
+
+func TestSelectDevice(t *testing.T) {
+	bus := usb.Init(
+		InitBus(Options{Seed: []byte("first"), Label: "first", Path: "swemu0"}),
+		InitBus(Options{Seed: []byte("second"), Label: "second", Path: "swemu1"}),
+	)
+	label := func(options ...skywallet.Option) (string, error) {
+		driver := skywallet.NewDriverWithBus(skywallet.DeviceTypeEmulator, bus, options...)
+		features, err := skywallet.NewDeviceWithDriver(driver).Features()
+		if err != nil {
+			return "", err
+		}
+		return features.GetLabel(), nil
+	}
+
+	first, err := label()
+	require.NoError(t, err)
+	require.Equal(t, "first", first)
+
+	second, err := label(skywallet.WithPath("swemu1"))
+	require.NoError(t, err)
+	require.Equal(t, "second", second)
+
+	second, err = label(skywallet.WithLabel("second"))
+	require.NoError(t, err)
+	require.Equal(t, "second", second)
+
+	device, _ := newTestDevice(Options{Seed: []byte("second")})
+	features, err := device.Features()
+	require.NoError(t, err)
+	second, err = label(skywallet.WithDeviceID(features.GetDeviceId()))
+	require.NoError(t, err)
+	require.Equal(t, "second", second)
+
+	for _, device := range []string{"swemu1", "second", features.GetDeviceId()} {
+		second, err = label(skywallet.WithDevice(device))
+		require.NoError(t, err)
+		require.Equal(t, "second", second)
+	}
+
+	_, err = label(skywallet.WithLabel("third"))
+	require.Equal(t, skywallet.ErrDeviceNotFound, err)
+	_, err = label(skywallet.WithPath("swemu0"), skywallet.WithLabel("second"))
+	require.Equal(t, skywallet.ErrDeviceNotFound, err)
+}
+
+// busyBus is a bus whose wallet fails to connect
+type busyBus struct {
+	*Bus
+}
+
+var errBusy = errors.New("device busy")
+
+func (b busyBus) Connect(path string) (usb.Device, error) {
+	return nil, errBusy
+}
+
+func TestSelectDeviceConnectError(t *testing.T) {
+	bus := usb.Init(
+		busyBus{InitBus(Options{Seed: []byte("first"), Label: "first", Path: "swemu0"})},
+		InitBus(Options{Seed: []byte("second"), Label: "second", Path: "swemu1"}),
+	)
+	label := func(options ...skywallet.Option) (string, error) {
+		driver := skywallet.NewDriverWithBus(skywallet.DeviceTypeEmulator, bus, options...)
+		features, err := skywallet.NewDeviceWithDriver(driver).Features()
+		if err != nil {
+			return "", err
+		}
+		return features.GetLabel(), nil
+	}
+
+	// the busy wallet is skipped
+	second, err := label()
+	require.NoError(t, err)
+	require.Equal(t, "second", second)
+	second, err = label(skywallet.WithLabel("second"))
+	require.NoError(t, err)
+	require.Equal(t, "second", second)
+
+	_, err = label(skywallet.WithPath("swemu0"))
+	require.Equal(t, errBusy, err)
+}
+
+func TestWatch(t *testing.T) {
+	first := InitBus(Options{Seed: []byte("first"), Label: "first", Path: "swemu0"})
+	second := InitBus(Options{Seed: []byte("second"), Label: "second", Path: "swemu1"})
+	second.SetAttached(false)
+	driver := skywallet.NewDriverWithBus(skywallet.DeviceTypeEmulator, usb.Init(first, second))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events := driver.Watch(ctx, skywallet.WatchOptions{
+		Interval: 10 * time.Millisecond,
+		Features: true,
+	})
+
+	next := func() skywallet.DeviceEvent {
+		select {
+		case event := <-events:
+			return event
+		case <-time.After(time.Second):
+			t.Fatal("no device event received")
+			return skywallet.DeviceEvent{}
+		}
+	}
+
+	// attached wallets are reported on start
+	event := next()
+	require.Equal(t, skywallet.DeviceArrived, event.Type)
+	require.Equal(t, "swemu0", event.Info.Path)
+	require.Equal(t, "first", event.Features.GetLabel())
+	require.Equal(t, skywallet.DeviceModeFirmware, event.Mode)
+
+	// a wallet in use through the driver is not probed
+	session, err := skywallet.NewDeviceWithDriver(driver).OpenSession()
+	require.NoError(t, err)
+	first.SetAttached(false)
+	event = next()
+	require.Equal(t, skywallet.DeviceRemoved, event.Type)
+	first.SetAttached(true)
+	event = next()
+	require.Equal(t, skywallet.DeviceArrived, event.Type)
+	require.Equal(t, "swemu0", event.Info.Path)
+	require.Nil(t, event.Features)
+	require.Equal(t, skywallet.DeviceModeFirmware, event.Mode)
+	features, err := session.Features()
+	require.NoError(t, err)
+	require.Equal(t, "first", features.GetLabel())
+	require.NoError(t, session.Close())
+
+	second.SetAttached(true)
+	event = next()
+	require.Equal(t, skywallet.DeviceArrived, event.Type)
+	require.Equal(t, "swemu1", event.Info.Path)
+	require.Equal(t, "second", event.Features.GetLabel())
+
+	first.SetAttached(false)
+	event = next()
+	require.Equal(t, skywallet.DeviceRemoved, event.Type)
+	require.Equal(t, "swemu0", event.Info.Path)
+	require.Nil(t, event.Features)
+
+	cancel()
+	for range events {
+	}
+}
+
+func TestAddressGen(t *testing.T) {
+	device, _ := newTestDevice(Options{Mnemonic: testMnemonic})
+
+	tt := []struct {
+		name       string
+		addressN   uint32
+		startIndex uint32
+		addresses  []string
+	}{
+		{
+			name:      "addressN 2",
+			addressN:  2,
+			addresses: []string{testAddress, "zC8GAQGQBfwk7vtTxVoRG7iMperHNuyYPs"},
+		},
+		{
+			name:       "addressN 2 startIndex 2",
+			addressN:   2,
+			startIndex: 2,
+			addresses:  []string{"28L2fexvThTVz6e2dWUV4pSuCP8SAnCUVku", "2NckPkQRQFa5E7HtqDkZmV1TH4HCzR2N5J6"},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			msg, err := device.AddressGen(tc.addressN, tc.startIndex, false)
+			require.NoError(t, err)
+			addresses, err := skywallet.DecodeResponseSkycoinAddress(msg)
+			require.NoError(t, err)
+			require.Equal(t, tc.addresses, addresses)
+		})
+	}
+
+	_, err := device.AddressGen(maxAddresses+1, 0, false)
+	requireFailure(t, err, messages.FailureType_Failure_AddressGeneration)
+}
+
+func TestAddressGenConfirm(t *testing.T) {
+	device, _ := newTestDevice(Options{Mnemonic: testMnemonic})
+
+	msg, err := device.AddressGen(1, 0, true)
+	require.NoError(t, err)
+	requireKind(t, msg, messages.MessageType_MessageType_ButtonRequest)
+
+	msg, err = device.ButtonAck()
+	require.NoError(t, err)
+	addresses, err := skywallet.DecodeResponseSkycoinAddress(msg)
+	require.NoError(t, err)
+	require.Equal(t, []string{testAddress}, addresses)
+
+	// rejecting on the device cancels the action
+	require.NoError(t, device.SetAutoPressButton(true, skywallet.ButtonLeft))
+	msg, err = device.AddressGen(1, 0, true)
+	require.NoError(t, err)
+	_, err = device.ButtonAck()
+	requireFailure(t, err, messages.FailureType_Failure_ActionCancelled)
+}
+
+func TestSession(t *testing.T) {
+	device, _ := newTestDevice(Options{Mnemonic: testMnemonic})
+
+	session, err := device.OpenSession()
+	require.NoError(t, err)
+	defer session.Close()
+
+	require.True(t, session.Connected())
+
+	msg, err := session.AddressGen(1, 0, true)
+	require.NoError(t, err)
+	requireKind(t, msg, messages.MessageType_MessageType_ButtonRequest)
+
+	msg, err = session.ButtonAck()
+	require.NoError(t, err)
+	addresses, err := skywallet.DecodeResponseSkycoinAddress(msg)
+	require.NoError(t, err)
+	require.Equal(t, []string{testAddress}, addresses)
+
+	msg, err = session.AddressGen(2, 2, false)
+	require.NoError(t, err)
+	addresses, err = skywallet.DecodeResponseSkycoinAddress(msg)
+	require.NoError(t, err)
+	require.Len(t, addresses, 2)
+
+	require.NoError(t, session.Close())
+	_, err = session.GetFeatures()
+	require.Equal(t, skywallet.ErrSessionClosed, err)
+}
+
+func TestContextCancel(t *testing.T) {
+	device, _ := newTestDevice(Options{Mnemonic: testMnemonic, WaitButtonPress: true})
+
+	msg, err := device.AddressGen(1, 0, true)
+	require.NoError(t, err)
+	requireKind(t, msg, messages.MessageType_MessageType_ButtonRequest)
+
+	// nobody presses the button, the request is cancelled when the deadline is exceeded
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err = device.ButtonAckContext(ctx)
+	require.Equal(t, context.DeadlineExceeded, err)
+
+	// the device was told to cancel the action and keeps answering
+	msg, err = device.AddressGen(1, 0, false)
+	require.NoError(t, err)
+	requireKind(t, msg, messages.MessageType_MessageType_ResponseSkycoinAddress)
+
+	ctx, cancel = context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err = device.GetAddressesContext(ctx, 1, 0, true)
+	require.Equal(t, context.DeadlineExceeded, err)
+
+	// a session is closed once a request is cancelled
+	session, err := device.OpenSession()
+	require.NoError(t, err)
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	_, err = session.GetFeaturesContext(ctx)
+	require.Equal(t, context.Canceled, err)
+	require.Equal(t, skywallet.ErrSessionClosed, session.Close())
+
+	features, err := device.FeaturesContext(context.Background())
+	require.NoError(t, err)
+	require.True(t, features.GetInitialized())
+}
+
+func TestNotInitialized(t *testing.T) {
+	device, _ := newTestDevice(Options{})
+
+	_, err := device.AddressGen(1, 0, false)
+	requireFailure(t, err, messages.FailureType_Failure_NotInitialized)
+	require.Equal(t, messages.MessageType_MessageType_SkycoinAddress, err.(*skywallet.FailureError).MsgType)
+}
+
+func TestSignMessage(t *testing.T) {
+	device, _ := newTestDevice(Options{Mnemonic: testMnemonic})
+
+	msg, err := device.SignMessage(0, "Hello World")
+	require.NoError(t, err)
+	signature, err := skywallet.DecodeResponseSkycoinSignMessage(msg)
+	require.NoError(t, err)
+
+	sig, err := cipher.SigFromHex(signature)
+	require.NoError(t, err)
+	require.NoError(t, cipher.VerifyAddressSignedHash(cipher.MustDecodeBase58Address(testAddress), sig, cipher.SumSHA256([]byte("Hello World"))))
+
+	msg, err = device.CheckMessageSignature("Hello World", signature, testAddress)
+	require.NoError(t, err)
+	result, err := skywallet.DecodeSuccessMsg(msg)
+	require.NoError(t, err)
+	require.Equal(t, testAddress, result)
+
+	_, err = device.CheckMessageSignature("Hello World!", signature, testAddress)
+	requireFailure(t, err, messages.FailureType_Failure_InvalidSignature)
+}
+
+func TestTransactionSign(t *testing.T) {
+	device, _ := newTestDevice(Options{Mnemonic: testMnemonic})
+
+	inputs := []*messages.SkycoinTransactionInput{
+		{
+			HashIn: proto.String("181bd5656115172fe81451fae4fb56498a97744d89702e73da75ba91ed5200f9"),
+			Index:  proto.Uint32(0),
+		},
+	}
+	outputs := []*messages.SkycoinTransactionOutput{
+		{
+			Address: proto.String("K9TzLrgqz7uXn3QJHGxmzdRByAzH33J2ot"),
+			Coin:    proto.Uint64(100000),
+			Hour:    proto.Uint64(2),
+		},
+	}
+
+	msg, err := device.TransactionSign(inputs, outputs)
+	require.NoError(t, err)
+	requireKind(t, msg, messages.MessageType_MessageType_ButtonRequest)
+
+	msg, err = device.ButtonAck()
+	require.NoError(t, err)
+	signatures, err := skywallet.DecodeResponseTransactionSign(msg)
+	require.NoError(t, err)
+	require.Len(t, signatures, 1)
+
+	sig, err := cipher.SigFromHex(signatures[0])
+	require.NoError(t, err)
+	hash := cipher.MustSHA256FromHex("d11c62b1e0e9abf629b1f5f4699cef9fbc504b45ceedf0047ead686979498218")
+	require.NoError(t, cipher.VerifyAddressSignedHash(cipher.MustDecodeBase58Address(testAddress), sig, hash))
+}
+
+func TestTransactionSignLimits(t *testing.T) {
+	device, _ := newTestDevice(Options{Mnemonic: testMnemonic})
+
+	const nIn, nOut = skywallet.MaxTransactionInputs, skywallet.MaxTransactionOutputs
+	keys := cipher.MustGenerateDeterministicKeyPairs([]byte(testMnemonic), nIn)
+
+	tx := &transaction.Transaction{}
+	inputs := make([]*messages.SkycoinTransactionInput, nIn+1)
+	for i := range inputs {
+		hash := cipher.SumSHA256([]byte{byte(i)})
+		tx.In = append(tx.In, hash)
+		inputs[i] = &messages.SkycoinTransactionInput{
+			HashIn: proto.String(hash.Hex()),
+			Index:  proto.Uint32(uint32(i)),
+		}
+	}
+	outputs := make([]*messages.SkycoinTransactionOutput, nOut+1)
+	for i := range outputs {
+		address := cipher.MustDecodeBase58Address("K9TzLrgqz7uXn3QJHGxmzdRByAzH33J2ot")
+		tx.Out = append(tx.Out, transaction.Output{Address: address, Coins: uint64(i + 1), Hours: 2})
+		outputs[i] = &messages.SkycoinTransactionOutput{
+			Address: proto.String(address.String()),
+			Coin:    proto.Uint64(uint64(i + 1)),
+			Hour:    proto.Uint64(2),
+		}
+	}
+
+	// the firmware signs the hash of the whole transaction, larger ones are refused before reaching the device
+	_, err := device.TransactionSign(inputs, outputs[:nOut])
+	require.Equal(t, skywallet.ErrTransactionTooLarge, err)
+	_, err = device.TransactionSign(inputs[:nIn], outputs)
+	require.Equal(t, skywallet.ErrTransactionTooLarge, err)
+
+	msg, err := device.TransactionSign(inputs[:nIn], outputs[:nOut])
+	require.NoError(t, err)
+	requireKind(t, msg, messages.MessageType_MessageType_ButtonRequest)
+
+	msg, err = device.ButtonAck()
+	require.NoError(t, err)
+	signatures, err := skywallet.DecodeResponseTransactionSign(msg)
+	require.NoError(t, err)
+	require.Len(t, signatures, nIn)
+
+	tx.In = tx.In[:nIn]
+	tx.Out = tx.Out[:nOut]
+	innerHash := tx.HashInner()
+	for i, signature := range signatures {
+		sig, err := cipher.SigFromHex(signature)
+		require.NoError(t, err)
+		address := cipher.MustAddressFromSecKey(keys[i])
+		require.NoError(t, cipher.VerifyAddressSignedHash(address, sig, cipher.AddSHA256(innerHash, tx.In[i])))
+	}
+}
+
+func TestChangePin(t *testing.T) {
+	device, wallet := newTestDevice(Options{Mnemonic: testMnemonic})
+
+	msg, err := device.ChangePin(new(bool))
+	require.NoError(t, err)
+	requireKind(t, msg, messages.MessageType_MessageType_ButtonRequest)
+
+	msg, err = device.ButtonAck()
+	require.NoError(t, err)
+	requireKind(t, msg, messages.MessageType_MessageType_PinMatrixRequest)
+
+	msg, err = device.PinMatrixAck("1234")
+	require.NoError(t, err)
+	requireKind(t, msg, messages.MessageType_MessageType_PinMatrixRequest)
+
+	msg, err = device.PinMatrixAck("1234")
+	require.NoError(t, err)
+	requireKind(t, msg, messages.MessageType_MessageType_Success)
+	require.Equal(t, "1234", wallet.PIN())
+
+	// a fresh session asks for the PIN
+	device, _ = newTestDevice(Options{Mnemonic: testMnemonic, PIN: "1234"})
+	msg, err = device.AddressGen(1, 0, false)
+	require.NoError(t, err)
+	requireKind(t, msg, messages.MessageType_MessageType_PinMatrixRequest)
+
+	_, err = device.PinMatrixAck("4321")
+	requireFailure(t, err, messages.FailureType_Failure_PinInvalid)
+}
+
+func TestPassphrase(t *testing.T) {
+	device, _ := newTestDevice(Options{Mnemonic: testMnemonic, PassphraseProtection: true})
+
+	msg, err := device.AddressGen(1, 0, false)
+	require.NoError(t, err)
+	requireKind(t, msg, messages.MessageType_MessageType_PassphraseRequest)
+
+	msg, err = device.PassphraseAck("secret")
+	require.NoError(t, err)
+	addresses, err := skywallet.DecodeResponseSkycoinAddress(msg)
+	require.NoError(t, err)
+	require.Len(t, addresses, 1)
+	require.NotEqual(t, testAddress, addresses[0])
+}
+
+func TestWipeAndSetMnemonic(t *testing.T) {
+	device, wallet := newTestDevice(Options{Mnemonic: testMnemonic})
+
+	_, err := device.SetMnemonic(testMnemonic)
+	requireFailure(t, err, messages.FailureType_Failure_UnexpectedMessage)
+
+	msg, err := device.Wipe()
+	require.NoError(t, err)
+	requireKind(t, msg, messages.MessageType_MessageType_ButtonRequest)
+	msg, err = device.ButtonAck()
+	require.NoError(t, err)
+	requireKind(t, msg, messages.MessageType_MessageType_Success)
+	require.Empty(t, wallet.Mnemonic())
+
+	// the last word holds the checksum
+	_, err = device.SetMnemonic(strings.Replace(testMnemonic, "thank", "that", 1))
+	requireFailure(t, err, messages.FailureType_Failure_DataError)
+
+	msg, err = device.SetMnemonic(testMnemonic)
+	require.NoError(t, err)
+	msg, err = device.ButtonAck()
+	require.NoError(t, err)
+	requireKind(t, msg, messages.MessageType_MessageType_Success)
+	require.Equal(t, testMnemonic, wallet.Mnemonic())
+}
+
+func TestGenerateMnemonicAndBackup(t *testing.T) {
+	device, wallet := newTestDevice(Options{Seed: []byte("generate")})
+
+	msg, err := device.GenerateMnemonic(12, false)
+	require.NoError(t, err)
+	requireKind(t, msg, messages.MessageType_MessageType_Success)
+	require.Len(t, strings.Fields(wallet.Mnemonic()), 12)
+	require.True(t, isValidMnemonic(wallet.Mnemonic()))
+
+	msg, err = device.Backup()
+	require.NoError(t, err)
+	for msg.Kind == uint16(messages.MessageType_MessageType_ButtonRequest) {
+		msg, err = device.ButtonAck()
+		require.NoError(t, err)
+	}
+	requireKind(t, msg, messages.MessageType_MessageType_Success)
+
+	_, err = device.Backup()
+	requireFailure(t, err, messages.FailureType_Failure_UnexpectedMessage)
+}
+
+func TestRecovery(t *testing.T) {
+	device, wallet := newTestDevice(Options{})
+
+	msg, err := device.Recovery(12, new(bool), false)
+	require.NoError(t, err)
+	requireKind(t, msg, messages.MessageType_MessageType_ButtonRequest)
+	msg, err = device.ButtonAck()
+	require.NoError(t, err)
+
+	for _, word := range []string{"cloud", "flower", "upset", "remain", "green", "metal", "below", "cup", "stem", "infant", "art", "thank"} {
+		requireKind(t, msg, messages.MessageType_MessageType_WordRequest)
+		msg, err = device.WordAck(word)
+		require.NoError(t, err)
+	}
+	requireKind(t, msg, messages.MessageType_MessageType_Success)
+	require.Equal(t, testMnemonic, wallet.Mnemonic())
+}
+
+func TestMnemonic(t *testing.T) {
+	// BIP39 test vectors
+	tt := []struct {
+		entropy  []byte
+		mnemonic string
+	}{
+		{
+			entropy:  make([]byte, 16),
+			mnemonic: "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about",
+		},
+		{
+			entropy:  bytes.Repeat([]byte{0x7f}, 16),
+			mnemonic: "legal winner thank year wave sausage worth useful legal winner thank yellow",
+		},
+		{
+			entropy:  bytes.Repeat([]byte{0x80}, 16),
+			mnemonic: "letter advice cage absurd amount doctor acoustic avoid letter advice cage above",
+		},
+		{
+			entropy:  bytes.Repeat([]byte{0xff}, 16),
+			mnemonic: "zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo wrong",
+		},
+		{
+			entropy:  make([]byte, 32),
+			mnemonic: strings.Repeat("abandon ", 23) + "art",
+		},
+		{
+			entropy:  bytes.Repeat([]byte{0xff}, 32),
+			mnemonic: strings.Repeat("zoo ", 23) + "vote",
+		},
+	}
+
+	for _, tc := range tt {
+		require.Equal(t, tc.mnemonic, newMnemonic(tc.entropy))
+		require.True(t, isValidMnemonic(tc.mnemonic), tc.mnemonic)
+	}
+
+	require.True(t, isValidMnemonic(testMnemonic))
+	for _, mnemonic := range []string{
+		"",
+		"cloud flower upset remain green metal below cup stem infant art",
+		"flower cloud upset remain green metal below cup stem infant art thank",
+		"cloud flower upset remain green metal below cup stem infant art thanks",
+	} {
+		require.False(t, isValidMnemonic(mnemonic), mnemonic)
+	}
+}
+
+type testPrompter struct {
+	pin        string
+	passphrase string
+	words      []string
+	buttons    []messages.ButtonRequestType
+	err        error
+}
+
+func (p *testPrompter) PinMatrix(pinType messages.PinMatrixRequestType) (string, error) {
+	return p.pin, p.err
+}
+
+func (p *testPrompter) Passphrase() (string, error) {
+	return p.passphrase, p.err
+}
+
+func (p *testPrompter) Word() (string, error) {
+	word := p.words[0]
+	p.words = p.words[1:]
+	return word, p.err
+}
+
+func (p *testPrompter) ButtonConfirm(code messages.ButtonRequestType) error {
+	p.buttons = append(p.buttons, code)
+	return p.err
+}
+
+func TestRunFlow(t *testing.T) {
+	device, _ := newTestDevice(Options{Mnemonic: testMnemonic, PIN: "1234", PassphraseProtection: true})
+
+	session, err := device.OpenSession()
+	require.NoError(t, err)
+	defer session.Close()
+
+	prompter := &testPrompter{pin: "1234"}
+	msg, err := session.AddressGen(1, 0, true)
+	require.NoError(t, err)
+	msg, err = skywallet.RunFlow(session, msg, prompter)
+	require.NoError(t, err)
+	addresses, err := skywallet.DecodeResponseSkycoinAddress(msg)
+	require.NoError(t, err)
+	require.Equal(t, []string{testAddress}, addresses)
+	require.Equal(t, []messages.ButtonRequestType{messages.ButtonRequestType_ButtonRequest_Address}, prompter.buttons)
+
+	// a failing prompter cancels the device action
+	prompter = &testPrompter{err: errors.New("rejected")}
+	msg, err = session.Wipe()
+	require.NoError(t, err)
+	_, err = skywallet.RunFlow(session, msg, prompter)
+	require.Equal(t, prompter.err, err)
+
+	_, err = session.ButtonAck()
+	requireFailure(t, err, messages.FailureType_Failure_UnexpectedMessage)
+}
+
+func TestRunFlowRecovery(t *testing.T) {
+	device, wallet := newTestDevice(Options{})
+
+	session, err := device.OpenSession()
+	require.NoError(t, err)
+	defer session.Close()
+
+	prompter := &testPrompter{words: []string{"cloud", "flower", "upset", "remain", "green", "metal", "below", "cup", "stem", "infant", "art", "thank"}}
+	msg, err := session.Recovery(12, new(bool), false)
+	require.NoError(t, err)
+	msg, err = skywallet.RunFlow(session, msg, prompter)
+	require.NoError(t, err)
+	requireKind(t, msg, messages.MessageType_MessageType_Success)
+	require.Empty(t, prompter.words)
+	require.Equal(t, testMnemonic, wallet.Mnemonic())
+}
+
+func TestTypedAPI(t *testing.T) {
+	device, _ := newTestDevice(Options{Mnemonic: testMnemonic, PIN: "1234", Label: "typed"})
+
+	// the PIN can not be requested without a prompter
+	_, err := device.GetAddresses(1, 0, false)
+	require.Equal(t, skywallet.ErrPrompterNotSet, err)
+
+	device.SetPrompter(&testPrompter{pin: "4321"})
+	_, err = device.GetAddresses(1, 0, false)
+	require.True(t, errors.Is(err, skywallet.ErrPinInvalid))
+
+	device.SetPrompter(&testPrompter{pin: "1234"})
+	addresses, err := device.GetAddresses(2, 0, false)
+	require.NoError(t, err)
+	require.Equal(t, []cipher.Address{
+		cipher.MustDecodeBase58Address(testAddress),
+		cipher.MustDecodeBase58Address("zC8GAQGQBfwk7vtTxVoRG7iMperHNuyYPs"),
+	}, addresses)
+
+	_, err = device.GetAddresses(maxAddresses+1, 0, false)
+	require.True(t, errors.Is(err, skywallet.ErrAddressGeneration))
+
+	signatures, err := device.SignTransaction([]*messages.SkycoinTransactionInput{
+		{
+			HashIn: proto.String("181bd5656115172fe81451fae4fb56498a97744d89702e73da75ba91ed5200f9"),
+			Index:  proto.Uint32(0),
+		},
+	}, []*messages.SkycoinTransactionOutput{
+		{
+			Address: proto.String("K9TzLrgqz7uXn3QJHGxmzdRByAzH33J2ot"),
+			Coin:    proto.Uint64(100000),
+			Hour:    proto.Uint64(2),
+		},
+	})
+	require.NoError(t, err)
+	require.Len(t, signatures, 1)
+	hash := cipher.MustSHA256FromHex("d11c62b1e0e9abf629b1f5f4699cef9fbc504b45ceedf0047ead686979498218")
+	require.NoError(t, cipher.VerifyAddressSignedHash(addresses[0], signatures[0], hash))
+
+	features, err := device.Features()
+	require.NoError(t, err)
+	require.Equal(t, "typed", features.GetLabel())
+	require.True(t, features.GetInitialized())
+	require.True(t, features.GetPinProtection())
+}
+
+func TestVerify(t *testing.T) {
+	device, _ := newTestDevice(Options{Mnemonic: testMnemonic, PIN: "1234"})
+	device.SetPrompter(&testPrompter{pin: "1234"})
+	device.SetVerify(true)
+
+	sig, err := device.GetMessageSignature(0, "Hello World")
+	require.NoError(t, err)
+	require.NoError(t, skywallet.VerifyMessageSignature(cipher.MustDecodeBase58Address(testAddress), "Hello World", sig))
+
+	// the second input is owned by another address
+	signatures, err := device.SignTransaction([]*messages.SkycoinTransactionInput{
+		{
+			HashIn: proto.String("181bd5656115172fe81451fae4fb56498a97744d89702e73da75ba91ed5200f9"),
+			Index:  proto.Uint32(0),
+		},
+		{
+			HashIn: proto.String("7ac50d2fc5b5ba4ca4fb4e0a43bb2b4e1c2d5ca1db7d8e40fdd3f1be0c6e1c87"),
+			Index:  proto.Uint32(1),
+		},
+	}, []*messages.SkycoinTransactionOutput{
+		{
+			Address: proto.String("K9TzLrgqz7uXn3QJHGxmzdRByAzH33J2ot"),
+			Coin:    proto.Uint64(100000),
+			Hour:    proto.Uint64(2),
+		},
+	})
+	require.NoError(t, err)
+	require.Len(t, signatures, 2)
+
+	// the raw requests are verified once the flow ends, against the addresses trusted by the caller
+	device.SetExpectedAddresses([]cipher.Address{cipher.MustDecodeBase58Address("zC8GAQGQBfwk7vtTxVoRG7iMperHNuyYPs")})
+
+	msg, err := device.AddressGen(1, 0, true)
+	require.NoError(t, err)
+	_, err = skywallet.RunFlow(device, msg, &testPrompter{pin: "1234"})
+	var verr *skywallet.VerificationError
+	require.True(t, errors.As(err, &verr))
+	require.Equal(t, skywallet.ErrUnexpectedAddress, verr.Err)
+
+	// answered without user input
+	_, err = device.SignMessage(0, "Hello World")
+	require.True(t, errors.Is(err, cipher.ErrInvalidAddressForSig))
+
+	// answers are returned as is without verification
+	device.SetVerify(false)
+	addresses, err := device.GetAddresses(1, 0, false)
+	require.NoError(t, err)
+	require.Equal(t, []cipher.Address{cipher.MustDecodeBase58Address(testAddress)}, addresses)
+}
+
+func TestAddressBook(t *testing.T) {
+	dir, err := ioutil.TempDir("", "addressbook")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	device, _ := newTestDevice(Options{Mnemonic: testMnemonic})
+	session, err := device.OpenSession()
+	require.NoError(t, err)
+	defer session.Close()
+
+	book := skywallet.NewAddressBook(dir)
+	book.BatchSize = 4
+
+	cache, err := book.Sync(context.Background(), session, 10)
+	require.NoError(t, err)
+	require.Len(t, cache.Addresses, 10)
+	require.Equal(t, testAddress, cache.Addresses[0])
+
+	addresses, err := session.GetAddresses(10, 0, false)
+	require.NoError(t, err)
+	for i, address := range addresses {
+		require.Equal(t, address.String(), cache.Addresses[i])
+	}
+
+	// the addresses 2 and 5 have history
+	used := map[string]bool{
+		cache.Addresses[2]: true,
+		cache.Addresses[5]: true,
+	}
+	hasHistory := func(address cipher.Address) (bool, error) {
+		return used[address.String()], nil
+	}
+	cache, err = book.Discover(context.Background(), session, 6, hasHistory)
+	require.NoError(t, err)
+	require.Equal(t, 6, cache.Used)
+	// the addresses up to 5+6 are derived in batches of 4
+	require.Len(t, cache.Addresses, 14)
+
+	_, err = book.Discover(context.Background(), session, 0, hasHistory)
+	require.Equal(t, skywallet.ErrGapLimitZero, err)
+
+	caches, err := book.List(cache.DeviceID)
+	require.NoError(t, err)
+	require.Len(t, caches, 1)
+	require.Equal(t, cache.Addresses, caches[0].Addresses)
+	require.Equal(t, 6, caches[0].Used)
+
+	caches, err = book.List("unknown")
+	require.NoError(t, err)
+	require.Empty(t, caches)
+}
+
+func TestAddressBookPassphrase(t *testing.T) {
+	dir, err := ioutil.TempDir("", "addressbook")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	book := skywallet.NewAddressBook(dir)
+
+	// every passphrase opens a wallet cached on its own,
+	// the device caches the passphrase so a new one is started per passphrase
+	for _, passphrase := range []string{"first", "second"} {
+		device, _ := newTestDevice(Options{Seed: []byte("book"), Mnemonic: testMnemonic, PassphraseProtection: true})
+		device.SetPrompter(&testPrompter{passphrase: passphrase})
+		session, err := device.OpenSession()
+		require.NoError(t, err)
+		_, err = book.Sync(context.Background(), session, 2)
+		session.Close()
+		require.NoError(t, err)
+	}
+
+	caches, err := book.List("")
+	require.NoError(t, err)
+	require.Len(t, caches, 2)
+	require.Equal(t, caches[0].DeviceID, caches[1].DeviceID)
+	require.NotEqual(t, caches[0].Name(), caches[1].Name())
+	require.NotEqual(t, caches[0].Addresses[0], caches[1].Addresses[0])
+	require.True(t, caches[0].PassphraseProtection)
+}
+
+func TestUnexpectedMessage(t *testing.T) {
+	device, _ := newTestDevice(Options{Mnemonic: testMnemonic})
+
+	_, err := device.ButtonAck()
+	requireFailure(t, err, messages.FailureType_Failure_UnexpectedMessage)
+	require.True(t, errors.Is(err, skywallet.ErrUnexpectedMessage))
+
+	msg, err := device.Wipe()
+	require.NoError(t, err)
+	requireKind(t, msg, messages.MessageType_MessageType_ButtonRequest)
+	// the Failure answering a Cancel is its result
+	msg, err = device.Cancel()
+	require.NoError(t, err)
+	requireKind(t, msg, messages.MessageType_MessageType_Failure)
+}
+
+func TestGetRawEntropy(t *testing.T) {
+	device, _ := newTestDevice(Options{Seed: []byte("entropy")})
+
+	dir, err := ioutil.TempDir("", "emulator")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	analyzer := entropy.NewAnalyzer(entropy.DefaultMinEntropy)
+	device.SetEntropyAnalyzer(analyzer)
+
+	outFile := filepath.Join(dir, "entropy.bin")
+	err = device.SaveDeviceEntropyInFile(outFile, 3*maxEntropyBytes/2, skywallet.MessageDeviceGetRawEntropy)
+	require.NoError(t, err)
+
+	data, err := ioutil.ReadFile(outFile)
+	require.NoError(t, err)
+	require.Len(t, data, 3*maxEntropyBytes/2)
+
+	report := analyzer.Report()
+	require.Equal(t, int64(len(data)), report.Bytes)
+	require.True(t, report.Passed)
+
+	// the raw bytes are written to any writer
+	var buf bytes.Buffer
+	require.NoError(t, device.SaveDeviceEntropy(&buf, 100, skywallet.MessageDeviceGetRawEntropy))
+	require.Equal(t, 100, buf.Len())
+	require.Equal(t, int64(len(data)+100), analyzer.Report().Bytes)
+}
+
+func TestEntropyReader(t *testing.T) {
+	device, _ := newTestDevice(Options{Seed: []byte("entropy"), RequireGetEntropyConfirm: true})
+
+	r, err := device.OpenEntropyReader(context.Background(), skywallet.MessageDeviceGetMixedEntropy)
+	require.NoError(t, err)
+
+	// a small read is served from a single request, the rest of the entropy is kept
+	small := make([]byte, 4)
+	_, err = io.ReadFull(r, small)
+	require.NoError(t, err)
+
+	// a read larger than the firmware limit is served by several requests
+	var buf bytes.Buffer
+	n, err := io.CopyN(&buf, r, 3*maxEntropyBytes/2)
+	require.NoError(t, err)
+	require.Equal(t, int64(3*maxEntropyBytes/2), n)
+	require.NotEqual(t, make([]byte, maxEntropyBytes), buf.Bytes()[:maxEntropyBytes])
+
+	require.NoError(t, r.Close())
+	// the session is closed, the device is available again
+	_, err = device.GetFeatures()
+	require.NoError(t, err)
+}
+
+func TestConnected(t *testing.T) {
+	device, _ := newTestDevice(Options{})
+
+	require.NoError(t, device.Connect())
+	require.True(t, device.Connected())
+	require.NoError(t, device.Disconnect())
+}
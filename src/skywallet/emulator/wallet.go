@@ -0,0 +1,833 @@
+package emulator
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/util/logging"
+
+	messages "github.com/skycoin/hardware-wallet-protob/go"
+
+	"github.com/skycoin/hardware-wallet-go/src/skywallet"
+	"github.com/skycoin/hardware-wallet-go/src/skywallet/transaction"
+	"github.com/skycoin/hardware-wallet-go/src/skywallet/wire"
+)
+
+var (
+	log = logging.MustGetLogger("swemulator")
+)
+
+const (
+	// maxAddresses is the firmware limit of addresses generated per request
+	maxAddresses = 99
+	// maxTransactionInputs is the firmware limit of inputs per TransactionSign
+	maxTransactionInputs = 8
+	// maxTransactionOutputs is the firmware limit of outputs per TransactionSign
+	maxTransactionOutputs = 8
+	// maxEntropyBytes is the firmware limit of entropy bytes per Entropy message
+	maxEntropyBytes = 1024
+
+	firmwareMajor = 1
+	firmwareMinor = 7
+	firmwarePatch = 0
+
+	featureRequireGetEntropyConfirm = 1 << 0
+	featureIsGetEntropyEnabled      = 1 << 1
+	featureIsEmulator               = 1 << 2
+)
+
+// Options software wallet initial state
+type Options struct {
+	// Seed makes the device id and the internal entropy deterministic
+	Seed []byte
+	// Mnemonic preloaded in the wallet, empty if not initialized
+	Mnemonic string
+	// PIN preloaded in the wallet, empty if not protected
+	PIN string
+	// Label of the wallet
+	Label string
+	// PassphraseProtection enables passphrase requests
+	PassphraseProtection bool
+	// NeedsBackup marks the preloaded mnemonic as not backed up
+	NeedsBackup bool
+	// RequireGetEntropyConfirm asks for a button press before returning entropy
+	RequireGetEntropyConfirm bool
+	// WaitButtonPress makes button requests wait for a simulated button press
+	// instead of being confirmed when the host reads the response
+	WaitButtonPress bool
+	// Path of the wallet reported by the bus, defaults to "swemu"
+	Path string
+}
+
+// step is the continuation of a workflow waiting for a host message
+type step struct {
+	expect messages.MessageType
+	resume func(data []byte)
+}
+
+// Wallet is the software wallet state machine.
+//
+// The PIN matrix shown by the emulator has the digits in keypad order
+// (7 8 9 / 4 5 6 / 1 2 3), so the encoded PIN sent by the host is the PIN itself.
+// Buttons requests are confirmed when the host reads the response unless a
+// left button press was simulated before or Options.WaitButtonPress is set.
+type Wallet struct {
+	mu   sync.Mutex
+	cond *sync.Cond
+
+	seed                     []byte
+	entropyCounter           uint64
+	deviceID                 string
+	mnemonic                 string
+	pin                      string
+	label                    string
+	language                 string
+	passphraseProtection     bool
+	needsBackup              bool
+	requireGetEntropyConfirm bool
+	waitButtonPress          bool
+
+	pinCached  bool
+	passphrase *string
+
+	pending    *step
+	buttonNext func()
+	out        [][64]byte
+}
+
+// NewWallet creates a software wallet
+func NewWallet(options Options) *Wallet {
+	w := &Wallet{
+		seed:                     options.Seed,
+		mnemonic:                 options.Mnemonic,
+		pin:                      options.PIN,
+		label:                    options.Label,
+		language:                 "english",
+		passphraseProtection:     options.PassphraseProtection,
+		needsBackup:              options.NeedsBackup,
+		requireGetEntropyConfirm: options.RequireGetEntropyConfirm,
+		waitButtonPress:          options.WaitButtonPress,
+	}
+	w.cond = sync.NewCond(&w.mu)
+	w.deviceID = strings.ToUpper(hex.EncodeToString(w.internalEntropy(12)))
+	return w
+}
+
+// Mnemonic returns the mnemonic currently configured in the wallet
+func (w *Wallet) Mnemonic() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.mnemonic
+}
+
+// PIN returns the PIN currently configured in the wallet
+func (w *Wallet) PIN() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.pin
+}
+
+func (w *Wallet) wakeUp() {
+	w.mu.Lock()
+	w.cond.Broadcast()
+	w.mu.Unlock()
+}
+
+// nextReport blocks until a report is available or closed returns true
+func (w *Wallet) nextReport(closed func() bool) ([64]byte, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for len(w.out) == 0 {
+		if closed() {
+			return [64]byte{}, false
+		}
+		if w.buttonNext != nil && !w.waitButtonPress {
+			// nobody simulated a button press, the user confirms the action
+			w.resolveButton(-1)
+			continue
+		}
+		w.cond.Wait()
+	}
+
+	rep := w.out[0]
+	w.out = w.out[1:]
+	return rep, true
+}
+
+// Write queues the reports produced by wire.Message.WriteTo
+func (w *Wallet) Write(p []byte) (int, error) {
+	var rep [64]byte
+	copy(rep[:], p)
+	w.out = append(w.out, rep)
+	return len(p), nil
+}
+
+func (w *Wallet) pressButton(button int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.resolveButton(button)
+}
+
+func (w *Wallet) resolveButton(button int) {
+	next := w.buttonNext
+	if next == nil {
+		return
+	}
+	w.buttonNext = nil
+
+	if button == buttonLeft {
+		w.fail(messages.FailureType_Failure_ActionCancelled, "Action cancelled by user")
+		return
+	}
+	next()
+}
+
+func (w *Wallet) send(kind messages.MessageType, msg proto.Message) {
+	data, err := proto.Marshal(msg)
+	if err != nil {
+		log.Panic(err)
+	}
+	m := wire.Message{Kind: uint16(kind), Data: data}
+	if _, err := m.WriteTo(w); err != nil {
+		log.Panic(err)
+	}
+	w.cond.Broadcast()
+}
+
+func (w *Wallet) succeed(message string) {
+	w.send(messages.MessageType_MessageType_Success, &messages.Success{
+		Message: proto.String(message),
+	})
+}
+
+func (w *Wallet) fail(code messages.FailureType, message string) {
+	w.pending = nil
+	w.buttonNext = nil
+	w.send(messages.MessageType_MessageType_Failure, &messages.Failure{
+		Code:    code.Enum(),
+		Message: proto.String(message),
+	})
+}
+
+// expect waits for a host message of the given kind before resuming the workflow
+func (w *Wallet) expect(kind messages.MessageType, resume func(data []byte)) {
+	w.pending = &step{
+		expect: kind,
+		resume: resume,
+	}
+}
+
+// confirm asks the user to press a button before running next
+func (w *Wallet) confirm(code messages.ButtonRequestType, next func()) {
+	w.send(messages.MessageType_MessageType_ButtonRequest, &messages.ButtonRequest{
+		Code: code.Enum(),
+	})
+	w.expect(messages.MessageType_MessageType_ButtonAck, func([]byte) {
+		w.buttonNext = next
+	})
+}
+
+// askPin asks the user for a PIN and hands it to next
+func (w *Wallet) askPin(kind messages.PinMatrixRequestType, next func(pin string)) {
+	w.send(messages.MessageType_MessageType_PinMatrixRequest, &messages.PinMatrixRequest{
+		Type: kind.Enum(),
+	})
+	w.expect(messages.MessageType_MessageType_PinMatrixAck, func(data []byte) {
+		var msg messages.PinMatrixAck
+		if !w.decode(data, &msg) {
+			return
+		}
+		pin := msg.GetPin()
+		if !isValidPin(pin) {
+			w.fail(messages.FailureType_Failure_PinInvalid, "PIN invalid")
+			return
+		}
+		next(pin)
+	})
+}
+
+// checkPin asks for the current PIN if the wallet is protected and it is not cached
+func (w *Wallet) checkPin(force bool, next func()) {
+	if w.pin == "" || (w.pinCached && !force) {
+		next()
+		return
+	}
+	w.askPin(messages.PinMatrixRequestType_PinMatrixRequestType_Current, func(pin string) {
+		if pin != w.pin {
+			w.pinCached = false
+			w.fail(messages.FailureType_Failure_PinInvalid, "PIN invalid")
+			return
+		}
+		w.pinCached = true
+		next()
+	})
+}
+
+// checkPassphrase asks for the passphrase if enabled and it is not cached
+func (w *Wallet) checkPassphrase(next func()) {
+	if !w.passphraseProtection || w.passphrase != nil {
+		next()
+		return
+	}
+	w.send(messages.MessageType_MessageType_PassphraseRequest, &messages.PassphraseRequest{})
+	w.expect(messages.MessageType_MessageType_PassphraseAck, func(data []byte) {
+		var msg messages.PassphraseAck
+		if !w.decode(data, &msg) {
+			return
+		}
+		passphrase := msg.GetPassphrase()
+		w.passphrase = &passphrase
+		next()
+	})
+}
+
+// unlock checks the wallet is initialized and asks for PIN and passphrase
+func (w *Wallet) unlock(next func()) {
+	if w.mnemonic == "" {
+		w.fail(messages.FailureType_Failure_NotInitialized, "Mnemonic not set")
+		return
+	}
+	w.checkPin(false, func() {
+		w.checkPassphrase(next)
+	})
+}
+
+// decode unmarshals data received from the host into msg.
+// Host messages are framed with a '\n' in place of the first protobuf key,
+// the firmware decoder only looks at the field number so the key is restored
+// here from the definition of the first field.
+func (w *Wallet) decode(data []byte, msg proto.Message) bool {
+	if len(data) > 0 {
+		data = append([]byte{}, data...)
+		props := proto.GetProperties(reflect.TypeOf(msg).Elem())
+		for _, p := range props.Prop {
+			if p.Tag == 1 {
+				data[0] = byte(p.Tag<<3 | p.WireType)
+				break
+			}
+		}
+	}
+	if err := proto.Unmarshal(data, msg); err != nil {
+		w.fail(messages.FailureType_Failure_DataError, fmt.Sprintf("Failed to decode message: %s", err))
+		return false
+	}
+	return true
+}
+
+// handle dispatches a message received from the host
+func (w *Wallet) handle(kind uint16, data []byte) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	msgType := messages.MessageType(kind)
+	w.buttonNext = nil
+
+	if w.pending != nil {
+		pending := w.pending
+		w.pending = nil
+		switch msgType {
+		case pending.expect:
+			pending.resume(data)
+			return
+		case messages.MessageType_MessageType_Cancel:
+			w.fail(messages.FailureType_Failure_ActionCancelled, "Action cancelled by user")
+			return
+		case messages.MessageType_MessageType_Initialize:
+		default:
+			w.fail(messages.FailureType_Failure_UnexpectedMessage, "Unexpected message")
+			return
+		}
+	}
+
+	switch msgType {
+	case messages.MessageType_MessageType_Initialize:
+		w.passphrase = nil
+		w.send(messages.MessageType_MessageType_Features, w.features())
+	case messages.MessageType_MessageType_GetFeatures:
+		w.send(messages.MessageType_MessageType_Features, w.features())
+	case messages.MessageType_MessageType_Ping:
+		var msg messages.Ping
+		if w.decode(data, &msg) {
+			w.succeed(msg.GetMessage())
+		}
+	case messages.MessageType_MessageType_Cancel:
+		w.fail(messages.FailureType_Failure_ActionCancelled, "Action cancelled by user")
+	case messages.MessageType_MessageType_EntropyAck:
+		w.send(messages.MessageType_MessageType_Success, &messages.Success{
+			MsgType: messages.MessageType_MessageType_EntropyAck.Enum(),
+		})
+	case messages.MessageType_MessageType_ApplySettings:
+		w.applySettings(data)
+	case messages.MessageType_MessageType_SetMnemonic:
+		w.setMnemonic(data)
+	case messages.MessageType_MessageType_GenerateMnemonic:
+		w.generateMnemonic(data)
+	case messages.MessageType_MessageType_ChangePin:
+		w.changePin(data)
+	case messages.MessageType_MessageType_WipeDevice:
+		w.confirm(messages.ButtonRequestType_ButtonRequest_WipeDevice, w.wipe)
+	case messages.MessageType_MessageType_BackupDevice:
+		w.backup()
+	case messages.MessageType_MessageType_RecoveryDevice:
+		w.recovery(data)
+	case messages.MessageType_MessageType_SkycoinAddress:
+		w.addressGen(data)
+	case messages.MessageType_MessageType_SkycoinSignMessage:
+		w.signMessage(data)
+	case messages.MessageType_MessageType_SkycoinCheckMessageSignature:
+		w.checkMessageSignature(data)
+	case messages.MessageType_MessageType_TransactionSign:
+		w.transactionSign(data)
+	case messages.MessageType_MessageType_GetRawEntropy:
+		var msg messages.GetRawEntropy
+		if w.decode(data, &msg) {
+			w.getEntropy(msg.GetSize_(), false)
+		}
+	case messages.MessageType_MessageType_GetMixedEntropy:
+		var msg messages.GetMixedEntropy
+		if w.decode(data, &msg) {
+			w.getEntropy(msg.GetSize_(), true)
+		}
+	case messages.MessageType_MessageType_FirmwareErase, messages.MessageType_MessageType_FirmwareUpload:
+		w.fail(messages.FailureType_Failure_UnexpectedMessage, "Not in bootloader mode")
+	default:
+		w.fail(messages.FailureType_Failure_UnexpectedMessage, "Unexpected message")
+	}
+}
+
+func (w *Wallet) features() *messages.Features {
+	firmwareFeatures := uint32(featureIsGetEntropyEnabled | featureIsEmulator)
+	if w.requireGetEntropyConfirm {
+		firmwareFeatures |= featureRequireGetEntropyConfirm
+	}
+
+	return &messages.Features{
+		Vendor:               proto.String("Skycoin Foundation"),
+		MajorVersion:         proto.Uint32(firmwareMajor),
+		MinorVersion:         proto.Uint32(firmwareMinor),
+		PatchVersion:         proto.Uint32(firmwarePatch),
+		BootloaderMode:       proto.Bool(false),
+		DeviceId:             proto.String(w.deviceID),
+		PinProtection:        proto.Bool(w.pin != ""),
+		PassphraseProtection: proto.Bool(w.passphraseProtection),
+		Language:             proto.String(w.language),
+		Label:                proto.String(w.label),
+		Initialized:          proto.Bool(w.mnemonic != ""),
+		PinCached:            proto.Bool(w.pinCached),
+		PassphraseCached:     proto.Bool(w.passphrase != nil),
+		NeedsBackup:          proto.Bool(w.needsBackup),
+		Model:                proto.String("1"),
+		FwMajor:              proto.Uint32(firmwareMajor),
+		FwMinor:              proto.Uint32(firmwareMinor),
+		FwPatch:              proto.Uint32(firmwarePatch),
+		FirmwareFeatures:     proto.Uint32(firmwareFeatures),
+	}
+}
+
+func (w *Wallet) applySettings(data []byte) {
+	var msg messages.ApplySettings
+	if !w.decode(data, &msg) {
+		return
+	}
+
+	if msg.GetLanguage() != "" && msg.GetLanguage() != "english" {
+		w.fail(messages.FailureType_Failure_DataError, "Unsupported language")
+		return
+	}
+	if msg.GetLabel() == "" && msg.GetLanguage() == "" && msg.UsePassphrase == nil {
+		w.fail(messages.FailureType_Failure_DataError, "No setting provided")
+		return
+	}
+
+	w.checkPin(false, func() {
+		w.confirm(messages.ButtonRequestType_ButtonRequest_ProtectCall, func() {
+			if msg.GetLabel() != "" {
+				w.label = msg.GetLabel()
+			}
+			if msg.GetLanguage() != "" {
+				w.language = msg.GetLanguage()
+			}
+			if msg.UsePassphrase != nil {
+				w.passphraseProtection = msg.GetUsePassphrase()
+				w.passphrase = nil
+			}
+			w.succeed("Settings applied")
+		})
+	})
+}
+
+func (w *Wallet) setMnemonic(data []byte) {
+	var msg messages.SetMnemonic
+	if !w.decode(data, &msg) {
+		return
+	}
+
+	if w.mnemonic != "" {
+		w.fail(messages.FailureType_Failure_UnexpectedMessage, "Device is already initialized. Use Wipe first.")
+		return
+	}
+	if !isValidMnemonic(msg.GetMnemonic()) {
+		w.fail(messages.FailureType_Failure_DataError, "Mnemonic with wrong checksum provided")
+		return
+	}
+
+	w.confirm(messages.ButtonRequestType_ButtonRequest_ProtectCall, func() {
+		w.mnemonic = strings.Join(strings.Fields(msg.GetMnemonic()), " ")
+		w.needsBackup = false
+		w.succeed("Mnemonic successfully configured")
+	})
+}
+
+func (w *Wallet) generateMnemonic(data []byte) {
+	var msg messages.GenerateMnemonic
+	if !w.decode(data, &msg) {
+		return
+	}
+
+	if w.mnemonic != "" {
+		w.fail(messages.FailureType_Failure_UnexpectedMessage, "Device is already initialized. Use Wipe first.")
+		return
+	}
+	wordCount := msg.GetWordCount()
+	if wordCount == 0 {
+		wordCount = 12
+	}
+	if !isValidWordCount(wordCount) {
+		w.fail(messages.FailureType_Failure_DataError, "Invalid word count")
+		return
+	}
+
+	w.send(messages.MessageType_MessageType_EntropyRequest, &messages.EntropyRequest{})
+	w.expect(messages.MessageType_MessageType_EntropyAck, func(data []byte) {
+		var ack messages.EntropyAck
+		if !w.decode(data, &ack) {
+			return
+		}
+		// 128 bits of entropy for 12 words, 256 bits for 24 words
+		mixed := sha256.Sum256(append(w.internalEntropy(32), ack.GetEntropy()...))
+		w.mnemonic = newMnemonic(mixed[:wordCount/3*4])
+		w.passphraseProtection = msg.GetPassphraseProtection()
+		w.needsBackup = true
+		w.succeed("Mnemonic successfully configured")
+	})
+}
+
+func (w *Wallet) changePin(data []byte) {
+	var msg messages.ChangePin
+	if !w.decode(data, &msg) {
+		return
+	}
+
+	if msg.GetRemove() {
+		w.confirm(messages.ButtonRequestType_ButtonRequest_ProtectCall, func() {
+			w.checkPin(true, func() {
+				w.pin = ""
+				w.pinCached = false
+				w.succeed("PIN removed")
+			})
+		})
+		return
+	}
+
+	w.confirm(messages.ButtonRequestType_ButtonRequest_ProtectCall, func() {
+		w.checkPin(true, func() {
+			w.askPin(messages.PinMatrixRequestType_PinMatrixRequestType_NewFirst, func(first string) {
+				w.askPin(messages.PinMatrixRequestType_PinMatrixRequestType_NewSecond, func(second string) {
+					if first != second {
+						w.fail(messages.FailureType_Failure_PinMismatch, "PIN mismatch")
+						return
+					}
+					w.pin = first
+					w.pinCached = true
+					w.succeed("PIN changed")
+				})
+			})
+		})
+	})
+}
+
+func (w *Wallet) wipe() {
+	w.mnemonic = ""
+	w.pin = ""
+	w.label = ""
+	w.language = "english"
+	w.passphraseProtection = false
+	w.needsBackup = false
+	w.pinCached = false
+	w.passphrase = nil
+	w.deviceID = strings.ToUpper(hex.EncodeToString(w.internalEntropy(12)))
+	w.succeed("Device wiped")
+}
+
+func (w *Wallet) backup() {
+	if w.mnemonic == "" {
+		w.fail(messages.FailureType_Failure_NotInitialized, "Device not initialized")
+		return
+	}
+	if !w.needsBackup {
+		w.fail(messages.FailureType_Failure_UnexpectedMessage, "Seed already backed up")
+		return
+	}
+
+	words := strings.Fields(w.mnemonic)
+	var showWord func(i int)
+	showWord = func(i int) {
+		if i == len(words) {
+			w.needsBackup = false
+			w.succeed("Seed successfully backed up")
+			return
+		}
+		w.confirm(messages.ButtonRequestType_ButtonRequest_ConfirmWord, func() {
+			showWord(i + 1)
+		})
+	}
+	w.checkPin(false, func() {
+		showWord(0)
+	})
+}
+
+func (w *Wallet) recovery(data []byte) {
+	var msg messages.RecoveryDevice
+	if !w.decode(data, &msg) {
+		return
+	}
+
+	dryRun := msg.GetDryRun()
+	if !dryRun && w.mnemonic != "" {
+		w.fail(messages.FailureType_Failure_UnexpectedMessage, "Device is already initialized. Use Wipe first.")
+		return
+	}
+	if dryRun && w.mnemonic == "" {
+		w.fail(messages.FailureType_Failure_NotInitialized, "Device not initialized")
+		return
+	}
+	if !isValidWordCount(msg.GetWordCount()) {
+		w.fail(messages.FailureType_Failure_DataError, "Invalid word count")
+		return
+	}
+
+	var words []string
+	var askWord func()
+	askWord = func() {
+		if uint32(len(words)) == msg.GetWordCount() {
+			mnemonic := strings.Join(words, " ")
+			if !isValidMnemonic(mnemonic) {
+				w.fail(messages.FailureType_Failure_DataError, "Invalid seed, are words in correct order?")
+				return
+			}
+			if dryRun {
+				if mnemonic != w.mnemonic {
+					w.fail(messages.FailureType_Failure_DataError, "The seed is valid but does not match the one in the device")
+					return
+				}
+				w.succeed("The seed is valid and matches the one in the device")
+				return
+			}
+			w.mnemonic = mnemonic
+			w.passphraseProtection = msg.GetPassphraseProtection()
+			w.needsBackup = false
+			w.succeed("Device recovered")
+			return
+		}
+		w.send(messages.MessageType_MessageType_WordRequest, &messages.WordRequest{})
+		w.expect(messages.MessageType_MessageType_WordAck, func(data []byte) {
+			var ack messages.WordAck
+			if !w.decode(data, &ack) {
+				return
+			}
+			words = append(words, strings.TrimSpace(ack.GetWord()))
+			askWord()
+		})
+	}
+
+	w.checkPin(false, func() {
+		w.confirm(messages.ButtonRequestType_ButtonRequest_ProtectCall, askWord)
+	})
+}
+
+func (w *Wallet) addressGen(data []byte) {
+	var msg messages.SkycoinAddress
+	if !w.decode(data, &msg) {
+		return
+	}
+
+	if msg.GetAddressN() > maxAddresses {
+		w.fail(messages.FailureType_Failure_AddressGeneration, "Asking for too much addresses")
+		return
+	}
+
+	w.unlock(func() {
+		keys := w.keys(msg.GetStartIndex(), msg.GetAddressN())
+		addresses := make([]string, len(keys))
+		for i, key := range keys {
+			addresses[i] = cipher.MustAddressFromSecKey(key).String()
+		}
+		respond := func() {
+			w.send(messages.MessageType_MessageType_ResponseSkycoinAddress, &messages.ResponseSkycoinAddress{
+				Addresses: addresses,
+			})
+		}
+		if msg.GetConfirmAddress() && len(addresses) == 1 {
+			w.confirm(messages.ButtonRequestType_ButtonRequest_Address, respond)
+			return
+		}
+		respond()
+	})
+}
+
+func (w *Wallet) signMessage(data []byte) {
+	var msg messages.SkycoinSignMessage
+	if !w.decode(data, &msg) {
+		return
+	}
+
+	w.unlock(func() {
+		key := w.keys(msg.GetAddressN(), 1)[0]
+		sig, err := cipher.SignHash(skywallet.MessageHash(msg.GetMessage()), key)
+		if err != nil {
+			w.fail(messages.FailureType_Failure_ProcessError, err.Error())
+			return
+		}
+		w.send(messages.MessageType_MessageType_ResponseSkycoinSignMessage, &messages.ResponseSkycoinSignMessage{
+			SignedMessage: proto.String(sig.Hex()),
+		})
+	})
+}
+
+func (w *Wallet) checkMessageSignature(data []byte) {
+	var msg messages.SkycoinCheckMessageSignature
+	if !w.decode(data, &msg) {
+		return
+	}
+
+	address, err := cipher.DecodeBase58Address(msg.GetAddress())
+	if err != nil {
+		w.fail(messages.FailureType_Failure_InvalidSignature, "Invalid address")
+		return
+	}
+	sig, err := cipher.SigFromHex(msg.GetSignature())
+	if err != nil {
+		w.fail(messages.FailureType_Failure_InvalidSignature, "Invalid signature")
+		return
+	}
+	if err := cipher.VerifyAddressSignedHash(address, sig, skywallet.MessageHash(msg.GetMessage())); err != nil {
+		w.fail(messages.FailureType_Failure_InvalidSignature, "Invalid signature")
+		return
+	}
+	w.succeed(address.String())
+}
+
+func (w *Wallet) transactionSign(data []byte) {
+	var msg messages.TransactionSign
+	if !w.decode(data, &msg) {
+		return
+	}
+
+	if len(msg.TransactionIn) > maxTransactionInputs || len(msg.TransactionOut) > maxTransactionOutputs {
+		w.fail(messages.FailureType_Failure_DataError, "Cannot have more than 8 inputs or 8 outputs")
+		return
+	}
+	if int(msg.GetNbIn()) != len(msg.TransactionIn) || int(msg.GetNbOut()) != len(msg.TransactionOut) {
+		w.fail(messages.FailureType_Failure_DataError, "Wrong number of inputs or outputs")
+		return
+	}
+
+	tx, err := transaction.FromMessages(msg.TransactionIn, msg.TransactionOut)
+	if err != nil {
+		w.fail(messages.FailureType_Failure_DataError, err.Error())
+		return
+	}
+
+	w.unlock(func() {
+		w.confirm(messages.ButtonRequestType_ButtonRequest_SignTx, func() {
+			signatures := make([]string, len(tx.In))
+			for i := range tx.In {
+				key := w.keys(msg.TransactionIn[i].GetIndex(), 1)[0]
+				sig, err := cipher.SignHash(tx.SignedHash(i), key)
+				if err != nil {
+					w.fail(messages.FailureType_Failure_ProcessError, err.Error())
+					return
+				}
+				signatures[i] = sig.Hex()
+			}
+			w.send(messages.MessageType_MessageType_ResponseTransactionSign, &messages.ResponseTransactionSign{
+				Signatures: signatures,
+				Padding:    proto.Bool(true),
+			})
+		})
+	})
+}
+
+func (w *Wallet) getEntropy(size uint32, mixed bool) {
+	if size > maxEntropyBytes {
+		size = maxEntropyBytes
+	}
+
+	respond := func() {
+		entropy := w.internalEntropy(int(size))
+		if mixed {
+			for i := 0; i < len(entropy); i += sha256.Size {
+				h := sha256.Sum256(entropy[i:])
+				copy(entropy[i:], h[:])
+			}
+		}
+		w.send(messages.MessageType_MessageType_Entropy, &messages.Entropy{
+			Entropy: entropy,
+		})
+	}
+
+	if w.requireGetEntropyConfirm {
+		w.confirm(messages.ButtonRequestType_ButtonRequest_ProtectCall, respond)
+		return
+	}
+	respond()
+}
+
+// internalEntropy returns deterministic bytes derived from the wallet seed
+func (w *Wallet) internalEntropy(n int) []byte {
+	var buf bytes.Buffer
+	for buf.Len() < n {
+		w.entropyCounter++
+		var counter [8]byte
+		binary.BigEndian.PutUint64(counter[:], w.entropyCounter)
+		h := sha256.Sum256(append(append([]byte{}, w.seed...), counter[:]...))
+		buf.Write(h[:])
+	}
+	return buf.Bytes()[:n]
+}
+
+// keys returns the secret keys for the addresses in [start, start+n).
+// When a passphrase is set it is appended to the mnemonic to derive a different wallet.
+func (w *Wallet) keys(start, n uint32) []cipher.SecKey {
+	seed := w.mnemonic
+	if w.passphrase != nil && *w.passphrase != "" {
+		seed += " " + *w.passphrase
+	}
+	keys := cipher.MustGenerateDeterministicKeyPairs([]byte(seed), int(start+n))
+	return keys[start:]
+}
+
+func isValidWordCount(wordCount uint32) bool {
+	return wordCount == 12 || wordCount == 24
+}
+
+func isValidPin(pin string) bool {
+	if len(pin) == 0 || len(pin) > 9 {
+		return false
+	}
+	for _, c := range pin {
+		if c < '1' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
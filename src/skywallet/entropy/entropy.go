@@ -0,0 +1,325 @@
+/*
+Package entropy runs randomness health checks on the entropy read from a device.
+
+An Analyzer is written the entropy as it arrives and keeps running counts only, so
+streams of any size are analyzed in constant memory. Its Report holds the results of:
+
+	monobit                the proportion of ones bits, NIST SP 800-22 2.1
+	runs                   the number of runs of identical bits, NIST SP 800-22 2.3
+	chi_square             the distribution of the byte values, 255 degrees of freedom
+	repetition_count       the longest run of an identical byte, NIST SP 800-90B 4.4.1
+	adaptive_proportion    the occurrences of a byte in a window, NIST SP 800-90B 4.4.2
+
+The statistical tests pass if their p-value is at least Alpha. The health tests treat each
+byte as a sample having the min-entropy given to NewAnalyzer and pass if their statistic
+stays below the cutoff giving a false positive probability of 2^-20 per sample.
+*/
+package entropy
+
+import (
+	"fmt"
+	"math"
+	"math/bits"
+)
+
+const (
+	// Alpha is the significance level of the statistical tests
+	Alpha = 0.01
+	// DefaultMinEntropy is the min-entropy per byte claimed for the device noise source
+	DefaultMinEntropy = 4.0
+	// AdaptiveProportionWindow is the number of bytes of a window of the adaptive proportion test
+	AdaptiveProportionWindow = 512
+
+	// healthAlpha is the false positive probability per sample of the health tests, 2^-20
+	healthAlpha = 1.0 / (1 << 20)
+	// minBits is the number of bits needed by the monobit and runs tests
+	minBits = 100
+	// minChiSquareBytes gives an expected count of 5 per byte value
+	minChiSquareBytes = 5 * 256
+)
+
+// Result is the result of a test
+type Result struct {
+	Name   string `json:"name"`
+	Passed bool   `json:"passed"`
+	// Skipped is set if there was not enough entropy to run the test, the test did not pass
+	Skipped bool `json:"skipped,omitempty"`
+	// Statistic is the value computed by the test, e.g. the longest run of the repetition count test
+	Statistic float64 `json:"statistic"`
+	// PValue is set for the statistical tests
+	PValue float64 `json:"p_value,omitempty"`
+	// Cutoff is set for the health tests, the test fails if Statistic reaches it
+	Cutoff int `json:"cutoff,omitempty"`
+}
+
+func (r Result) String() string {
+	status := "PASS"
+	switch {
+	case r.Skipped:
+		return fmt.Sprintf("%-20s SKIP not enough entropy", r.Name)
+	case !r.Passed:
+		status = "FAIL"
+	}
+	// counts are printed as integers
+	statistic := fmt.Sprintf("%.4f", r.Statistic)
+	if r.Statistic == math.Trunc(r.Statistic) {
+		statistic = fmt.Sprintf("%.0f", r.Statistic)
+	}
+	if r.Cutoff != 0 {
+		return fmt.Sprintf("%-20s %s %s (cutoff %d)", r.Name, status, statistic, r.Cutoff)
+	}
+	return fmt.Sprintf("%-20s %s %s (p-value %.4f)", r.Name, status, statistic, r.PValue)
+}
+
+// Report is the result of the tests run on the analyzed entropy
+type Report struct {
+	Bytes      int64    `json:"bytes"`
+	MinEntropy float64  `json:"min_entropy"`
+	Passed     bool     `json:"passed"`
+	Results    []Result `json:"results"`
+}
+
+// Analyzer computes the statistics of the entropy written to it
+type Analyzer struct {
+	minEntropy float64
+
+	bytes int64
+	ones  int64
+	// transitions is the number of adjacent bits which differ
+	transitions int64
+	counts      [256]int64
+	last        byte
+
+	// run is the length of the current run of identical bytes, maxRun the longest one
+	run    int
+	maxRun int
+
+	// windowValue is the first byte of the current adaptive proportion window,
+	// windowCount its occurrences in the window and windowPos the bytes in the window
+	windowValue byte
+	windowCount int
+	windowPos   int
+	maxWindow   int
+	windows     int64
+}
+
+// NewAnalyzer returns an analyzer checking the entropy against a min-entropy of minEntropy bits per byte,
+// DefaultMinEntropy is used if minEntropy is not in (0, 8]
+func NewAnalyzer(minEntropy float64) *Analyzer {
+	if minEntropy <= 0 || minEntropy > 8 {
+		minEntropy = DefaultMinEntropy
+	}
+	return &Analyzer{
+		minEntropy: minEntropy,
+	}
+}
+
+// Write updates the statistics with p, it never fails
+func (a *Analyzer) Write(p []byte) (int, error) {
+	for _, b := range p {
+		// the bits of a byte are taken most significant first
+		a.transitions += int64(bits.OnesCount8((b ^ (b >> 1)) & 0x7f))
+		if a.bytes > 0 && a.last&1 != b>>7 {
+			a.transitions++
+		}
+		a.ones += int64(bits.OnesCount8(b))
+		a.counts[b]++
+
+		if a.bytes > 0 && b == a.last {
+			a.run++
+		} else {
+			a.run = 1
+		}
+		if a.run > a.maxRun {
+			a.maxRun = a.run
+		}
+
+		if a.windowPos == 0 {
+			a.windowValue = b
+			a.windowCount = 0
+		}
+		if b == a.windowValue {
+			a.windowCount++
+		}
+		a.windowPos++
+		if a.windowCount > a.maxWindow {
+			a.maxWindow = a.windowCount
+		}
+		if a.windowPos == AdaptiveProportionWindow {
+			a.windowPos = 0
+			a.windows++
+		}
+
+		a.last = b
+		a.bytes++
+	}
+	return len(p), nil
+}
+
+// Report runs the tests on the entropy written so far
+func (a *Analyzer) Report() Report {
+	report := Report{
+		Bytes:      a.bytes,
+		MinEntropy: a.minEntropy,
+		Results: []Result{
+			a.monobit(),
+			a.runs(),
+			a.chiSquare(),
+			a.repetitionCount(),
+			a.adaptiveProportion(),
+		},
+	}
+
+	report.Passed = true
+	for _, r := range report.Results {
+		report.Passed = report.Passed && r.Passed
+	}
+	return report
+}
+
+func statisticalResult(name string, statistic, pValue float64) Result {
+	return Result{
+		Name:      name,
+		Passed:    pValue >= Alpha,
+		Statistic: statistic,
+		PValue:    pValue,
+	}
+}
+
+func (a *Analyzer) monobit() Result {
+	n := float64(a.bytes * 8)
+	if n < minBits {
+		return Result{Name: "monobit", Skipped: true}
+	}
+
+	s := math.Abs(float64(2*a.ones)-n) / math.Sqrt(n)
+	return statisticalResult("monobit", s, math.Erfc(s/math.Sqrt2))
+}
+
+func (a *Analyzer) runs() Result {
+	n := float64(a.bytes * 8)
+	if n < minBits {
+		return Result{Name: "runs", Skipped: true}
+	}
+
+	v := float64(a.transitions + 1)
+	pi := float64(a.ones) / n
+	// the test is not applicable if the monobit test would fail, it fails
+	if math.Abs(pi-0.5) >= 2/math.Sqrt(n) {
+		return statisticalResult("runs", v, 0)
+	}
+
+	pValue := math.Erfc(math.Abs(v-2*n*pi*(1-pi)) / (2 * math.Sqrt(2*n) * pi * (1 - pi)))
+	return statisticalResult("runs", v, pValue)
+}
+
+func (a *Analyzer) chiSquare() Result {
+	if a.bytes < minChiSquareBytes {
+		return Result{Name: "chi_square", Skipped: true}
+	}
+
+	expected := float64(a.bytes) / 256
+	var chi2 float64
+	for _, count := range a.counts {
+		d := float64(count) - expected
+		chi2 += d * d / expected
+	}
+	return statisticalResult("chi_square", chi2, gammaQ(255.0/2, chi2/2))
+}
+
+func (a *Analyzer) repetitionCount() Result {
+	if a.bytes == 0 {
+		return Result{Name: "repetition_count", Skipped: true}
+	}
+
+	cutoff := RepetitionCountCutoff(a.minEntropy)
+	return Result{
+		Name:      "repetition_count",
+		Passed:    a.maxRun < cutoff,
+		Statistic: float64(a.maxRun),
+		Cutoff:    cutoff,
+	}
+}
+
+func (a *Analyzer) adaptiveProportion() Result {
+	if a.windows == 0 {
+		return Result{Name: "adaptive_proportion", Skipped: true}
+	}
+
+	cutoff := AdaptiveProportionCutoff(a.minEntropy)
+	return Result{
+		Name:      "adaptive_proportion",
+		Passed:    a.maxWindow < cutoff,
+		Statistic: float64(a.maxWindow),
+		Cutoff:    cutoff,
+	}
+}
+
+// RepetitionCountCutoff returns the run length of an identical byte failing the repetition count test
+func RepetitionCountCutoff(minEntropy float64) int {
+	return 1 + int(math.Ceil(-math.Log2(healthAlpha)/minEntropy))
+}
+
+// AdaptiveProportionCutoff returns the occurrences of the first byte of a window failing the adaptive proportion test
+func AdaptiveProportionCutoff(minEntropy float64) int {
+	return 1 + critBinom(AdaptiveProportionWindow, math.Pow(2, -minEntropy), 1-healthAlpha)
+}
+
+// critBinom returns the smallest k for which the binomial distribution of n trials of
+// probability p has a cumulative probability of at least q
+func critBinom(n int, p, q float64) int {
+	pmf := math.Pow(1-p, float64(n))
+	cdf := pmf
+	k := 0
+	for cdf < q && k < n {
+		pmf *= float64(n-k) / float64(k+1) * p / (1 - p)
+		cdf += pmf
+		k++
+	}
+	return k
+}
+
+// gammaQ returns the regularized upper incomplete gamma function Q(s, x)
+func gammaQ(s, x float64) float64 {
+	if x <= 0 {
+		return 1
+	}
+	lgamma, _ := math.Lgamma(s)
+	prefix := math.Exp(s*math.Log(x) - x - lgamma)
+
+	if x < s+1 {
+		// series of the lower function P
+		sum, term := 1/s, 1/s
+		for i := 1; i < 1000 && term > sum*1e-15; i++ {
+			term *= x / (s + float64(i))
+			sum += term
+		}
+		return 1 - prefix*sum
+	}
+
+	// continued fraction, modified Lentz method
+	const tiny = 1e-300
+	b := x + 1 - s
+	c := 1 / tiny
+	d := 1 / b
+	h := d
+	for i := 1; i < 1000; i++ {
+		an := -float64(i) * (float64(i) - s)
+		b += 2
+		d = an*d + b
+		if math.Abs(d) < tiny {
+			d = tiny
+		}
+		c = b + an/c
+		if math.Abs(c) < tiny {
+			c = tiny
+		}
+		d = 1 / d
+		delta := d * c
+		h *= delta
+		if math.Abs(delta-1) < 1e-15 {
+			break
+		}
+	}
+	return prefix * h
+}
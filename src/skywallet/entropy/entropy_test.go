@@ -0,0 +1,157 @@
+package entropy
+
+import (
+	"bytes"
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func randomBytes(n int) []byte {
+	data := make([]byte, n)
+	rand.New(rand.NewSource(1)).Read(data) // nolint: gosec
+	return data
+}
+
+func requireResult(t *testing.T, report Report, name string, passed bool) Result {
+	for _, r := range report.Results {
+		if r.Name == name {
+			require.Equal(t, passed, r.Passed, r.String())
+			return r
+		}
+	}
+	t.Fatalf("no %s result", name)
+	return Result{}
+}
+
+func TestAnalyzer(t *testing.T) {
+	tt := []struct {
+		name    string
+		data    []byte
+		passed  bool
+		results map[string]bool
+	}{
+		{
+			name:   "random",
+			data:   randomBytes(1 << 16),
+			passed: true,
+			results: map[string]bool{
+				"monobit":             true,
+				"runs":                true,
+				"chi_square":          true,
+				"repetition_count":    true,
+				"adaptive_proportion": true,
+			},
+		},
+		{
+			name:   "stuck",
+			data:   bytes.Repeat([]byte{0xa5}, 1<<12),
+			passed: false,
+			results: map[string]bool{
+				"chi_square":          false,
+				"repetition_count":    false,
+				"adaptive_proportion": false,
+			},
+		},
+		{
+			// alternating bits have the expected proportion of ones but too many runs
+			name:   "alternating bits",
+			data:   bytes.Repeat([]byte{0x55, 0x55, 0xaa, 0xaa}, 1<<10),
+			passed: false,
+			results: map[string]bool{
+				"monobit": true,
+				"runs":    false,
+			},
+		},
+		{
+			name: "biased",
+			data: func() []byte {
+				data := randomBytes(1 << 12)
+				for i := range data {
+					data[i] |= 0x81
+				}
+				return data
+			}(),
+			passed: false,
+			results: map[string]bool{
+				"monobit": false,
+				"runs":    false,
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			a := NewAnalyzer(0)
+			n, err := a.Write(tc.data)
+			require.NoError(t, err)
+			require.Equal(t, len(tc.data), n)
+
+			report := a.Report()
+			require.Equal(t, int64(len(tc.data)), report.Bytes)
+			require.Equal(t, DefaultMinEntropy, report.MinEntropy)
+			require.Equal(t, tc.passed, report.Passed)
+			for name, passed := range tc.results {
+				requireResult(t, report, name, passed)
+			}
+		})
+	}
+}
+
+func TestAnalyzerStream(t *testing.T) {
+	data := randomBytes(5000)
+
+	whole := NewAnalyzer(0)
+	_, err := whole.Write(data)
+	require.NoError(t, err)
+
+	// the entropy is analyzed the same whatever the size of the buffers
+	streamed := NewAnalyzer(0)
+	for i := 0; i < len(data); i += 37 {
+		end := i + 37
+		if end > len(data) {
+			end = len(data)
+		}
+		_, err := streamed.Write(data[i:end])
+		require.NoError(t, err)
+	}
+	require.Equal(t, whole.Report(), streamed.Report())
+}
+
+func TestAnalyzerNotEnoughEntropy(t *testing.T) {
+	a := NewAnalyzer(8)
+	_, err := a.Write([]byte{1, 2, 3})
+	require.NoError(t, err)
+	report := a.Report()
+	require.False(t, report.Passed)
+	requireResult(t, report, "repetition_count", true)
+	for _, name := range []string{"monobit", "runs", "chi_square", "adaptive_proportion"} {
+		r := requireResult(t, report, name, false)
+		require.True(t, r.Skipped)
+	}
+}
+
+func TestCutoffs(t *testing.T) {
+	// NIST SP 800-90B 4.4.1 and table 2 of 4.4.2
+	require.Equal(t, 21, RepetitionCountCutoff(1))
+	require.Equal(t, 4, RepetitionCountCutoff(8))
+	require.Equal(t, 410, AdaptiveProportionCutoff(0.5))
+	require.Equal(t, 311, AdaptiveProportionCutoff(1))
+	require.Equal(t, 177, AdaptiveProportionCutoff(2))
+	require.Equal(t, 62, AdaptiveProportionCutoff(4))
+	require.Equal(t, 13, AdaptiveProportionCutoff(8))
+}
+
+func TestGammaQ(t *testing.T) {
+	// Q(1, x) is exp(-x)
+	for _, x := range []float64{0.1, 1, 2.5, 10} {
+		require.InDelta(t, math.Exp(-x), gammaQ(1, x), 1e-12)
+	}
+	// Q(1/2, x) is erfc(sqrt(x))
+	for _, x := range []float64{0.2, 1, 4} {
+		require.InDelta(t, math.Erfc(math.Sqrt(x)), gammaQ(0.5, x), 1e-12)
+	}
+	require.Equal(t, 1.0, gammaQ(127.5, 0))
+}
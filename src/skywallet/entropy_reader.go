@@ -0,0 +1,123 @@
+package skywallet
+
+import (
+	"context"
+	"errors"
+	"math"
+
+	messages "github.com/skycoin/hardware-wallet-protob/go"
+)
+
+// entropyReadMin is the least number of bytes an EntropyReader asks the device for,
+// the bytes not read yet are returned by the next reads
+const entropyReadMin = 32
+
+// ErrNoEntropy is returned if the device answers a request for entropy without entropy
+var ErrNoEntropy = errors.New("device returned no entropy")
+
+// GetEntropy asks the device for entropyBytes of entropy through the request built by
+// getEntropyMsgBuilder, MessageDeviceGetRawEntropy or MessageDeviceGetMixedEntropy.
+// The button requests are acknowledged, the device may return less entropy than asked.
+func (s *Session) GetEntropy(entropyBytes uint32, getEntropyMsgBuilder func(entropyBytes uint32) ([][64]byte, error)) ([]byte, error) {
+	return s.GetEntropyContext(context.Background(), entropyBytes, getEntropyMsgBuilder)
+}
+
+// GetEntropyContext is like GetEntropy but the request is cancelled if ctx is done
+func (s *Session) GetEntropyContext(ctx context.Context, entropyBytes uint32, getEntropyMsgBuilder func(entropyBytes uint32) ([][64]byte, error)) ([]byte, error) {
+	chunks, err := getEntropyMsgBuilder(entropyBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	msg, err := s.send(ctx, chunks)
+	for err == nil && msg.Kind == uint16(messages.MessageType_MessageType_ButtonRequest) {
+		msg, err = s.ButtonAckContext(ctx)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := expectMessage(msg, messages.MessageType_MessageType_Entropy); err != nil {
+		return nil, err
+	}
+
+	entropy, err := DecodeResponseEntropyMessage(msg)
+	if err != nil {
+		return nil, err
+	}
+	if len(entropy.GetEntropy()) == 0 {
+		return nil, ErrNoEntropy
+	}
+	return entropy.GetEntropy(), nil
+}
+
+// EntropyReader is an io.Reader of the device entropy, a Read asks the device for
+// entropy if none is left from the previous request
+type EntropyReader struct {
+	ctx                  context.Context
+	session              *Session
+	getEntropyMsgBuilder func(entropyBytes uint32) ([][64]byte, error)
+	// closeSession is set if the reader opened the session
+	closeSession bool
+	buf          []byte
+}
+
+// NewEntropyReader returns a reader of the entropy requested through getEntropyMsgBuilder,
+// MessageDeviceGetRawEntropy or MessageDeviceGetMixedEntropy. The requests are cancelled if ctx is done.
+func (s *Session) NewEntropyReader(ctx context.Context, getEntropyMsgBuilder func(entropyBytes uint32) ([][64]byte, error)) *EntropyReader {
+	return &EntropyReader{
+		ctx:                  ctx,
+		session:              s,
+		getEntropyMsgBuilder: getEntropyMsgBuilder,
+	}
+}
+
+// OpenEntropyReader is like Session.NewEntropyReader but the reader opens a session of its own,
+// the device is locked until the reader is closed
+func (d *Device) OpenEntropyReader(ctx context.Context, getEntropyMsgBuilder func(entropyBytes uint32) ([][64]byte, error)) (*EntropyReader, error) {
+	s, err := d.OpenSession()
+	if err != nil {
+		return nil, err
+	}
+
+	r := s.NewEntropyReader(ctx, getEntropyMsgBuilder)
+	r.closeSession = true
+	return r, nil
+}
+
+// Read reads up to len(p) bytes of entropy, the device is asked for len(p) bytes
+// if no entropy is left from the previous request
+func (r *EntropyReader) Read(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+
+	if len(r.buf) == 0 {
+		size := uint64(len(p))
+		if size < entropyReadMin {
+			size = entropyReadMin
+		}
+		if size > math.MaxUint32 {
+			size = math.MaxUint32
+		}
+
+		entropy, err := r.session.GetEntropyContext(r.ctx, uint32(size), r.getEntropyMsgBuilder)
+		if err != nil {
+			return 0, err
+		}
+		r.buf = entropy
+	}
+
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
+
+// Close closes the session opened by Device.OpenEntropyReader,
+// the session given to Session.NewEntropyReader is left open
+func (r *EntropyReader) Close() error {
+	if !r.closeSession {
+		return nil
+	}
+	return r.session.Close()
+}
@@ -0,0 +1,133 @@
+package skywallet
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/gogo/protobuf/proto"
+
+	messages "github.com/skycoin/hardware-wallet-protob/go"
+
+	"github.com/skycoin/hardware-wallet-go/src/skywallet/wire"
+)
+
+// Errors matching the messages.Failure codes sent by the device, check them with errors.Is
+var (
+	// ErrUnexpectedMessage is returned if the device did not expect the message sent
+	ErrUnexpectedMessage = errors.New("unexpected message")
+	// ErrButtonExpected is returned if the device expected a ButtonAck
+	ErrButtonExpected = errors.New("button expected")
+	// ErrDataError is returned if the device rejected the request data
+	ErrDataError = errors.New("data error")
+	// ErrActionCancelled is returned if the action was cancelled on the device
+	ErrActionCancelled = errors.New("action cancelled")
+	// ErrPinExpected is returned if the device expected a PinMatrixAck
+	ErrPinExpected = errors.New("pin expected")
+	// ErrPinCancelled is returned if the PIN entry was cancelled
+	ErrPinCancelled = errors.New("pin cancelled")
+	// ErrPinInvalid is returned if the PIN is wrong
+	ErrPinInvalid = errors.New("pin invalid")
+	// ErrInvalidSignature is returned if a signature does not match
+	ErrInvalidSignature = errors.New("invalid signature")
+	// ErrProcessError is returned if the device failed processing the request
+	ErrProcessError = errors.New("process error")
+	// ErrNotEnoughFunds is returned if there are not enough funds
+	ErrNotEnoughFunds = errors.New("not enough funds")
+	// ErrNotInitialized is returned if the device has no seed configured
+	ErrNotInitialized = errors.New("device not initialized")
+	// ErrPinMismatch is returned if the PIN confirmation does not match
+	ErrPinMismatch = errors.New("pin mismatch")
+	// ErrAddressGeneration is returned if the device failed generating addresses
+	ErrAddressGeneration = errors.New("address generation failed")
+	// ErrFirmwarePanic is returned if the firmware crashed
+	ErrFirmwarePanic = errors.New("firmware panic")
+	// ErrFirmwareError is returned on unspecified firmware errors
+	ErrFirmwareError = errors.New("firmware error")
+)
+
+var failureErrors = map[messages.FailureType]error{
+	messages.FailureType_Failure_UnexpectedMessage: ErrUnexpectedMessage,
+	messages.FailureType_Failure_ButtonExpected:    ErrButtonExpected,
+	messages.FailureType_Failure_DataError:         ErrDataError,
+	messages.FailureType_Failure_ActionCancelled:   ErrActionCancelled,
+	messages.FailureType_Failure_PinExpected:       ErrPinExpected,
+	messages.FailureType_Failure_PinCancelled:      ErrPinCancelled,
+	messages.FailureType_Failure_PinInvalid:        ErrPinInvalid,
+	messages.FailureType_Failure_InvalidSignature:  ErrInvalidSignature,
+	messages.FailureType_Failure_ProcessError:      ErrProcessError,
+	messages.FailureType_Failure_NotEnoughFunds:    ErrNotEnoughFunds,
+	messages.FailureType_Failure_NotInitialized:    ErrNotInitialized,
+	messages.FailureType_Failure_PinMismatch:       ErrPinMismatch,
+	messages.FailureType_Failure_AddressGeneration: ErrAddressGeneration,
+	messages.FailureType_Failure_FirmwarePanic:     ErrFirmwarePanic,
+	messages.FailureType_Failure_FirmwareError:     ErrFirmwareError,
+}
+
+// FailureError is a messages.Failure sent by the device
+type FailureError struct {
+	// MsgType is the type of the message the device answered with the failure
+	MsgType messages.MessageType
+	Code    messages.FailureType
+	Message string
+}
+
+func (e *FailureError) Error() string {
+	if e.Message == "" {
+		return e.Code.String()
+	}
+	return e.Message
+}
+
+// Is reports whether target is the error matching the failure code
+func (e *FailureError) Is(target error) bool {
+	err, ok := failureErrors[e.Code]
+	return ok && err == target
+}
+
+// DecodeFailureError convert byte data into a FailureError
+func DecodeFailureError(msg wire.Message) (*FailureError, error) {
+	if msg.Kind != uint16(messages.MessageType_MessageType_Failure) {
+		return nil, fmt.Errorf("calling DecodeFailureError with wrong message type: %s", messages.MessageType(msg.Kind))
+	}
+
+	failure := &messages.Failure{}
+	if err := proto.Unmarshal(msg.Data, failure); err != nil {
+		return nil, err
+	}
+
+	return &FailureError{
+		Code:    failure.GetCode(),
+		Message: failure.GetMessage(),
+	}, nil
+}
+
+// checkFailure returns a FailureError if msg is a Failure answering a message of type msgType
+func checkFailure(msg wire.Message, msgType messages.MessageType) error {
+	if msg.Kind != uint16(messages.MessageType_MessageType_Failure) {
+		return nil
+	}
+
+	failure, err := DecodeFailureError(msg)
+	if err != nil {
+		return err
+	}
+	failure.MsgType = msgType
+	return failure
+}
+
+// expectMessage returns nil if msg is of the given kind, a FailureError if msg is a Failure
+// and an error for any other message kind
+func expectMessage(msg wire.Message, kind messages.MessageType) error {
+	switch msg.Kind {
+	case uint16(kind):
+		return nil
+	case uint16(messages.MessageType_MessageType_Failure):
+		failure, err := DecodeFailureError(msg)
+		if err != nil {
+			return err
+		}
+		return failure
+	default:
+		return fmt.Errorf("received unexpected message type: %s", messages.MessageType(msg.Kind))
+	}
+}
@@ -0,0 +1,252 @@
+/*
+Package firmware parses Skywallet firmware images and validates them against the
+device features before they are uploaded.
+
+An image is a 256 bytes header followed by the firmware code:
+
+	offset  size  field
+	0x00    4     magic, "SKY1"
+	0x04    4     code length, little endian
+	0x08    3     indices of the keys that signed the image, 0 for an empty signature slot
+	0x0B    1     flags
+	0x0C    52    reserved
+	0x40    3*64  signature slots
+
+This is the header of the Trezor One legacy images, which the Skywallet bootloader
+inherits with its own magic. It holds no firmware version, the version of an image
+is the one it is released under and is given to Validate through Policy.ImageVersion.
+
+The code hash, signed by the release keys and confirmed on the device, is the
+SHA256 of the image without its header.
+*/
+package firmware
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	messages "github.com/skycoin/hardware-wallet-protob/go"
+)
+
+const (
+	// Magic is the magic string starting Skywallet firmware images
+	Magic = "SKY1"
+	// HeaderSize is the size of the image header excluded from the code hash
+	HeaderSize = 0x100
+	// MaxImageSize is the size of the flash area holding the firmware
+	MaxImageSize = 0xF0000
+	// SignatureSlots is the number of signatures in the image header
+	SignatureSlots = 3
+	// KeyCount is the number of release keys known by the bootloader, key indices start at 1
+	KeyCount = 5
+
+	signatureSize   = 64
+	signatureOffset = 0x40
+)
+
+var (
+	// ErrInvalidMagic is returned if the image does not start with Magic
+	ErrInvalidMagic = errors.New("not a Skywallet firmware image, invalid magic")
+	// ErrImageTooShort is returned if the image is not longer than its header
+	ErrImageTooShort = errors.New("firmware image too short")
+	// ErrImageTooLarge is returned if the image does not fit in the device flash
+	ErrImageTooLarge = errors.New("firmware image too large")
+	// ErrCodeLength is returned if the code length in the header does not match the image size
+	ErrCodeLength = errors.New("firmware code length does not match the image size")
+	// ErrNotBootloaderMode is returned if the device is not in bootloader mode
+	ErrNotBootloaderMode = errors.New("device is not in bootloader mode")
+	// ErrUnsigned is returned if the image is not signed by SignatureSlots release keys
+	ErrUnsigned = errors.New("firmware image is not signed")
+	// ErrInvalidVersion is returned by ParseVersion if the version is not formatted as major.minor.patch
+	ErrInvalidVersion = errors.New("invalid firmware version, expected major.minor.patch")
+)
+
+// Version is a firmware version
+type Version struct {
+	Major uint32 `json:"major"`
+	Minor uint32 `json:"minor"`
+	Patch uint32 `json:"patch"`
+}
+
+// ParseVersion parses a version formatted as major.minor.patch, with an optional "v" prefix
+func ParseVersion(s string) (Version, error) {
+	parts := strings.Split(strings.TrimPrefix(s, "v"), ".")
+	if len(parts) != 3 {
+		return Version{}, ErrInvalidVersion
+	}
+
+	var numbers [3]uint32
+	for i, part := range parts {
+		n, err := strconv.ParseUint(part, 10, 32)
+		if err != nil {
+			return Version{}, ErrInvalidVersion
+		}
+		numbers[i] = uint32(n)
+	}
+
+	return Version{
+		Major: numbers[0],
+		Minor: numbers[1],
+		Patch: numbers[2],
+	}, nil
+}
+
+// IsZero reports whether the version is not set
+func (v Version) IsZero() bool {
+	return v == Version{}
+}
+
+// Less reports whether v is older than other
+func (v Version) Less(other Version) bool {
+	if v.Major != other.Major {
+		return v.Major < other.Major
+	}
+	if v.Minor != other.Minor {
+		return v.Minor < other.Minor
+	}
+	return v.Patch < other.Patch
+}
+
+func (v Version) String() string {
+	return fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+}
+
+// DowngradeError is returned if the image version is older than the installed firmware
+type DowngradeError struct {
+	Installed Version
+	Image     Version
+}
+
+func (e *DowngradeError) Error() string {
+	return fmt.Sprintf("firmware image %s is older than the installed firmware %s", e.Image, e.Installed)
+}
+
+// Image is a parsed firmware image
+type Image struct {
+	CodeLength uint32
+	// KeyIndices are the indices of the keys that signed the image, 0 for an empty slot
+	KeyIndices [SignatureSlots]uint8
+	Flags      uint8
+	Signatures [SignatureSlots][signatureSize]byte
+
+	data []byte
+}
+
+// Parse parses a firmware image, checking its magic and size
+func Parse(data []byte) (*Image, error) {
+	if len(data) <= HeaderSize {
+		return nil, ErrImageTooShort
+	}
+	if len(data) > MaxImageSize {
+		return nil, ErrImageTooLarge
+	}
+	if string(data[:len(Magic)]) != Magic {
+		return nil, ErrInvalidMagic
+	}
+
+	img := &Image{
+		CodeLength: binary.LittleEndian.Uint32(data[0x04:]),
+		Flags:      data[0x0B],
+		data:       data,
+	}
+	if int(img.CodeLength) != len(data)-HeaderSize {
+		return nil, ErrCodeLength
+	}
+
+	copy(img.KeyIndices[:], data[0x08:0x0B])
+	for i := range img.Signatures {
+		offset := signatureOffset + i*signatureSize
+		copy(img.Signatures[i][:], data[offset:offset+signatureSize])
+	}
+
+	return img, nil
+}
+
+// Bytes returns the image as uploaded to the device
+func (img *Image) Bytes() []byte {
+	return img.data
+}
+
+// Code returns the firmware code following the header
+func (img *Image) Code() []byte {
+	return img.data[HeaderSize:]
+}
+
+// Hash returns the code hash confirmed on the device during the upload
+func (img *Image) Hash() [32]byte {
+	return sha256.Sum256(img.Code())
+}
+
+// Signed reports whether every signature slot holds a signature of a distinct release key.
+// The signatures themselves are checked by the bootloader.
+func (img *Image) Signed() bool {
+	var seen [KeyCount + 1]bool
+	for i, index := range img.KeyIndices {
+		if index == 0 || index > KeyCount || seen[index] {
+			return false
+		}
+		seen[index] = true
+		if img.Signatures[i] == [signatureSize]byte{} {
+			return false
+		}
+	}
+	return true
+}
+
+// Policy tells which risky uploads Validate accepts with a warning instead of an error
+type Policy struct {
+	AllowDowngrade bool
+	AllowUnsigned  bool
+	// ImageVersion is the version the image is released under, downgrades can not be detected if it is not set
+	ImageVersion Version
+}
+
+// Validate checks that the image can be uploaded to the device with the given features.
+// Uploads accepted by policy are reported in the returned warnings.
+// Unsigned images are refused on devices with memory read protection enabled, those are
+// production devices, regardless of policy.
+func (img *Image) Validate(features *messages.Features, policy Policy) ([]string, error) {
+	if !features.GetBootloaderMode() {
+		return nil, ErrNotBootloaderMode
+	}
+
+	var warnings []string
+	if !img.Signed() {
+		if rdpLevel(features.GetFirmwareFeatures()) == 2 || !policy.AllowUnsigned {
+			return nil, ErrUnsigned
+		}
+		warnings = append(warnings, "firmware image is not signed")
+	}
+
+	installed := Version{
+		Major: features.GetFwMajor(),
+		Minor: features.GetFwMinor(),
+		Patch: features.GetFwPatch(),
+	}
+	switch {
+	case !features.GetFirmwarePresent() || installed.IsZero():
+		// no installed firmware to compare with
+	case policy.ImageVersion.IsZero():
+		warnings = append(warnings, "firmware image version not given, downgrades can not be detected")
+	case policy.ImageVersion.Less(installed):
+		if !policy.AllowDowngrade {
+			return nil, &DowngradeError{
+				Installed: installed,
+				Image:     policy.ImageVersion,
+			}
+		}
+		warnings = append(warnings, fmt.Sprintf("downgrading firmware from %s to %s", installed, policy.ImageVersion))
+	}
+
+	return warnings, nil
+}
+
+// rdpLevel decodes the memory read protection level of the Features.FirmwareFeatures flags,
+// see skywallet.FirmwareFeatures
+func rdpLevel(flags uint32) uint8 {
+	return uint8(flags>>3) & 0x3
+}
@@ -0,0 +1,136 @@
+package firmware
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"testing"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/stretchr/testify/require"
+
+	messages "github.com/skycoin/hardware-wallet-protob/go"
+)
+
+// testImage returns a signed image
+func testImage(codeLength int) []byte {
+	data := make([]byte, HeaderSize+codeLength)
+	copy(data, Magic)
+	binary.LittleEndian.PutUint32(data[0x04:], uint32(codeLength))
+	copy(data[0x08:], []byte{1, 2, 3})
+	for i := signatureOffset; i < HeaderSize; i++ {
+		data[i] = 0xAA
+	}
+	for i := HeaderSize; i < len(data); i++ {
+		data[i] = byte(i)
+	}
+	return data
+}
+
+func bootloaderFeatures(major, minor, patch uint32) *messages.Features {
+	return &messages.Features{
+		BootloaderMode:  proto.Bool(true),
+		FirmwarePresent: proto.Bool(true),
+		FwMajor:         proto.Uint32(major),
+		FwMinor:         proto.Uint32(minor),
+		FwPatch:         proto.Uint32(patch),
+	}
+}
+
+func TestParse(t *testing.T) {
+	data := testImage(1024)
+	img, err := Parse(data)
+	require.NoError(t, err)
+	require.Equal(t, uint32(1024), img.CodeLength)
+	require.Equal(t, [SignatureSlots]uint8{1, 2, 3}, img.KeyIndices)
+	require.Equal(t, sha256.Sum256(data[HeaderSize:]), img.Hash())
+	require.Equal(t, data, img.Bytes())
+	require.True(t, img.Signed())
+
+	_, err = Parse(data[:HeaderSize])
+	require.Equal(t, ErrImageTooShort, err)
+
+	_, err = Parse(data[:len(data)-1])
+	require.Equal(t, ErrCodeLength, err)
+
+	_, err = Parse(testImage(MaxImageSize))
+	require.Equal(t, ErrImageTooLarge, err)
+
+	data[0] = 'T'
+	_, err = Parse(data)
+	require.Equal(t, ErrInvalidMagic, err)
+}
+
+func TestSigned(t *testing.T) {
+	for _, indices := range [][]byte{{0, 2, 3}, {1, 1, 3}, {1, 2, KeyCount + 1}} {
+		data := testImage(16)
+		copy(data[0x08:], indices)
+		img, err := Parse(data)
+		require.NoError(t, err)
+		require.False(t, img.Signed(), "%v", indices)
+	}
+
+	data := testImage(16)
+	copy(data[signatureOffset+signatureSize:], make([]byte, signatureSize))
+	img, err := Parse(data)
+	require.NoError(t, err)
+	require.False(t, img.Signed())
+}
+
+func TestParseVersion(t *testing.T) {
+	v, err := ParseVersion("1.7.0")
+	require.NoError(t, err)
+	require.Equal(t, Version{Major: 1, Minor: 7}, v)
+	require.Equal(t, "1.7.0", v.String())
+
+	v, err = ParseVersion("v1.10.2")
+	require.NoError(t, err)
+	require.Equal(t, Version{Major: 1, Minor: 10, Patch: 2}, v)
+
+	for _, s := range []string{"", "1.7", "1.7.0.1", "1.x.0", "1.-7.0"} {
+		_, err = ParseVersion(s)
+		require.Equal(t, ErrInvalidVersion, err, s)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	img, err := Parse(testImage(16))
+	require.NoError(t, err)
+	version := Version{Major: 1, Minor: 7}
+
+	warnings, err := img.Validate(bootloaderFeatures(1, 6, 1), Policy{ImageVersion: version})
+	require.NoError(t, err)
+	require.Empty(t, warnings)
+
+	_, err = img.Validate(&messages.Features{}, Policy{ImageVersion: version})
+	require.Equal(t, ErrNotBootloaderMode, err)
+
+	_, err = img.Validate(bootloaderFeatures(1, 8, 0), Policy{ImageVersion: version})
+	require.Equal(t, &DowngradeError{Installed: Version{1, 8, 0}, Image: Version{1, 7, 0}}, err)
+
+	warnings, err = img.Validate(bootloaderFeatures(1, 8, 0), Policy{ImageVersion: version, AllowDowngrade: true})
+	require.NoError(t, err)
+	require.Len(t, warnings, 1)
+
+	// the header holds no version
+	warnings, err = img.Validate(bootloaderFeatures(1, 8, 0), Policy{})
+	require.NoError(t, err)
+	require.Equal(t, []string{"firmware image version not given, downgrades can not be detected"}, warnings)
+
+	data := testImage(16)
+	data[0x08] = 0
+	unsigned, err := Parse(data)
+	require.NoError(t, err)
+
+	_, err = unsigned.Validate(bootloaderFeatures(1, 6, 1), Policy{ImageVersion: version})
+	require.Equal(t, ErrUnsigned, err)
+
+	warnings, err = unsigned.Validate(bootloaderFeatures(1, 6, 1), Policy{ImageVersion: version, AllowUnsigned: true})
+	require.NoError(t, err)
+	require.Len(t, warnings, 1)
+
+	// memory protected devices never get unsigned firmware
+	features := bootloaderFeatures(1, 6, 1)
+	features.FirmwareFeatures = proto.Uint32(1 << 4)
+	_, err = unsigned.Validate(features, Policy{AllowUnsigned: true})
+	require.Equal(t, ErrUnsigned, err)
+}
@@ -0,0 +1,78 @@
+package skywallet
+
+import (
+	"fmt"
+)
+
+// progressBatchChunks is the number of chunks written between two transfer progress events
+const progressBatchChunks = 64
+
+// FirmwareStage is a stage of a firmware upload
+type FirmwareStage int
+
+const (
+	// FirmwareStageReplug Device.Reflash waits for the user to plug the device in bootloader mode
+	FirmwareStageReplug FirmwareStage = iota
+	// FirmwareStageErase the device erases the installed firmware
+	FirmwareStageErase
+	// FirmwareStageTransfer the image is sent to the device
+	FirmwareStageTransfer
+	// FirmwareStageConfirm the user confirms on the device that the fingerprint matches the image hash
+	FirmwareStageConfirm
+	// FirmwareStageReboot the firmware is installed and the device reboots
+	FirmwareStageReboot
+	// FirmwareStageReconnect Device.Reflash waits for the device to restart in firmware mode
+	FirmwareStageReconnect
+)
+
+var firmwareStageNames = []string{"replug", "erase", "transfer", "confirm", "reboot", "reconnect"}
+
+func (s FirmwareStage) String() string {
+	if s < 0 || int(s) >= len(firmwareStageNames) {
+		return fmt.Sprintf("FirmwareStage(%d)", int(s))
+	}
+	return firmwareStageNames[s]
+}
+
+// MarshalText encodes the stage as its name
+func (s FirmwareStage) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// FirmwareProgress is a progress event of a firmware upload
+type FirmwareProgress struct {
+	Stage FirmwareStage `json:"stage"`
+	// Written and Total are the image bytes sent to the device, set in FirmwareStageTransfer
+	Written int `json:"written"`
+	Total   int `json:"total"`
+}
+
+// FirmwareProgressFunc receives the progress events of firmware uploads
+type FirmwareProgressFunc func(FirmwareProgress)
+
+// reportFirmwareProgress calls the firmware progress callback of the device if set
+func (d *Device) reportFirmwareProgress(p FirmwareProgress) {
+	if d.firmwareProgress != nil {
+		d.firmwareProgress(p)
+	}
+}
+
+// FirmwareUploadError is returned if a firmware upload fails,
+// the installed firmware is erased already if Stage is past FirmwareStageErase.
+// A failed upload can not be resumed, the bootloader receives the whole image in a single
+// FirmwareUpload message and has no request to continue a transfer. The device stays in
+// bootloader mode, so calling Reflash again restarts the upload from FirmwareStageErase
+// without replugging the device.
+type FirmwareUploadError struct {
+	Stage FirmwareStage
+	Err   error
+}
+
+func (e *FirmwareUploadError) Error() string {
+	return fmt.Sprintf("firmware upload failed at the %s stage: %v", e.Stage, e.Err)
+}
+
+// Unwrap returns the error that stopped the upload
+func (e *FirmwareUploadError) Unwrap() error {
+	return e.Err
+}
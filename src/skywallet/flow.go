@@ -0,0 +1,152 @@
+package skywallet
+
+import (
+	"context"
+	"errors"
+
+	"github.com/gogo/protobuf/proto"
+
+	messages "github.com/skycoin/hardware-wallet-protob/go"
+
+	"github.com/skycoin/hardware-wallet-go/src/skywallet/wire"
+)
+
+// ErrPrompterNotSet is returned if the device requests user input and no Prompter was set
+var ErrPrompterNotSet = errors.New("device requested user input but no prompter is set")
+
+// Prompter collects the user input requested by the device while running a workflow
+type Prompter interface {
+	// PinMatrix returns the PIN encoded as positions in the matrix shown by the device
+	PinMatrix(pinType messages.PinMatrixRequestType) (string, error)
+	// Passphrase returns the passphrase protecting the wallet
+	Passphrase() (string, error)
+	// Word returns the next mnemonic word during the recovery procedure
+	Word() (string, error)
+	// ButtonConfirm is called when the device waits for the user to press a button
+	ButtonConfirm(code messages.ButtonRequestType) error
+}
+
+// Responder sends the device the acknowledgements required while running a workflow,
+// it is implemented by both Device and Session
+type Responder interface {
+	ButtonAck() (wire.Message, error)
+	PinMatrixAck(p string) (wire.Message, error)
+	PassphraseAck(passphrase string) (wire.Message, error)
+	WordAck(word string) (wire.Message, error)
+	Cancel() (wire.Message, error)
+}
+
+// ContextResponder is a Responder whose acknowledgements are cancelled if ctx is done,
+// it is implemented by both Device and Session
+type ContextResponder interface {
+	ButtonAckContext(ctx context.Context) (wire.Message, error)
+	PinMatrixAckContext(ctx context.Context, p string) (wire.Message, error)
+	PassphraseAckContext(ctx context.Context, passphrase string) (wire.Message, error)
+	WordAckContext(ctx context.Context, word string) (wire.Message, error)
+	CancelContext(ctx context.Context) (wire.Message, error)
+}
+
+// RunFlow answers the device requests starting at msg using prompter until the device
+// sends a terminal message (e.g. Success or a response) which is returned.
+// If the prompter fails the device action is cancelled and the prompter error is returned.
+func RunFlow(r Responder, msg wire.Message, prompter Prompter) (wire.Message, error) {
+	return RunFlowContext(context.Background(), contextResponder{r}, msg, prompter)
+}
+
+// RunFlowContext is like RunFlow but the acknowledgements sent to the device are cancelled if ctx is done
+func RunFlowContext(ctx context.Context, r ContextResponder, msg wire.Message, prompter Prompter) (wire.Message, error) {
+	var err error
+	for {
+		switch msg.Kind {
+		case uint16(messages.MessageType_MessageType_ButtonRequest):
+			var req messages.ButtonRequest
+			if err = proto.Unmarshal(msg.Data, &req); err != nil {
+				return wire.Message{}, err
+			}
+			if err = prompter.ButtonConfirm(req.GetCode()); err != nil {
+				return wire.Message{}, cancelFlow(ctx, r, err)
+			}
+			msg, err = r.ButtonAckContext(ctx)
+		case uint16(messages.MessageType_MessageType_PinMatrixRequest):
+			var req messages.PinMatrixRequest
+			if err = proto.Unmarshal(msg.Data, &req); err != nil {
+				return wire.Message{}, err
+			}
+			var pin string
+			if pin, err = prompter.PinMatrix(req.GetType()); err != nil {
+				return wire.Message{}, cancelFlow(ctx, r, err)
+			}
+			msg, err = r.PinMatrixAckContext(ctx, pin)
+		case uint16(messages.MessageType_MessageType_PassphraseRequest):
+			var passphrase string
+			if passphrase, err = prompter.Passphrase(); err != nil {
+				return wire.Message{}, cancelFlow(ctx, r, err)
+			}
+			msg, err = r.PassphraseAckContext(ctx, passphrase)
+		case uint16(messages.MessageType_MessageType_WordRequest):
+			var word string
+			if word, err = prompter.Word(); err != nil {
+				return wire.Message{}, cancelFlow(ctx, r, err)
+			}
+			msg, err = r.WordAckContext(ctx, word)
+		default:
+			return msg, nil
+		}
+
+		if err != nil {
+			return wire.Message{}, err
+		}
+	}
+}
+
+func cancelFlow(ctx context.Context, r ContextResponder, err error) error {
+	if _, cancelErr := r.CancelContext(ctx); cancelErr != nil {
+		log.Errorf("failed to cancel device action: %v", cancelErr)
+	}
+	return err
+}
+
+// contextResponder runs a Responder ignoring the context
+type contextResponder struct {
+	Responder
+}
+
+func (r contextResponder) ButtonAckContext(ctx context.Context) (wire.Message, error) {
+	return r.ButtonAck()
+}
+
+func (r contextResponder) PinMatrixAckContext(ctx context.Context, p string) (wire.Message, error) {
+	return r.PinMatrixAck(p)
+}
+
+func (r contextResponder) PassphraseAckContext(ctx context.Context, passphrase string) (wire.Message, error) {
+	return r.PassphraseAck(passphrase)
+}
+
+func (r contextResponder) WordAckContext(ctx context.Context, word string) (wire.Message, error) {
+	return r.WordAck(word)
+}
+
+func (r contextResponder) CancelContext(ctx context.Context) (wire.Message, error) {
+	return r.Cancel()
+}
+
+// noPrompter is used by the typed api if no Prompter was set,
+// it only lets the device wait for the user to press a button
+type noPrompter struct{}
+
+func (noPrompter) PinMatrix(pinType messages.PinMatrixRequestType) (string, error) {
+	return "", ErrPrompterNotSet
+}
+
+func (noPrompter) Passphrase() (string, error) {
+	return "", ErrPrompterNotSet
+}
+
+func (noPrompter) Word() (string, error) {
+	return "", ErrPrompterNotSet
+}
+
+func (noPrompter) ButtonConfirm(code messages.ButtonRequestType) error {
+	return nil
+}
@@ -2,18 +2,21 @@ package skywallet
 
 import (
 	"bytes"
+	"context"
 	"encoding/binary"
 	"errors"
 	"fmt"
 	"io"
+	"reflect"
 	"runtime"
-	"sync"
+	"strings"
 	"time"
 
 	"github.com/gogo/protobuf/proto"
 
 	messages "github.com/skycoin/hardware-wallet-protob/go"
 
+	"github.com/skycoin/hardware-wallet-go/src/skywallet/capture"
 	"github.com/skycoin/hardware-wallet-go/src/skywallet/usb"
 	"github.com/skycoin/hardware-wallet-go/src/skywallet/wire"
 )
@@ -48,6 +51,11 @@ const (
 	// SkycoinHwProductID from https://github.com/skycoin/hardware-wallet/blob/50000f674c56c0cc18eec30d55978b73ed279b2e/tiny-firmware/bootloader/usb.c#L58
 	SkycoinHwProductID = 0x0001
 
+	// SkycoinBootloaderProductID is the bootloader product id assumed by the usb package (usb.ProductT1Bootloader),
+	// wallets enumerating with it are listed too. The bootloader usb.c cited above enumerates with SkycoinHwProductID,
+	// so a wallet in bootloader mode is normally told apart only by Features.BootloaderMode.
+	SkycoinBootloaderProductID = 0x0000
+
 	// EmulatorPort is the emulator udp port
 	EmulatorPort = 21324
 )
@@ -57,9 +65,13 @@ const (
 // DeviceDriver is the api for hardware wallet communication
 type DeviceDriver interface {
 	SendToDevice(dev usb.Device, chunks [][64]byte) (wire.Message, error)
+	SendToDeviceContext(ctx context.Context, dev usb.Device, chunks [][64]byte) (wire.Message, error)
 	SendToDeviceNoAnswer(dev usb.Device, chunks [][64]byte) error
 	GetDevice() (usb.Device, error)
 	GetDeviceInfos() ([]usb.Info, error)
+	Watch(ctx context.Context, options WatchOptions) <-chan DeviceEvent
+	SetEntropySource(source io.Reader)
+	EntropySource() io.Reader
 	DeviceType() DeviceType
 	Close()
 }
@@ -68,6 +80,85 @@ type DeviceDriver interface {
 type Driver struct {
 	deviceType DeviceType
 	bus        usb.Bus
+	selector   deviceSelector
+	// entropySource provides the host entropy of the EntropyAck messages, nil for the default source
+	entropySource io.Reader
+	// connections tracks the wallets in use, Watch does not probe them
+	connections *connections
+}
+
+// Option selects which wallet the driver connects to when several are attached
+type Option func(*Driver)
+
+// WithPath selects the wallet at the given usb.Info path
+func WithPath(path string) Option {
+	return func(drv *Driver) {
+		drv.selector.path = path
+	}
+}
+
+// WithDeviceID selects the wallet reporting the given DeviceId in its features
+func WithDeviceID(deviceID string) Option {
+	return func(drv *Driver) {
+		drv.selector.deviceID = deviceID
+	}
+}
+
+// WithLabel selects the wallet reporting the given Label in its features
+func WithLabel(label string) Option {
+	return func(drv *Driver) {
+		drv.selector.label = label
+	}
+}
+
+// WithDevice selects the wallet whose path, DeviceId or Label is device
+func WithDevice(device string) Option {
+	return func(drv *Driver) {
+		drv.selector.device = device
+	}
+}
+
+// WithCapture records the usb reports exchanged with the wallets to w, see the capture package
+func WithCapture(w io.Writer) Option {
+	return func(drv *Driver) {
+		drv.bus = capture.NewRecordingBus(drv.bus, w)
+	}
+}
+
+// WithEntropySource sets the source of the host entropy like Device.SetEntropySource
+func WithEntropySource(source io.Reader) Option {
+	return func(drv *Driver) {
+		drv.entropySource = source
+	}
+}
+
+// deviceSelector matches the wallets to connect to, empty fields match any wallet
+type deviceSelector struct {
+	path     string
+	deviceID string
+	label    string
+	device   string
+}
+
+func (s deviceSelector) matchInfo(info usb.Info) bool {
+	return s.path == "" || s.path == info.Path
+}
+
+func (s deviceSelector) needsFeatures() bool {
+	return s.deviceID != "" || s.label != "" || s.device != ""
+}
+
+func (s deviceSelector) matchFeatures(info usb.Info, features *messages.Features) bool {
+	if s.deviceID != "" && s.deviceID != features.GetDeviceId() {
+		return false
+	}
+	if s.label != "" && s.label != features.GetLabel() {
+		return false
+	}
+	if s.device != "" && s.device != info.Path && s.device != features.GetDeviceId() && s.device != features.GetLabel() {
+		return false
+	}
+	return true
 }
 
 func initUsb() []usb.Bus {
@@ -88,28 +179,35 @@ func initUsb() []usb.Bus {
 }
 
 // NewDriver create a new device driver
-func NewDriver(deviceType DeviceType) (*Driver, error) {
+func NewDriver(deviceType DeviceType, options ...Option) (*Driver, error) {
 	switch deviceType {
 	case DeviceTypeUSB:
-		return &Driver{
-			deviceType: deviceType,
-			bus:        usb.Init(initUsb()...),
-		}, nil
+		return NewDriverWithBus(deviceType, usb.Init(initUsb()...), options...), nil
 	case DeviceTypeEmulator:
 		udpBus, err := usb.InitUDP([]int{EmulatorPort})
 		if err != nil {
 			return nil, err
 		}
 
-		return &Driver{
-			deviceType: deviceType,
-			bus:        usb.Init(udpBus),
-		}, nil
+		return NewDriverWithBus(deviceType, usb.Init(udpBus), options...), nil
 	}
 
 	return nil, fmt.Errorf("invalid device %s", deviceType)
 }
 
+// NewDriverWithBus create a new device driver communicating through the given bus
+func NewDriverWithBus(deviceType DeviceType, bus usb.Bus, options ...Option) *Driver {
+	drv := &Driver{
+		deviceType:  deviceType,
+		bus:         bus,
+		connections: newConnections(),
+	}
+	for _, option := range options {
+		option(drv)
+	}
+	return drv
+}
+
 // Close closes the bus
 func (drv *Driver) Close() {
 	drv.bus.Close()
@@ -120,6 +218,16 @@ func (drv *Driver) DeviceType() DeviceType {
 	return drv.deviceType
 }
 
+// SetEntropySource sets the source of the host entropy answering the EntropyRequest of the wallet, nil for the default one
+func (drv *Driver) SetEntropySource(source io.Reader) {
+	drv.entropySource = source
+}
+
+// EntropySource returns the source of the host entropy, nil for the default one
+func (drv *Driver) EntropySource() io.Reader {
+	return drv.entropySource
+}
+
 // SendToDeviceNoAnswer sends msg to device and doesnt return response
 func (drv *Driver) SendToDeviceNoAnswer(dev usb.Device, chunks [][64]byte) error {
 	return sendToDeviceNoAnswer(dev, chunks)
@@ -127,21 +235,23 @@ func (drv *Driver) SendToDeviceNoAnswer(dev usb.Device, chunks [][64]byte) error
 
 // SendToDevice sends msg to device and returns response
 func (drv *Driver) SendToDevice(dev usb.Device, chunks [][64]byte) (wire.Message, error) {
-	return sendToDevice(dev, chunks)
+	return sendToDevice(dev, chunks, drv.entropySource)
 }
 
-// GetDevice returns a device instance
-func (drv *Driver) GetDevice() (usb.Device, error) {
-	var vendorID, productID uint16
-
-	if drv.deviceType == DeviceTypeUSB {
-		vendorID = SkycoinVendorID
-		productID = SkycoinHwProductID
-	} else if drv.deviceType != DeviceTypeEmulator {
-		return nil, fmt.Errorf("invalid device type: %s", drv.deviceType)
-	}
+// SendToDeviceContext sends msg to device and returns response.
+// If ctx is done before the device answers, the device action is cancelled,
+// dev is closed and ctx.Err() is returned.
+func (drv *Driver) SendToDeviceContext(ctx context.Context, dev usb.Device, chunks [][64]byte) (wire.Message, error) {
+	return withContext(ctx, dev, func(dev usb.Device) (wire.Message, error) {
+		return sendToDevice(dev, chunks, drv.entropySource)
+	})
+}
 
-	infos, err := drv.bus.Enumerate(vendorID, productID)
+// GetDevice returns a device instance, if several wallets are attached
+// the first one matching the driver selection is returned. The wallets failing
+// to connect are skipped, the last connection error is returned if none matches.
+func (drv *Driver) GetDevice() (usb.Device, error) {
+	infos, err := drv.enumerate()
 	if len(infos) <= 0 {
 		return nil, ErrNoDeviceConnected
 	}
@@ -150,29 +260,112 @@ func (drv *Driver) GetDevice() (usb.Device, error) {
 		return nil, err
 	}
 
-	tries := 0
-	for tries < 3 {
-		dev, err := drv.bus.Connect(infos[0].Path)
+	var connectErr error
+	for _, info := range infos {
+		if !drv.selector.matchInfo(info) {
+			continue
+		}
+
+		dev, err := drv.connect(info.Path)
+		if err != nil {
+			log.Errorf("failed to connect to device %s: %v", info.Path, err)
+			connectErr = err
+			continue
+		}
+
+		if !drv.selector.needsFeatures() {
+			return dev, nil
+		}
+
+		features, err := getFeatures(dev, drv.entropySource)
+		if err == nil && drv.selector.matchFeatures(info, features) {
+			return dev, nil
+		}
 		if err != nil {
-			log.Print(err.Error())
-			tries++
-			time.Sleep(100 * time.Millisecond)
-		} else {
-			return dev, err
+			log.Errorf("failed to get features of device %s: %v", info.Path, err)
+		}
+
+		if err := dev.Close(false); err != nil {
+			log.Errorf("failed to close device %s: %v", info.Path, err)
+		}
+	}
+
+	if connectErr != nil {
+		return nil, connectErr
+	}
+	return nil, ErrDeviceNotFound
+}
+
+// enumerate returns the wallets attached through the driver bus, in firmware or bootloader mode
+func (drv *Driver) enumerate() ([]usb.Info, error) {
+	switch drv.deviceType {
+	case DeviceTypeEmulator:
+		return drv.bus.Enumerate(0, 0)
+	case DeviceTypeUSB:
+	default:
+		return nil, fmt.Errorf("invalid device type: %s", drv.deviceType)
+	}
+
+	// a product id of 0 matches every product of the vendor, the other products are filtered out
+	infos, err := drv.bus.Enumerate(SkycoinVendorID, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	var wallets []usb.Info
+	for _, info := range infos {
+		if info.ProductID == SkycoinHwProductID || info.ProductID == SkycoinBootloaderProductID {
+			wallets = append(wallets, info)
 		}
 	}
+	return wallets, nil
+}
+
+// connect opens a connection to the wallet at path, it is registered as in use until closed
+func (drv *Driver) connect(path string) (usb.Device, error) {
+	drv.connections.add(path)
+
+	var err error
+	for tries := 0; tries < 3; tries++ {
+		var dev usb.Device
+		dev, err = drv.bus.Connect(path)
+		if err == nil {
+			return &connection{
+				Device:      dev,
+				connections: drv.connections,
+				path:        path,
+			}, nil
+		}
+		log.Print(err.Error())
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	drv.connections.remove(path)
 	return nil, err
 }
 
+func getFeatures(dev usb.Device, entropySource io.Reader) (*messages.Features, error) {
+	chunks, err := MessageGetFeatures()
+	if err != nil {
+		return nil, err
+	}
+
+	msg, err := sendToDevice(dev, chunks, entropySource)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := expectMessage(msg, messages.MessageType_MessageType_Features); err != nil {
+		return nil, err
+	}
+
+	return DecodeFeatures(msg)
+}
+
 // GetDeviceInfos returns information from the attached usb
 func (drv *Driver) GetDeviceInfos() ([]usb.Info, error) {
 	if drv.DeviceType() == DeviceTypeUSB {
-		infos, err := drv.bus.Enumerate(SkycoinVendorID, SkycoinHwProductID)
-		if err != nil {
-			return nil, err
-		}
-
-		return infos, nil
+		return drv.enumerate()
 	}
 	return nil, errors.New("reading device info make sense for physical devices only")
 }
@@ -187,64 +380,149 @@ func sendToDeviceNoAnswer(dev usb.Device, chunks [][64]byte) error {
 	return nil
 }
 
-func sendToDevice(dev usb.Device, chunks [][64]byte) (wire.Message, error) {
-	var msg *wire.Message
-	var err error
-	for _, element := range chunks {
-		_, err := dev.Write(element[:])
-		if err != nil {
-			return wire.Message{}, err
+// cancelAnswerTimeout is how long the answer of the device to a Cancel is waited for
+const cancelAnswerTimeout = 5 * time.Second
+
+// withContext runs fn, which talks to dev, until ctx is done. In that case the device
+// is sent a Cancel message, its answer is discarded and dev is closed, then ctx.Err()
+// is returned. fn runs on the calling goroutine, which makes all the writes to dev.
+func withContext(ctx context.Context, dev usb.Device, fn func(dev usb.Device) (wire.Message, error)) (wire.Message, error) {
+	if err := ctx.Err(); err != nil {
+		if err := dev.Close(false); err != nil {
+			log.Errorf("failed to close device: %v", err)
 		}
+		return wire.Message{}, err
 	}
 
-	msg, err = wire.ReadFrom(dev)
-	if err != nil {
-		return wire.Message{}, err
+	cdev := &contextDevice{
+		Device: dev,
+		ctx:    ctx,
+		reads:  make(chan readResult, 1),
+	}
+	msg, err := fn(cdev)
+	if err == nil || err != ctx.Err() {
+		return msg, err
 	}
 
-	for msg.Kind == uint16(messages.MessageType_MessageType_EntropyRequest) {
-		var wg sync.WaitGroup
-		wg.Add(1)
+	cdev.cancel()
+	if err := dev.Close(false); err != nil {
+		log.Errorf("failed to close device: %v", err)
+	}
+	// the pending read, if any, returns once dev is closed
+	cdev.wait()
 
-		go func() {
-			defer wg.Done()
-			entropyChunks, err := MessageEntropyAck(entropyBufferSize)
-			if err != nil {
-				log.Errorf("failed to create entropy ack msg: %v", err)
-				return
-			}
+	return wire.Message{}, err
+}
 
-			for _, element := range entropyChunks {
-				_, err := dev.Write(element[:])
-				if err != nil {
-					log.Errorf("entropy ack error: %v", err)
-					return
-				}
-			}
-		}()
+type readResult struct {
+	data []byte
+	err  error
+}
+
+// contextDevice is a device connection whose reads return ctx.Err() once ctx is done.
+// A read is made by a goroutine of its own so that it can be abandoned, it is picked up
+// by the next Read. The writes go straight to the device.
+type contextDevice struct {
+	usb.Device
+	ctx   context.Context
+	reads chan readResult
+	// pending is set while a read goroutine is running
+	pending bool
+}
+
+func (d *contextDevice) Read(p []byte) (int, error) {
+	if !d.pending {
+		d.pending = true
+		go func(size int) {
+			buf := make([]byte, size)
+			n, err := d.Device.Read(buf)
+			d.reads <- readResult{buf[:n], err}
+		}(len(p))
+	}
+
+	select {
+	case res := <-d.reads:
+		d.pending = false
+		return copy(p, res.data), res.err
+	case <-d.ctx.Done():
+		return 0, d.ctx.Err()
+	}
+}
+
+// cancel sends a Cancel message and discards the messages read until the Failure answering it,
+// the answer of the cancelled request comes first if the device sent it before reading the Cancel
+func (d *contextDevice) cancel() {
+	cancelChunks, err := MessageCancel()
+	if err == nil {
+		err = sendToDeviceNoAnswer(d.Device, cancelChunks)
+	}
+	if err != nil {
+		log.Errorf("failed to cancel device action: %v", err)
+		return
+	}
 
-		msg, err = wire.ReadFrom(dev)
+	ctx, cancel := context.WithTimeout(context.Background(), cancelAnswerTimeout)
+	defer cancel()
+	d.ctx = ctx
+	for {
+		msg, err := wire.ReadFrom(d)
 		if err != nil {
-			return wire.Message{}, err
+			log.Errorf("failed to read the answer to the device action cancel: %v", err)
+			return
+		}
+		if msg.Kind == uint16(messages.MessageType_MessageType_Failure) {
+			return
 		}
-		wg.Wait()
 	}
-	for msg.Kind == uint16(messages.MessageType_MessageType_Success) {
-		success, err := decodeSuccessMsgStruct(*msg)
+}
+
+// wait waits for the pending read to return
+func (d *contextDevice) wait() {
+	if d.pending {
+		<-d.reads
+		d.pending = false
+	}
+}
+
+// sendToDevice sends chunks to dev and returns the answer, see readAnswer
+func sendToDevice(dev usb.Device, chunks [][64]byte, entropySource io.Reader) (wire.Message, error) {
+	if err := sendToDeviceNoAnswer(dev, chunks); err != nil {
+		return wire.Message{}, err
+	}
+	return readAnswer(dev, entropySource)
+}
+
+// readAnswer reads the answer of dev to the message written last. The device may ask for
+// host entropy at any point of a request, its EntropyRequest are answered with entropy read
+// from entropySource and the Success acknowledging the EntropyAck are skipped.
+func readAnswer(dev usb.Device, entropySource io.Reader) (wire.Message, error) {
+	for {
+		msg, err := wire.ReadFrom(dev)
 		if err != nil {
 			return wire.Message{}, err
 		}
-		if success.MsgType != nil && *success.MsgType == messages.MessageType(messages.MessageType_MessageType_EntropyAck) {
-			msg, err = wire.ReadFrom(dev)
+
+		switch msg.Kind {
+		case uint16(messages.MessageType_MessageType_EntropyRequest):
+			entropyChunks, err := hostEntropyAck(entropySource)
+			if err != nil {
+				return wire.Message{}, err
+			}
+			if err := sendToDeviceNoAnswer(dev, entropyChunks); err != nil {
+				return wire.Message{}, fmt.Errorf("entropy ack error: %v", err)
+			}
+		case uint16(messages.MessageType_MessageType_Success):
+			success, err := decodeSuccessMsgStruct(*msg)
 			if err != nil {
 				return wire.Message{}, err
 			}
-		} else {
-			break
+			if success.GetMsgType() != messages.MessageType_MessageType_EntropyAck {
+				return *msg, nil
+			}
+		default:
+			return *msg, nil
 		}
 	}
-
-	return *msg, err
 }
 
 func binaryWrite(message io.Writer, data interface{}) {
@@ -264,29 +542,40 @@ func makeSkyWalletMessage(data []byte, msgID messages.MessageType) [][64]byte {
 		binaryWrite(message, data[1:])
 	}
 
-	messageLen := message.Len()
+	messageBytes := message.Bytes()
 	var chunks [][64]byte
-	i := 0
-	for messageLen > 0 {
+	for len(messageBytes) > 0 {
 		var chunk [64]byte
 		chunk[0] = '?'
-		copy(chunk[1:], message.Bytes()[63*i:63*(i+1)])
+		n := copy(chunk[1:], messageBytes)
 		chunks = append(chunks, chunk)
-		messageLen -= 63
-		i = i + 1
+		messageBytes = messageBytes[n:]
 	}
 	return chunks
 }
 
+// messageType returns the type of the message split in chunks by makeSkyWalletMessage
+func messageType(chunks [][64]byte) messages.MessageType {
+	if len(chunks) == 0 {
+		return 0
+	}
+	return messages.MessageType(binary.BigEndian.Uint16(chunks[0][3:5]))
+}
+
 // Initialize send an init request to the device
 func Initialize(dev usb.Device) error {
+	return initialize(dev, nil)
+}
+
+// initialize is like Initialize but the host entropy requested by the device is read from entropySource
+func initialize(dev usb.Device, entropySource io.Reader) error {
 	var chunks [][64]byte
 
 	chunks, err := MessageInitialize()
 	if err != nil {
 		return err
 	}
-	_, err = sendToDevice(dev, chunks)
+	_, err = sendToDevice(dev, chunks, entropySource)
 	return err
 }
 
@@ -314,6 +603,35 @@ func decodeSuccessMsgStruct(msg wire.Message) (messages.Success, error) {
 	return messages.Success{}, fmt.Errorf("calling DecodeSuccessMsg with wrong message type: %s", messages.MessageType(msg.Kind))
 }
 
+// DecodeMessage decodes msg into the protobuf message of its kind
+func DecodeMessage(msg wire.Message) (proto.Message, error) {
+	kind := messages.MessageType(msg.Kind)
+	name := strings.TrimPrefix(kind.String(), "MessageType_")
+	t := proto.MessageType(name)
+	if t == nil {
+		return nil, fmt.Errorf("unknown message type: %s", kind)
+	}
+
+	m := reflect.New(t.Elem()).Interface().(proto.Message)
+	err := proto.Unmarshal(msg.Data, m)
+	if err != nil && len(msg.Data) > 0 && msg.Data[0] == '\n' {
+		// makeSkyWalletMessage replaces the key of the first field with '\n', restore it
+		for _, p := range proto.GetProperties(t.Elem()).Prop {
+			if p.Tag == 1 {
+				data := append([]byte{}, msg.Data...)
+				data[0] = byte(p.Tag<<3 | p.WireType)
+				m.Reset()
+				err = proto.Unmarshal(data, m)
+				break
+			}
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 // DecodeSuccessMsg convert byte data into string containing the success message returned by the device
 func DecodeSuccessMsg(msg wire.Message) (string, error) {
 	success, err := decodeSuccessMsgStruct(msg)
@@ -377,6 +695,19 @@ func DecodeResponseSkycoinSignMessage(msg wire.Message) (string, error) {
 	return "", fmt.Errorf("calling DecodeResponseeSkycoinSignMessage with wrong message type: %s", messages.MessageType(msg.Kind))
 }
 
+// DecodeFeatures convert byte data into device features
+func DecodeFeatures(msg wire.Message) (*messages.Features, error) {
+	if msg.Kind == uint16(messages.MessageType_MessageType_Features) {
+		features := &messages.Features{}
+		err := proto.Unmarshal(msg.Data, features)
+		if err != nil {
+			return nil, err
+		}
+		return features, nil
+	}
+	return nil, fmt.Errorf("calling DecodeFeatures with wrong message type: %s", messages.MessageType(msg.Kind))
+}
+
 // DecodeResponseEntropyMessage convert byte data into entropy message, meant to be used after GetEntropy
 func DecodeResponseEntropyMessage(msg wire.Message) (*messages.Entropy, error) {
 	if msg.Kind == uint16(messages.MessageType_MessageType_Entropy) {
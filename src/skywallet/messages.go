@@ -2,7 +2,9 @@ package skywallet
 
 import (
 	"bytes"
+	"crypto/sha256"
 	"fmt"
+	"io"
 
 	"github.com/gogo/protobuf/proto"
 	"github.com/skycoin/skycoin/src/cipher"
@@ -285,8 +287,13 @@ func MessageSignMessage(addressIndex int, message string) ([][64]byte, error) {
 	return chunks, nil
 }
 
-// MessageTransactionSign prepare MessageTransactionSign request
+// MessageTransactionSign prepare MessageTransactionSign request, transactions with more than
+// MaxTransactionInputs inputs or MaxTransactionOutputs outputs are refused with ErrTransactionTooLarge
 func MessageTransactionSign(inputs []*messages.SkycoinTransactionInput, outputs []*messages.SkycoinTransactionOutput) ([][64]byte, error) {
+	if len(inputs) > MaxTransactionInputs || len(outputs) > MaxTransactionOutputs {
+		return [][64]byte{}, ErrTransactionTooLarge
+	}
+
 	skycoinTransactionSignMessage := &messages.TransactionSign{
 		NbIn:           proto.Uint32(uint32(len(inputs))),
 		NbOut:          proto.Uint32(uint32(len(outputs))),
@@ -336,6 +343,30 @@ func MessageEntropyAck(bufferSize int) ([][64]byte, error) {
 	if len(buffer) != bufferSize {
 		return nil, fmt.Errorf("required %d bytes but got %d", bufferSize, len(buffer))
 	}
+	return messageEntropyAck(buffer)
+}
+
+// hostEntropyAck prepares the EntropyAck answering an EntropyRequest with entropyBufferSize bytes
+// read from source, or from the source of MessageEntropyAck if nil
+func hostEntropyAck(source io.Reader) ([][64]byte, error) {
+	var buffer []byte
+	if source == nil {
+		buffer = cipher.RandByte(entropyBufferSize)
+	} else {
+		buffer = make([]byte, entropyBufferSize)
+		if _, err := io.ReadFull(source, buffer); err != nil {
+			return nil, fmt.Errorf("failed to read host entropy: %v", err)
+		}
+	}
+
+	return messageEntropyAck(buffer)
+}
+
+// messageEntropyAck prepares the EntropyAck holding buffer. The SHA256 of the entropy is
+// logged for audits, the entropy itself is never logged.
+func messageEntropyAck(buffer []byte) ([][64]byte, error) {
+	log.Infof("Sending %d bytes of host entropy with sha256 %x", len(buffer), sha256.Sum256(buffer))
+
 	entropyAck := &messages.EntropyAck{
 		Entropy: buffer,
 	}
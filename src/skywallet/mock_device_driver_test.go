@@ -2,6 +2,8 @@
 
 package skywallet
 
+import context "context"
+import io "io"
 import mock "github.com/stretchr/testify/mock"
 import usb "github.com/skycoin/hardware-wallet-go/src/skywallet/usb"
 import wire "github.com/skycoin/hardware-wallet-go/src/skywallet/wire"
@@ -30,6 +32,22 @@ func (_m *MockDeviceDriver) DeviceType() DeviceType {
 	return r0
 }
 
+// EntropySource provides a mock function with given fields:
+func (_m *MockDeviceDriver) EntropySource() io.Reader {
+	ret := _m.Called()
+
+	var r0 io.Reader
+	if rf, ok := ret.Get(0).(func() io.Reader); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(io.Reader)
+		}
+	}
+
+	return r0
+}
+
 // GetDevice provides a mock function with given fields:
 func (_m *MockDeviceDriver) GetDevice() (usb.Device, error) {
 	ret := _m.Called()
@@ -97,6 +115,27 @@ func (_m *MockDeviceDriver) SendToDevice(dev usb.Device, chunks [][64]byte) (wir
 	return r0, r1
 }
 
+// SendToDeviceContext provides a mock function with given fields: ctx, dev, chunks
+func (_m *MockDeviceDriver) SendToDeviceContext(ctx context.Context, dev usb.Device, chunks [][64]byte) (wire.Message, error) {
+	ret := _m.Called(ctx, dev, chunks)
+
+	var r0 wire.Message
+	if rf, ok := ret.Get(0).(func(context.Context, usb.Device, [][64]byte) wire.Message); ok {
+		r0 = rf(ctx, dev, chunks)
+	} else {
+		r0 = ret.Get(0).(wire.Message)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, usb.Device, [][64]byte) error); ok {
+		r1 = rf(ctx, dev, chunks)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // SendToDeviceNoAnswer provides a mock function with given fields: dev, chunks
 func (_m *MockDeviceDriver) SendToDeviceNoAnswer(dev usb.Device, chunks [][64]byte) error {
 	ret := _m.Called(dev, chunks)
@@ -110,3 +149,24 @@ func (_m *MockDeviceDriver) SendToDeviceNoAnswer(dev usb.Device, chunks [][64]by
 
 	return r0
 }
+
+// SetEntropySource provides a mock function with given fields: source
+func (_m *MockDeviceDriver) SetEntropySource(source io.Reader) {
+	_m.Called(source)
+}
+
+// Watch provides a mock function with given fields: ctx, options
+func (_m *MockDeviceDriver) Watch(ctx context.Context, options WatchOptions) <-chan DeviceEvent {
+	ret := _m.Called(ctx, options)
+
+	var r0 <-chan DeviceEvent
+	if rf, ok := ret.Get(0).(func(context.Context, WatchOptions) <-chan DeviceEvent); ok {
+		r0 = rf(ctx, options)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(<-chan DeviceEvent)
+		}
+	}
+
+	return r0
+}
@@ -2,8 +2,14 @@
 
 package skywallet
 
+import context "context"
+import cipher "github.com/skycoin/skycoin/src/cipher"
+import entropy "github.com/skycoin/hardware-wallet-go/src/skywallet/entropy"
+import firmware "github.com/skycoin/hardware-wallet-go/src/skywallet/firmware"
+import io "io"
 import messages "github.com/skycoin/hardware-wallet-protob/go"
 import mock "github.com/stretchr/testify/mock"
+import usb "github.com/skycoin/hardware-wallet-go/src/skywallet/usb"
 import wire "github.com/skycoin/hardware-wallet-go/src/skywallet/wire"
 
 // MockDevicer is an autogenerated mock type for the Devicer type
@@ -32,6 +38,27 @@ func (_m *MockDevicer) AddressGen(addressN uint32, startIndex uint32, confirmAdd
 	return r0, r1
 }
 
+// AddressGenContext provides a mock function with given fields: ctx, addressN, startIndex, confirmAddress
+func (_m *MockDevicer) AddressGenContext(ctx context.Context, addressN uint32, startIndex uint32, confirmAddress bool) (wire.Message, error) {
+	ret := _m.Called(ctx, addressN, startIndex, confirmAddress)
+
+	var r0 wire.Message
+	if rf, ok := ret.Get(0).(func(context.Context, uint32, uint32, bool) wire.Message); ok {
+		r0 = rf(ctx, addressN, startIndex, confirmAddress)
+	} else {
+		r0 = ret.Get(0).(wire.Message)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, uint32, uint32, bool) error); ok {
+		r1 = rf(ctx, addressN, startIndex, confirmAddress)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // ApplySettings provides a mock function with given fields: usePassphrase, label, language
 func (_m *MockDevicer) ApplySettings(usePassphrase *bool, label string, language string) (wire.Message, error) {
 	ret := _m.Called(usePassphrase, label, language)
@@ -53,6 +80,27 @@ func (_m *MockDevicer) ApplySettings(usePassphrase *bool, label string, language
 	return r0, r1
 }
 
+// ApplySettingsContext provides a mock function with given fields: ctx, usePassphrase, label, language
+func (_m *MockDevicer) ApplySettingsContext(ctx context.Context, usePassphrase *bool, label string, language string) (wire.Message, error) {
+	ret := _m.Called(ctx, usePassphrase, label, language)
+
+	var r0 wire.Message
+	if rf, ok := ret.Get(0).(func(context.Context, *bool, string, string) wire.Message); ok {
+		r0 = rf(ctx, usePassphrase, label, language)
+	} else {
+		r0 = ret.Get(0).(wire.Message)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, *bool, string, string) error); ok {
+		r1 = rf(ctx, usePassphrase, label, language)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // Available provides a mock function with given fields:
 func (_m *MockDevicer) Available() bool {
 	ret := _m.Called()
@@ -88,6 +136,27 @@ func (_m *MockDevicer) Backup() (wire.Message, error) {
 	return r0, r1
 }
 
+// BackupContext provides a mock function with given fields: ctx
+func (_m *MockDevicer) BackupContext(ctx context.Context) (wire.Message, error) {
+	ret := _m.Called(ctx)
+
+	var r0 wire.Message
+	if rf, ok := ret.Get(0).(func(context.Context) wire.Message); ok {
+		r0 = rf(ctx)
+	} else {
+		r0 = ret.Get(0).(wire.Message)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // ButtonAck provides a mock function with given fields:
 func (_m *MockDevicer) ButtonAck() (wire.Message, error) {
 	ret := _m.Called()
@@ -109,6 +178,27 @@ func (_m *MockDevicer) ButtonAck() (wire.Message, error) {
 	return r0, r1
 }
 
+// ButtonAckContext provides a mock function with given fields: ctx
+func (_m *MockDevicer) ButtonAckContext(ctx context.Context) (wire.Message, error) {
+	ret := _m.Called(ctx)
+
+	var r0 wire.Message
+	if rf, ok := ret.Get(0).(func(context.Context) wire.Message); ok {
+		r0 = rf(ctx)
+	} else {
+		r0 = ret.Get(0).(wire.Message)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // Cancel provides a mock function with given fields:
 func (_m *MockDevicer) Cancel() (wire.Message, error) {
 	ret := _m.Called()
@@ -130,6 +220,27 @@ func (_m *MockDevicer) Cancel() (wire.Message, error) {
 	return r0, r1
 }
 
+// CancelContext provides a mock function with given fields: ctx
+func (_m *MockDevicer) CancelContext(ctx context.Context) (wire.Message, error) {
+	ret := _m.Called(ctx)
+
+	var r0 wire.Message
+	if rf, ok := ret.Get(0).(func(context.Context) wire.Message); ok {
+		r0 = rf(ctx)
+	} else {
+		r0 = ret.Get(0).(wire.Message)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // ChangePin provides a mock function with given fields: removePin
 func (_m *MockDevicer) ChangePin(removePin *bool) (wire.Message, error) {
 	ret := _m.Called(removePin)
@@ -151,6 +262,27 @@ func (_m *MockDevicer) ChangePin(removePin *bool) (wire.Message, error) {
 	return r0, r1
 }
 
+// ChangePinContext provides a mock function with given fields: ctx, removePin
+func (_m *MockDevicer) ChangePinContext(ctx context.Context, removePin *bool) (wire.Message, error) {
+	ret := _m.Called(ctx, removePin)
+
+	var r0 wire.Message
+	if rf, ok := ret.Get(0).(func(context.Context, *bool) wire.Message); ok {
+		r0 = rf(ctx, removePin)
+	} else {
+		r0 = ret.Get(0).(wire.Message)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, *bool) error); ok {
+		r1 = rf(ctx, removePin)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // CheckMessageSignature provides a mock function with given fields: message, signature, address
 func (_m *MockDevicer) CheckMessageSignature(message string, signature string, address string) (wire.Message, error) {
 	ret := _m.Called(message, signature, address)
@@ -172,6 +304,27 @@ func (_m *MockDevicer) CheckMessageSignature(message string, signature string, a
 	return r0, r1
 }
 
+// CheckMessageSignatureContext provides a mock function with given fields: ctx, message, signature, address
+func (_m *MockDevicer) CheckMessageSignatureContext(ctx context.Context, message string, signature string, address string) (wire.Message, error) {
+	ret := _m.Called(ctx, message, signature, address)
+
+	var r0 wire.Message
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, string) wire.Message); ok {
+		r0 = rf(ctx, message, signature, address)
+	} else {
+		r0 = ret.Get(0).(wire.Message)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, string) error); ok {
+		r1 = rf(ctx, message, signature, address)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // Close provides a mock function with given fields:
 func (_m *MockDevicer) Close() {
 	_m.Called()
@@ -191,6 +344,52 @@ func (_m *MockDevicer) Connected() bool {
 	return r0
 }
 
+// Features provides a mock function with given fields:
+func (_m *MockDevicer) Features() (*messages.Features, error) {
+	ret := _m.Called()
+
+	var r0 *messages.Features
+	if rf, ok := ret.Get(0).(func() *messages.Features); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*messages.Features)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func() error); ok {
+		r1 = rf()
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// FeaturesContext provides a mock function with given fields: ctx
+func (_m *MockDevicer) FeaturesContext(ctx context.Context) (*messages.Features, error) {
+	ret := _m.Called(ctx)
+
+	var r0 *messages.Features
+	if rf, ok := ret.Get(0).(func(context.Context) *messages.Features); ok {
+		r0 = rf(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*messages.Features)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // FirmwareUpload provides a mock function with given fields: payload, hash
 func (_m *MockDevicer) FirmwareUpload(payload []byte, hash [32]byte) error {
 	ret := _m.Called(payload, hash)
@@ -205,6 +404,20 @@ func (_m *MockDevicer) FirmwareUpload(payload []byte, hash [32]byte) error {
 	return r0
 }
 
+// FirmwareUploadContext provides a mock function with given fields: ctx, payload, hash
+func (_m *MockDevicer) FirmwareUploadContext(ctx context.Context, payload []byte, hash [32]byte) error {
+	ret := _m.Called(ctx, payload, hash)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, []byte, [32]byte) error); ok {
+		r0 = rf(ctx, payload, hash)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // GenerateMnemonic provides a mock function with given fields: wordCount, usePassphrase
 func (_m *MockDevicer) GenerateMnemonic(wordCount uint32, usePassphrase bool) (wire.Message, error) {
 	ret := _m.Called(wordCount, usePassphrase)
@@ -226,6 +439,73 @@ func (_m *MockDevicer) GenerateMnemonic(wordCount uint32, usePassphrase bool) (w
 	return r0, r1
 }
 
+// GenerateMnemonicContext provides a mock function with given fields: ctx, wordCount, usePassphrase
+func (_m *MockDevicer) GenerateMnemonicContext(ctx context.Context, wordCount uint32, usePassphrase bool) (wire.Message, error) {
+	ret := _m.Called(ctx, wordCount, usePassphrase)
+
+	var r0 wire.Message
+	if rf, ok := ret.Get(0).(func(context.Context, uint32, bool) wire.Message); ok {
+		r0 = rf(ctx, wordCount, usePassphrase)
+	} else {
+		r0 = ret.Get(0).(wire.Message)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, uint32, bool) error); ok {
+		r1 = rf(ctx, wordCount, usePassphrase)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetAddresses provides a mock function with given fields: addressN, startIndex, confirmAddress
+func (_m *MockDevicer) GetAddresses(addressN uint32, startIndex uint32, confirmAddress bool) ([]cipher.Address, error) {
+	ret := _m.Called(addressN, startIndex, confirmAddress)
+
+	var r0 []cipher.Address
+	if rf, ok := ret.Get(0).(func(uint32, uint32, bool) []cipher.Address); ok {
+		r0 = rf(addressN, startIndex, confirmAddress)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]cipher.Address)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(uint32, uint32, bool) error); ok {
+		r1 = rf(addressN, startIndex, confirmAddress)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetAddressesContext provides a mock function with given fields: ctx, addressN, startIndex, confirmAddress
+func (_m *MockDevicer) GetAddressesContext(ctx context.Context, addressN uint32, startIndex uint32, confirmAddress bool) ([]cipher.Address, error) {
+	ret := _m.Called(ctx, addressN, startIndex, confirmAddress)
+
+	var r0 []cipher.Address
+	if rf, ok := ret.Get(0).(func(context.Context, uint32, uint32, bool) []cipher.Address); ok {
+		r0 = rf(ctx, addressN, startIndex, confirmAddress)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]cipher.Address)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, uint32, uint32, bool) error); ok {
+		r1 = rf(ctx, addressN, startIndex, confirmAddress)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetFeatures provides a mock function with given fields:
 func (_m *MockDevicer) GetFeatures() (wire.Message, error) {
 	ret := _m.Called()
@@ -247,6 +527,157 @@ func (_m *MockDevicer) GetFeatures() (wire.Message, error) {
 	return r0, r1
 }
 
+// GetFeaturesContext provides a mock function with given fields: ctx
+func (_m *MockDevicer) GetFeaturesContext(ctx context.Context) (wire.Message, error) {
+	ret := _m.Called(ctx)
+
+	var r0 wire.Message
+	if rf, ok := ret.Get(0).(func(context.Context) wire.Message); ok {
+		r0 = rf(ctx)
+	} else {
+		r0 = ret.Get(0).(wire.Message)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetMessageSignature provides a mock function with given fields: addressIndex, message
+func (_m *MockDevicer) GetMessageSignature(addressIndex int, message string) (cipher.Sig, error) {
+	ret := _m.Called(addressIndex, message)
+
+	var r0 cipher.Sig
+	if rf, ok := ret.Get(0).(func(int, string) cipher.Sig); ok {
+		r0 = rf(addressIndex, message)
+	} else {
+		r0 = ret.Get(0).(cipher.Sig)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(int, string) error); ok {
+		r1 = rf(addressIndex, message)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetMessageSignatureContext provides a mock function with given fields: ctx, addressIndex, message
+func (_m *MockDevicer) GetMessageSignatureContext(ctx context.Context, addressIndex int, message string) (cipher.Sig, error) {
+	ret := _m.Called(ctx, addressIndex, message)
+
+	var r0 cipher.Sig
+	if rf, ok := ret.Get(0).(func(context.Context, int, string) cipher.Sig); ok {
+		r0 = rf(ctx, addressIndex, message)
+	} else {
+		r0 = ret.Get(0).(cipher.Sig)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, int, string) error); ok {
+		r1 = rf(ctx, addressIndex, message)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetUsbInfo provides a mock function with given fields:
+func (_m *MockDevicer) GetUsbInfo() ([]usb.Info, error) {
+	ret := _m.Called()
+
+	var r0 []usb.Info
+	if rf, ok := ret.Get(0).(func() []usb.Info); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]usb.Info)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func() error); ok {
+		r1 = rf()
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Mode provides a mock function with given fields:
+func (_m *MockDevicer) Mode() (DeviceMode, error) {
+	ret := _m.Called()
+
+	var r0 DeviceMode
+	if rf, ok := ret.Get(0).(func() DeviceMode); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(DeviceMode)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func() error); ok {
+		r1 = rf()
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ModeContext provides a mock function with given fields: ctx
+func (_m *MockDevicer) ModeContext(ctx context.Context) (DeviceMode, error) {
+	ret := _m.Called(ctx)
+
+	var r0 DeviceMode
+	if rf, ok := ret.Get(0).(func(context.Context) DeviceMode); ok {
+		r0 = rf(ctx)
+	} else {
+		r0 = ret.Get(0).(DeviceMode)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// OpenSession provides a mock function with given fields:
+func (_m *MockDevicer) OpenSession() (*Session, error) {
+	ret := _m.Called()
+
+	var r0 *Session
+	if rf, ok := ret.Get(0).(func() *Session); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*Session)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func() error); ok {
+		r1 = rf()
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // PassphraseAck provides a mock function with given fields: passphrase
 func (_m *MockDevicer) PassphraseAck(passphrase string) (wire.Message, error) {
 	ret := _m.Called(passphrase)
@@ -268,6 +699,27 @@ func (_m *MockDevicer) PassphraseAck(passphrase string) (wire.Message, error) {
 	return r0, r1
 }
 
+// PassphraseAckContext provides a mock function with given fields: ctx, passphrase
+func (_m *MockDevicer) PassphraseAckContext(ctx context.Context, passphrase string) (wire.Message, error) {
+	ret := _m.Called(ctx, passphrase)
+
+	var r0 wire.Message
+	if rf, ok := ret.Get(0).(func(context.Context, string) wire.Message); ok {
+		r0 = rf(ctx, passphrase)
+	} else {
+		r0 = ret.Get(0).(wire.Message)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, passphrase)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // PinMatrixAck provides a mock function with given fields: p
 func (_m *MockDevicer) PinMatrixAck(p string) (wire.Message, error) {
 	ret := _m.Called(p)
@@ -289,6 +741,27 @@ func (_m *MockDevicer) PinMatrixAck(p string) (wire.Message, error) {
 	return r0, r1
 }
 
+// PinMatrixAckContext provides a mock function with given fields: ctx, p
+func (_m *MockDevicer) PinMatrixAckContext(ctx context.Context, p string) (wire.Message, error) {
+	ret := _m.Called(ctx, p)
+
+	var r0 wire.Message
+	if rf, ok := ret.Get(0).(func(context.Context, string) wire.Message); ok {
+		r0 = rf(ctx, p)
+	} else {
+		r0 = ret.Get(0).(wire.Message)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, p)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // Recovery provides a mock function with given fields: wordCount, usePassphrase, dryRun
 func (_m *MockDevicer) Recovery(wordCount uint32, usePassphrase bool, dryRun bool) (wire.Message, error) {
 	ret := _m.Called(wordCount, usePassphrase, dryRun)
@@ -310,6 +783,73 @@ func (_m *MockDevicer) Recovery(wordCount uint32, usePassphrase bool, dryRun boo
 	return r0, r1
 }
 
+// RecoveryContext provides a mock function with given fields: ctx, wordCount, usePassphrase, dryRun
+func (_m *MockDevicer) RecoveryContext(ctx context.Context, wordCount uint32, usePassphrase bool, dryRun bool) (wire.Message, error) {
+	ret := _m.Called(ctx, wordCount, usePassphrase, dryRun)
+
+	var r0 wire.Message
+	if rf, ok := ret.Get(0).(func(context.Context, uint32, bool, bool) wire.Message); ok {
+		r0 = rf(ctx, wordCount, usePassphrase, dryRun)
+	} else {
+		r0 = ret.Get(0).(wire.Message)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, uint32, bool, bool) error); ok {
+		r1 = rf(ctx, wordCount, usePassphrase, dryRun)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Reflash provides a mock function with given fields: img, policy
+func (_m *MockDevicer) Reflash(img *firmware.Image, policy firmware.Policy) ([]string, error) {
+	ret := _m.Called(img, policy)
+
+	var r0 []string
+	if rf, ok := ret.Get(0).(func(*firmware.Image, firmware.Policy) []string); ok {
+		r0 = rf(img, policy)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]string)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(*firmware.Image, firmware.Policy) error); ok {
+		r1 = rf(img, policy)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ReflashContext provides a mock function with given fields: ctx, img, policy
+func (_m *MockDevicer) ReflashContext(ctx context.Context, img *firmware.Image, policy firmware.Policy) ([]string, error) {
+	ret := _m.Called(ctx, img, policy)
+
+	var r0 []string
+	if rf, ok := ret.Get(0).(func(context.Context, *firmware.Image, firmware.Policy) []string); ok {
+		r0 = rf(ctx, img, policy)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]string)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, *firmware.Image, firmware.Policy) error); ok {
+		r1 = rf(ctx, img, policy)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // SetAutoPressButton provides a mock function with given fields: simulateButtonPress, simulateButtonType
 func (_m *MockDevicer) SetAutoPressButton(simulateButtonPress bool, simulateButtonType ButtonType) error {
 	ret := _m.Called(simulateButtonPress, simulateButtonType)
@@ -345,6 +885,57 @@ func (_m *MockDevicer) SetMnemonic(mnemonic string) (wire.Message, error) {
 	return r0, r1
 }
 
+// SetMnemonicContext provides a mock function with given fields: ctx, mnemonic
+func (_m *MockDevicer) SetMnemonicContext(ctx context.Context, mnemonic string) (wire.Message, error) {
+	ret := _m.Called(ctx, mnemonic)
+
+	var r0 wire.Message
+	if rf, ok := ret.Get(0).(func(context.Context, string) wire.Message); ok {
+		r0 = rf(ctx, mnemonic)
+	} else {
+		r0 = ret.Get(0).(wire.Message)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, mnemonic)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// SetEntropyAnalyzer provides a mock function with given fields: analyzer
+func (_m *MockDevicer) SetEntropyAnalyzer(analyzer *entropy.Analyzer) {
+	_m.Called(analyzer)
+}
+
+// SetEntropySource provides a mock function with given fields: source
+func (_m *MockDevicer) SetEntropySource(source io.Reader) {
+	_m.Called(source)
+}
+
+// SetFirmwareProgress provides a mock function with given fields: progress
+func (_m *MockDevicer) SetFirmwareProgress(progress FirmwareProgressFunc) {
+	_m.Called(progress)
+}
+
+// SetPrompter provides a mock function with given fields: prompter
+func (_m *MockDevicer) SetPrompter(prompter Prompter) {
+	_m.Called(prompter)
+}
+
+// SetExpectedAddresses provides a mock function with given fields: addresses
+func (_m *MockDevicer) SetExpectedAddresses(addresses []cipher.Address) {
+	_m.Called(addresses)
+}
+
+// SetVerify provides a mock function with given fields: verify
+func (_m *MockDevicer) SetVerify(verify bool) {
+	_m.Called(verify)
+}
+
 // SignMessage provides a mock function with given fields: addressIndex, message
 func (_m *MockDevicer) SignMessage(addressIndex int, message string) (wire.Message, error) {
 	ret := _m.Called(addressIndex, message)
@@ -366,6 +957,73 @@ func (_m *MockDevicer) SignMessage(addressIndex int, message string) (wire.Messa
 	return r0, r1
 }
 
+// SignMessageContext provides a mock function with given fields: ctx, addressIndex, message
+func (_m *MockDevicer) SignMessageContext(ctx context.Context, addressIndex int, message string) (wire.Message, error) {
+	ret := _m.Called(ctx, addressIndex, message)
+
+	var r0 wire.Message
+	if rf, ok := ret.Get(0).(func(context.Context, int, string) wire.Message); ok {
+		r0 = rf(ctx, addressIndex, message)
+	} else {
+		r0 = ret.Get(0).(wire.Message)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, int, string) error); ok {
+		r1 = rf(ctx, addressIndex, message)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// SignTransaction provides a mock function with given fields: inputs, outputs
+func (_m *MockDevicer) SignTransaction(inputs []*messages.SkycoinTransactionInput, outputs []*messages.SkycoinTransactionOutput) ([]cipher.Sig, error) {
+	ret := _m.Called(inputs, outputs)
+
+	var r0 []cipher.Sig
+	if rf, ok := ret.Get(0).(func([]*messages.SkycoinTransactionInput, []*messages.SkycoinTransactionOutput) []cipher.Sig); ok {
+		r0 = rf(inputs, outputs)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]cipher.Sig)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func([]*messages.SkycoinTransactionInput, []*messages.SkycoinTransactionOutput) error); ok {
+		r1 = rf(inputs, outputs)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// SignTransactionContext provides a mock function with given fields: ctx, inputs, outputs
+func (_m *MockDevicer) SignTransactionContext(ctx context.Context, inputs []*messages.SkycoinTransactionInput, outputs []*messages.SkycoinTransactionOutput) ([]cipher.Sig, error) {
+	ret := _m.Called(ctx, inputs, outputs)
+
+	var r0 []cipher.Sig
+	if rf, ok := ret.Get(0).(func(context.Context, []*messages.SkycoinTransactionInput, []*messages.SkycoinTransactionOutput) []cipher.Sig); ok {
+		r0 = rf(ctx, inputs, outputs)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]cipher.Sig)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, []*messages.SkycoinTransactionInput, []*messages.SkycoinTransactionOutput) error); ok {
+		r1 = rf(ctx, inputs, outputs)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // TransactionSign provides a mock function with given fields: inputs, outputs
 func (_m *MockDevicer) TransactionSign(inputs []*messages.SkycoinTransactionInput, outputs []*messages.SkycoinTransactionOutput) (wire.Message, error) {
 	ret := _m.Called(inputs, outputs)
@@ -387,6 +1045,27 @@ func (_m *MockDevicer) TransactionSign(inputs []*messages.SkycoinTransactionInpu
 	return r0, r1
 }
 
+// TransactionSignContext provides a mock function with given fields: ctx, inputs, outputs
+func (_m *MockDevicer) TransactionSignContext(ctx context.Context, inputs []*messages.SkycoinTransactionInput, outputs []*messages.SkycoinTransactionOutput) (wire.Message, error) {
+	ret := _m.Called(ctx, inputs, outputs)
+
+	var r0 wire.Message
+	if rf, ok := ret.Get(0).(func(context.Context, []*messages.SkycoinTransactionInput, []*messages.SkycoinTransactionOutput) wire.Message); ok {
+		r0 = rf(ctx, inputs, outputs)
+	} else {
+		r0 = ret.Get(0).(wire.Message)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, []*messages.SkycoinTransactionInput, []*messages.SkycoinTransactionOutput) error); ok {
+		r1 = rf(ctx, inputs, outputs)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // Wipe provides a mock function with given fields:
 func (_m *MockDevicer) Wipe() (wire.Message, error) {
 	ret := _m.Called()
@@ -408,6 +1087,27 @@ func (_m *MockDevicer) Wipe() (wire.Message, error) {
 	return r0, r1
 }
 
+// WipeContext provides a mock function with given fields: ctx
+func (_m *MockDevicer) WipeContext(ctx context.Context) (wire.Message, error) {
+	ret := _m.Called(ctx)
+
+	var r0 wire.Message
+	if rf, ok := ret.Get(0).(func(context.Context) wire.Message); ok {
+		r0 = rf(ctx)
+	} else {
+		r0 = ret.Get(0).(wire.Message)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // WordAck provides a mock function with given fields: word
 func (_m *MockDevicer) WordAck(word string) (wire.Message, error) {
 	ret := _m.Called(word)
@@ -428,3 +1128,24 @@ func (_m *MockDevicer) WordAck(word string) (wire.Message, error) {
 
 	return r0, r1
 }
+
+// WordAckContext provides a mock function with given fields: ctx, word
+func (_m *MockDevicer) WordAckContext(ctx context.Context, word string) (wire.Message, error) {
+	ret := _m.Called(ctx, word)
+
+	var r0 wire.Message
+	if rf, ok := ret.Get(0).(func(context.Context, string) wire.Message); ok {
+		r0 = rf(ctx, word)
+	} else {
+		r0 = ret.Get(0).(wire.Message)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, word)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
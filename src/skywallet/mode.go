@@ -0,0 +1,59 @@
+package skywallet
+
+import (
+	messages "github.com/skycoin/hardware-wallet-protob/go"
+
+	"github.com/skycoin/hardware-wallet-go/src/skywallet/usb"
+)
+
+// DeviceMode is the software a wallet is running
+type DeviceMode int
+
+const (
+	// DeviceModeUnknown the mode could not be determined
+	DeviceModeUnknown DeviceMode = iota
+	// DeviceModeFirmware the wallet runs its firmware
+	DeviceModeFirmware
+	// DeviceModeBootloader the wallet runs its bootloader, a firmware can be uploaded
+	DeviceModeBootloader
+)
+
+func (m DeviceMode) String() string {
+	switch m {
+	case DeviceModeFirmware:
+		return "FIRMWARE"
+	case DeviceModeBootloader:
+		return "BOOTLOADER"
+	default:
+		return "UNKNOWN"
+	}
+}
+
+// InfoMode returns a best-effort hint of the mode from the usb ids of a wallet.
+// The Skycoin bootloader enumerates with the firmware product id so the hint is normally
+// DeviceModeFirmware for this vendor, FeaturesMode is authoritative.
+func InfoMode(info usb.Info) DeviceMode {
+	if info.Bootloader() {
+		return DeviceModeBootloader
+	}
+	return DeviceModeFirmware
+}
+
+// FeaturesMode returns the mode reported in the features of a wallet
+func FeaturesMode(features *messages.Features) DeviceMode {
+	if features == nil {
+		return DeviceModeUnknown
+	}
+	if features.GetBootloaderMode() {
+		return DeviceModeBootloader
+	}
+	return DeviceModeFirmware
+}
+
+// deviceMode returns the mode of a wallet from its features if known, from its usb ids otherwise
+func deviceMode(info usb.Info, features *messages.Features) DeviceMode {
+	if features != nil {
+		return FeaturesMode(features)
+	}
+	return InfoMode(info)
+}
@@ -0,0 +1,61 @@
+package skywallet
+
+import (
+	"testing"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/stretchr/testify/require"
+
+	messages "github.com/skycoin/hardware-wallet-protob/go"
+
+	"github.com/skycoin/hardware-wallet-go/src/skywallet/usb"
+)
+
+func TestDeviceMode(t *testing.T) {
+	firmwareInfo := usb.Info{VendorID: SkycoinVendorID, ProductID: SkycoinHwProductID, Type: usb.TypeT1Hid}
+	bootloaderInfo := usb.Info{VendorID: SkycoinVendorID, ProductID: SkycoinBootloaderProductID, Type: usb.TypeT1Hid}
+
+	tt := []struct {
+		name     string
+		info     usb.Info
+		features *messages.Features
+		mode     DeviceMode
+	}{
+		{
+			name: "firmware product id",
+			info: firmwareInfo,
+			mode: DeviceModeFirmware,
+		},
+		{
+			name: "bootloader product id",
+			info: bootloaderInfo,
+			mode: DeviceModeBootloader,
+		},
+		{
+			name: "webusb bootloader",
+			info: usb.Info{Type: usb.TypeT1WebusbBoot},
+			mode: DeviceModeBootloader,
+		},
+		{
+			name:     "bootloader with the firmware product id",
+			info:     firmwareInfo,
+			features: &messages.Features{BootloaderMode: proto.Bool(true)},
+			mode:     DeviceModeBootloader,
+		},
+		{
+			name:     "features are authoritative",
+			info:     bootloaderInfo,
+			features: &messages.Features{},
+			mode:     DeviceModeFirmware,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.mode, deviceMode(tc.info, tc.features))
+		})
+	}
+
+	require.Equal(t, DeviceModeUnknown, FeaturesMode(nil))
+	require.Equal(t, "BOOTLOADER", DeviceModeBootloader.String())
+}
@@ -1,6 +1,10 @@
 package skywallet
 
-import "fmt"
+import (
+	"fmt"
+	"io"
+	"os"
+)
 
 const (
 	maxbars int = 100
@@ -11,26 +15,31 @@ type Progbar struct {
 	total int
 }
 
+// NewProgbar returns a progress bar reaching 100% at total
+func NewProgbar(total int) *Progbar {
+	return &Progbar{total: total}
+}
+
 // PrintProg print the progress var for the portion value
 func (p *Progbar) PrintProg(portion int) {
 	bars := p.calcBars(portion)
 	spaces := maxbars - bars - 1
 	percent := 100 * (float32(portion) / float32(p.total))
-	fmt.Print("\r[")
+	fmt.Fprint(os.Stderr, "\r[")
 	for i := 0; i < bars; i++ {
-		fmt.Print("=")
+		fmt.Fprint(os.Stderr, "=")
 	}
-	fmt.Print(">")
+	fmt.Fprint(os.Stderr, ">")
 	for i := 0; i <= spaces; i++ {
-		fmt.Print(" ")
+		fmt.Fprint(os.Stderr, " ")
 	}
-	fmt.Printf(" ] %3.2f%% (%d/%d)", percent, portion, p.total)
+	fmt.Fprintf(os.Stderr, " ] %3.2f%% (%d/%d)", percent, portion, p.total)
 }
 
 // PrintComplete print the progress bar as completed
 func (p *Progbar) PrintComplete() {
 	p.PrintProg(p.total)
-	fmt.Print("\n")
+	fmt.Fprint(os.Stderr, "\n")
 }
 
 func (p *Progbar) calcBars(portion int) int {
@@ -39,3 +48,17 @@ func (p *Progbar) calcBars(portion int) int {
 	}
 	return int(float32(maxbars) / (float32(p.total) / float32(portion)))
 }
+
+// progressWriter writes to w and prints the number of bytes written on progbar
+type progressWriter struct {
+	w       io.Writer
+	progbar *Progbar
+	written int
+}
+
+func (p *progressWriter) Write(b []byte) (int, error) {
+	n, err := p.w.Write(b)
+	p.written += n
+	p.progbar.PrintProg(p.written)
+	return n, err
+}
@@ -0,0 +1,52 @@
+package skywallet
+
+import (
+	"fmt"
+
+	messages "github.com/skycoin/hardware-wallet-protob/go"
+)
+
+// Redacted replaces the secret values of decoded messages and of Sensitive values
+const Redacted = "[redacted]"
+
+// secretFields are the protobuf names of the message fields holding a PIN, a passphrase,
+// mnemonic words or a private key, by message type.
+//
+// The values of these fields must never appear in logs or errors, TestSecretFields fails
+// if a message has a field named like a secret missing from the list.
+var secretFields = map[messages.MessageType][]string{
+	messages.MessageType_MessageType_PinMatrixAck:  {"pin"},
+	messages.MessageType_MessageType_PassphraseAck: {"passphrase"},
+	messages.MessageType_MessageType_WordAck:       {"word"},
+	messages.MessageType_MessageType_SetMnemonic:   {"mnemonic"},
+	messages.MessageType_MessageType_LoadDevice:    {"mnemonic", "node", "pin"},
+}
+
+// SecretFields returns the protobuf names of the fields of the messages of type kind holding secrets
+func SecretFields(kind messages.MessageType) []string {
+	return secretFields[kind]
+}
+
+// Sensitive is a secret value, e.g. a PIN, a passphrase, a mnemonic or a word,
+// that prints as Redacted whatever the format verb so it can be logged safely
+type Sensitive string
+
+// String returns Redacted
+func (s Sensitive) String() string {
+	return Redacted
+}
+
+// GoString returns Redacted
+func (s Sensitive) GoString() string {
+	return Redacted
+}
+
+// Format writes Redacted for every verb, e.g. %s, %q, %x or %#v
+func (s Sensitive) Format(f fmt.State, verb rune) {
+	fmt.Fprint(f, Redacted)
+}
+
+// MarshalText returns Redacted, it is used by encoding/json
+func (s Sensitive) MarshalText() ([]byte, error) {
+	return []byte(Redacted), nil
+}
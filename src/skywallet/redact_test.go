@@ -0,0 +1,161 @@
+package skywallet
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/sirupsen/logrus"
+	"github.com/skycoin/skycoin/src/util/logging"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	messages "github.com/skycoin/hardware-wallet-protob/go"
+
+	"github.com/skycoin/hardware-wallet-go/src/skywallet/wire"
+)
+
+// secretNames are the names of the protobuf fields holding a secret, the fields named
+// like this must be listed in secretFields. Flags like pin_protection are not secrets.
+var secretNames = map[string]bool{
+	"pin":        true,
+	"passphrase": true,
+	"word":       true,
+	"mnemonic":   true,
+	"node":       true,
+}
+
+// captureLogs sends the logs of the package to the returned buffer until restore is called
+func captureLogs() (logs *bytes.Buffer, restore func()) {
+	logs = &bytes.Buffer{}
+	logger := logrus.New()
+	logger.Out = logs
+	logger.Level = logrus.DebugLevel
+	defaultLog := log
+	log = &logging.Logger{FieldLogger: logger}
+	return logs, func() {
+		log = defaultLog
+	}
+}
+
+func TestSecretFields(t *testing.T) {
+	for value, name := range messages.MessageType_name {
+		kind := messages.MessageType(value)
+		typ := proto.MessageType(strings.TrimPrefix(name, "MessageType_"))
+		require.NotNil(t, typ, name)
+
+		fields := make(map[string]bool)
+		for _, p := range proto.GetProperties(typ.Elem()).Prop {
+			fields[p.OrigName] = true
+			if secretNames[p.OrigName] {
+				require.Contains(t, SecretFields(kind), p.OrigName, "%s field %s is not listed in secretFields", name, p.OrigName)
+			}
+		}
+
+		// the audited fields must exist
+		for _, field := range SecretFields(kind) {
+			require.True(t, fields[field], "%s has no field %s", name, field)
+		}
+	}
+}
+
+func TestSensitive(t *testing.T) {
+	s := Sensitive("1234")
+	for _, format := range []string{"%s", "%v", "%+v", "%#v", "%q", "%x", "%10s"} {
+		require.Equal(t, Redacted, fmt.Sprintf(format, s), format)
+	}
+	require.Equal(t, Redacted, fmt.Sprint(s))
+	require.Equal(t, "pin: "+Redacted, fmt.Errorf("pin: %v", s).Error())
+
+	data, err := json.Marshal(struct{ Pin Sensitive }{s})
+	require.NoError(t, err)
+	require.Equal(t, `{"Pin":"[redacted]"}`, string(data))
+}
+
+// TestSecretsNotLogged sends secrets to the device and checks they appear neither in the logs nor in the errors
+func TestSecretsNotLogged(t *testing.T) {
+	logs, restore := captureLogs()
+	defer restore()
+
+	failure, err := proto.Marshal(&messages.Failure{
+		Code:    messages.FailureType_Failure_DataError.Enum(),
+		Message: proto.String("Invalid input"),
+	})
+	require.NoError(t, err)
+
+	tt := []struct {
+		name   string
+		secret string
+		send   func(d *Device, secret string) (wire.Message, error)
+	}{
+		{
+			name:   "pin",
+			secret: "7391",
+			send:   (*Device).PinMatrixAck,
+		},
+		{
+			name:   "passphrase",
+			secret: "correct horse",
+			send:   (*Device).PassphraseAck,
+		},
+		{
+			name:   "word",
+			secret: "battery",
+			send:   (*Device).WordAck,
+		},
+		{
+			name:   "mnemonic",
+			secret: "cloud flower upset remain green metal below cup stem infant art thank",
+			send:   (*Device).SetMnemonic,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			logs.Reset()
+			driverMock := &MockDeviceDriver{}
+			driverMock.On("GetDevice").Return(&testHelperCloseableBuffer{}, nil)
+			driverMock.On("SendToDevice", mock.Anything, mock.Anything).Return(
+				wire.Message{Kind: uint16(messages.MessageType_MessageType_Failure), Data: failure}, nil).Once()
+			driverMock.On("SendToDevice", mock.Anything, mock.Anything).Return(wire.Message{}, errors.New("device unreachable"))
+			device := getMockDevice(driverMock)
+
+			// the failure sent by the device, then a communication error
+			for i := 0; i < 2; i++ {
+				_, err := tc.send(&device, tc.secret)
+				require.Error(t, err)
+				require.NotContains(t, err.Error(), tc.secret)
+			}
+
+			require.NotContains(t, logs.String(), tc.secret)
+			for _, word := range strings.Fields(tc.secret) {
+				require.NotContains(t, logs.String(), word)
+			}
+		})
+	}
+}
+
+func TestRecoveryPassphraseLogged(t *testing.T) {
+	logs, restore := captureLogs()
+	defer restore()
+
+	driverMock := &MockDeviceDriver{}
+	driverMock.On("GetDevice").Return(&testHelperCloseableBuffer{}, nil)
+	driverMock.On("SendToDevice", mock.Anything, mock.Anything).Return(
+		wire.Message{Kind: uint16(messages.MessageType_MessageType_Success)}, nil)
+	device := getMockDevice(driverMock)
+
+	// the value of the flag is logged, not its pointer
+	_, err := device.Recovery(12, proto.Bool(true), true)
+	require.NoError(t, err)
+	require.Contains(t, logs.String(), "Using passphrase true")
+
+	logs.Reset()
+	_, err = device.Recovery(12, nil, true)
+	require.NoError(t, err)
+	require.NotContains(t, logs.String(), "Using passphrase")
+}
@@ -0,0 +1,88 @@
+package skywallet
+
+import (
+	"context"
+	"time"
+
+	messages "github.com/skycoin/hardware-wallet-protob/go"
+
+	"github.com/skycoin/hardware-wallet-go/src/skywallet/firmware"
+)
+
+// modePollInterval is the interval between two features requests while waiting for a device mode
+var modePollInterval = defaultWatchInterval
+
+// Reflash installs img on the device, guiding the user through the mode changes:
+//
+// - if the device is not in bootloader mode, FirmwareStageReplug is reported and Reflash
+// waits for the user to plug the device while pressing both buttons
+//
+// - img is validated against the bootloader features with policy, then uploaded
+//
+// - FirmwareStageReconnect is reported and Reflash waits for the device to restart in firmware mode
+//
+// The warnings of the validation are returned. Stages are reported to the FirmwareProgressFunc
+// set by SetFirmwareProgress. Reflash does not return until the device is plugged in the expected
+// mode, use ReflashContext to give up.
+func (d *Device) Reflash(img *firmware.Image, policy firmware.Policy) ([]string, error) {
+	return d.ReflashContext(context.Background(), img, policy)
+}
+
+// ReflashContext is like Reflash but the flow is cancelled if ctx is done
+func (d *Device) ReflashContext(ctx context.Context, img *firmware.Image, policy firmware.Policy) ([]string, error) {
+	if d.Driver.DeviceType() != DeviceTypeUSB {
+		return nil, ErrDeviceTypeEmulator
+	}
+
+	features, err := d.FeaturesContext(ctx)
+	if err != nil && err != ErrNoDeviceConnected {
+		return nil, err
+	}
+
+	if FeaturesMode(features) != DeviceModeBootloader {
+		d.reportFirmwareProgress(FirmwareProgress{Stage: FirmwareStageReplug})
+		features, err = d.waitForMode(ctx, DeviceModeBootloader)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	warnings, err := img.Validate(features, policy)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := d.FirmwareUploadContext(ctx, img.Bytes(), img.Hash()); err != nil {
+		return nil, err
+	}
+
+	d.reportFirmwareProgress(FirmwareProgress{Stage: FirmwareStageReconnect})
+	if _, err := d.waitForMode(ctx, DeviceModeFirmware); err != nil {
+		return nil, err
+	}
+
+	return warnings, nil
+}
+
+// waitForMode polls the features of the device until it reports mode or ctx is done.
+// Errors are expected while the device is unplugged or restarting, they are logged only.
+func (d *Device) waitForMode(ctx context.Context, mode DeviceMode) (*messages.Features, error) {
+	ticker := time.NewTicker(modePollInterval)
+	defer ticker.Stop()
+
+	for {
+		features, err := d.FeaturesContext(ctx)
+		switch {
+		case err == nil && FeaturesMode(features) == mode:
+			return features, nil
+		case err != nil && err != ErrNoDeviceConnected:
+			log.Debugf("waiting for the device in %s mode: %v", mode, err)
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,178 @@
+package skywallet
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	messages "github.com/skycoin/hardware-wallet-protob/go"
+
+	"github.com/skycoin/hardware-wallet-go/src/skywallet/capture"
+	"github.com/skycoin/hardware-wallet-go/src/skywallet/wire"
+)
+
+func loadCapture(t *testing.T, name string) []capture.Report {
+	f, err := os.Open(filepath.Join("testdata", name))
+	require.NoError(t, err)
+	defer f.Close()
+
+	reports, err := capture.Load(f)
+	require.NoError(t, err)
+	return reports
+}
+
+// TestReplayEntropyRequest replays sessions in which the device asks for host entropy to check
+// the EntropyRequest handling shared by the requests
+func TestReplayEntropyRequest(t *testing.T) {
+	generateMnemonic := func(d *Device) (wire.Message, error) {
+		return d.GenerateMnemonic(12, false)
+	}
+	ping := func(d *Device) (wire.Message, error) {
+		s, err := d.OpenSession()
+		if err != nil {
+			return wire.Message{}, err
+		}
+		defer s.Close()
+
+		if !s.Connected() {
+			return wire.Message{}, errors.New("not connected")
+		}
+		return wire.Message{Kind: uint16(messages.MessageType_MessageType_Success)}, nil
+	}
+
+	tt := []struct {
+		name    string
+		capture string
+		send    func(d *Device) (wire.Message, error)
+		success string
+	}{
+		{
+			name:    "entropy request",
+			capture: "generate_mnemonic.jsonl",
+			send:    generateMnemonic,
+			success: "Mnemonic successfully configured",
+		},
+		{
+			// the device acknowledges the EntropyAck before answering the request
+			name:    "entropy ack success",
+			capture: "generate_mnemonic_ack_success.jsonl",
+			send:    generateMnemonic,
+			success: "Mnemonic successfully configured",
+		},
+		{
+			name:    "several entropy requests",
+			capture: "generate_mnemonic_entropy_requests.jsonl",
+			send:    generateMnemonic,
+			success: "Mnemonic successfully configured",
+		},
+		{
+			name:    "button ack",
+			capture: "button_ack_entropy_request.jsonl",
+			send:    (*Device).ButtonAck,
+			success: "Mnemonic successfully configured",
+		},
+		{
+			// the Success acknowledging the EntropyAck is not taken for the answer to the Ping
+			name:    "ping",
+			capture: "ping_entropy_request.jsonl",
+			send:    ping,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			bus := capture.NewReplayBus(loadCapture(t, tc.capture))
+			device := NewDeviceWithDriver(NewDriverWithBus(DeviceTypeUSB, bus))
+
+			msg, err := tc.send(device)
+			require.NoError(t, err)
+			require.Equal(t, uint16(messages.MessageType_MessageType_Success), msg.Kind)
+
+			if tc.success != "" {
+				success, err := DecodeSuccessMsg(msg)
+				require.NoError(t, err)
+				require.Equal(t, tc.success, success)
+			}
+			require.Zero(t, bus.Remaining())
+		})
+	}
+}
+
+func TestReplayEntropySource(t *testing.T) {
+	logs, restore := captureLogs()
+	defer restore()
+
+	hostEntropy := bytes.Repeat([]byte{0x5a}, entropyBufferSize)
+	var written bytes.Buffer
+	bus := capture.NewReplayBus(loadCapture(t, "generate_mnemonic.jsonl"))
+	device := NewDeviceWithDriver(NewDriverWithBus(DeviceTypeUSB, bus,
+		WithCapture(&written), WithEntropySource(bytes.NewReader(hostEntropy))))
+
+	_, err := device.GenerateMnemonic(12, false)
+	require.NoError(t, err)
+
+	// the EntropyAck holds the entropy of the source
+	reports, err := capture.Load(&written)
+	require.NoError(t, err)
+	var ack *messages.EntropyAck
+	for _, report := range reports {
+		if report.Kind != messages.MessageType_MessageType_EntropyAck.String() {
+			continue
+		}
+		// the message fits in a report
+		data, err := report.Bytes()
+		require.NoError(t, err)
+		msg, err := wire.ReadFrom(bytes.NewReader(data))
+		require.NoError(t, err)
+		m, err := DecodeMessage(*msg)
+		require.NoError(t, err)
+		ack = m.(*messages.EntropyAck)
+	}
+	require.NotNil(t, ack)
+	require.Equal(t, hostEntropy, ack.Entropy)
+
+	// only the hash of the entropy is logged
+	sum := sha256.Sum256(hostEntropy)
+	require.Contains(t, logs.String(), hex.EncodeToString(sum[:]))
+	require.NotContains(t, logs.String(), hex.EncodeToString(hostEntropy))
+
+	// a source running out of entropy fails the request
+	bus = capture.NewReplayBus(loadCapture(t, "generate_mnemonic.jsonl"))
+	device = NewDeviceWithDriver(NewDriverWithBus(DeviceTypeUSB, bus, WithEntropySource(bytes.NewReader(nil))))
+	_, err = device.GenerateMnemonic(12, false)
+	require.EqualError(t, err, "failed to read host entropy: EOF")
+
+	// the source can be set on the device as well
+	bus = capture.NewReplayBus(loadCapture(t, "generate_mnemonic.jsonl"))
+	device = NewDeviceWithDriver(NewDriverWithBus(DeviceTypeUSB, bus))
+	device.SetEntropySource(bytes.NewReader(nil))
+	_, err = device.GenerateMnemonic(12, false)
+	require.EqualError(t, err, "failed to read host entropy: EOF")
+}
+
+func TestMessageEntropyAckLogged(t *testing.T) {
+	logs, restore := captureLogs()
+	defer restore()
+
+	chunks, err := MessageEntropyAck(entropyBufferSize)
+	require.NoError(t, err)
+	var buf bytes.Buffer
+	for _, chunk := range chunks {
+		buf.Write(chunk[:])
+	}
+	msg, err := wire.ReadFrom(&buf)
+	require.NoError(t, err)
+	m, err := DecodeMessage(*msg)
+	require.NoError(t, err)
+	ack := m.(*messages.EntropyAck)
+
+	// the entropy of the operating system is audited like the one of a source
+	sum := sha256.Sum256(ack.Entropy)
+	require.Contains(t, logs.String(), hex.EncodeToString(sum[:]))
+}
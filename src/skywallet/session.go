@@ -0,0 +1,804 @@
+package skywallet
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/skycoin/skycoin/src/cipher"
+
+	messages "github.com/skycoin/hardware-wallet-protob/go"
+
+	"github.com/skycoin/hardware-wallet-go/src/skywallet/usb"
+	"github.com/skycoin/hardware-wallet-go/src/skywallet/wire"
+)
+
+// ErrSessionClosed is returned if a request is sent through a closed session
+var ErrSessionClosed = errors.New("session closed")
+
+// Session is a connection to the device kept open across several requests.
+// The device is locked while the session is open, any other request on the
+// same Device blocks until the session is closed.
+type Session struct {
+	device *Device
+	dev    usb.Device
+}
+
+// OpenSession connects to the device and keeps the connection open until Close is called
+func (d *Device) OpenSession() (*Session, error) {
+	d.Lock()
+
+	dev, err := d.Driver.GetDevice()
+	if err != nil {
+		d.Unlock()
+		return nil, err
+	}
+
+	return &Session{
+		device: d,
+		dev:    dev,
+	}, nil
+}
+
+// Close closes the connection to the device and releases the device lock
+func (s *Session) Close() error {
+	if s.dev == nil {
+		return ErrSessionClosed
+	}
+
+	err := s.dev.Close(false)
+	s.release()
+	return err
+}
+
+// release releases the device lock, the connection must be closed already
+func (s *Session) release() {
+	s.dev = nil
+	s.device.Unlock()
+}
+
+// checkContext releases the session if err reports the connection was closed because ctx is done
+func (s *Session) checkContext(ctx context.Context, err error) error {
+	if err != nil && err == ctx.Err() {
+		s.release()
+	}
+	return err
+}
+
+func (s *Session) send(ctx context.Context, chunks [][64]byte) (wire.Message, error) {
+	return s.sendProgress(ctx, chunks, nil)
+}
+
+// sendProgress is like send but if progress is set the chunks are written in batches
+// and progress is called with the number of chunks written after each batch
+func (s *Session) sendProgress(ctx context.Context, chunks [][64]byte, progress func(written int)) (wire.Message, error) {
+	msg, err := s.exchange(ctx, chunks, progress)
+	if err != nil {
+		return wire.Message{}, err
+	}
+
+	if err := checkFailure(msg, messageType(chunks)); err != nil {
+		return wire.Message{}, err
+	}
+
+	if progress != nil {
+		progress(len(chunks))
+	}
+
+	return msg, nil
+}
+
+// exchange writes chunks like sendProgress and returns the answer of the device,
+// a Failure answer is returned as is
+func (s *Session) exchange(ctx context.Context, chunks [][64]byte, progress func(written int)) (wire.Message, error) {
+	if s.dev == nil {
+		return wire.Message{}, ErrSessionClosed
+	}
+
+	last := chunks
+	if progress != nil {
+		for len(last) > progressBatchChunks {
+			if err := ctx.Err(); err != nil {
+				// drop the connection like SendToDeviceContext does
+				if err := s.dev.Close(false); err != nil {
+					log.Errorf("failed to close device: %v", err)
+				}
+				s.release()
+				return wire.Message{}, err
+			}
+
+			if err := s.device.Driver.SendToDeviceNoAnswer(s.dev, last[:progressBatchChunks]); err != nil {
+				return wire.Message{}, err
+			}
+			last = last[progressBatchChunks:]
+			progress(len(chunks) - len(last))
+		}
+	}
+
+	var msg wire.Message
+	var err error
+	if ctx.Done() == nil {
+		msg, err = s.device.Driver.SendToDevice(s.dev, last)
+	} else {
+		msg, err = s.device.Driver.SendToDeviceContext(ctx, s.dev, last)
+	}
+	if err := s.checkContext(ctx, err); err != nil {
+		return wire.Message{}, err
+	}
+
+	if err := s.checkAnswer(ctx, msg); err != nil {
+		return wire.Message{}, err
+	}
+
+	return msg, nil
+}
+
+// AddressGen Ask the device to generate an address
+func (s *Session) AddressGen(addressN, startIndex uint32, confirmAddress bool) (wire.Message, error) {
+	return s.AddressGenContext(context.Background(), addressN, startIndex, confirmAddress)
+}
+
+// AddressGenContext is like AddressGen but the request is cancelled if ctx is done
+func (s *Session) AddressGenContext(ctx context.Context, addressN, startIndex uint32, confirmAddress bool) (wire.Message, error) {
+	if addressN == 0 {
+		return wire.Message{}, ErrAddressNZero
+	}
+
+	addressGenChunks, err := MessageAddressGen(addressN, startIndex, confirmAddress)
+	if err != nil {
+		return wire.Message{}, err
+	}
+
+	s.expectAnswer(messages.MessageType_MessageType_ResponseSkycoinAddress, checkAddresses(addressN, startIndex))
+	return s.send(ctx, addressGenChunks)
+}
+
+// ApplySettings send ApplySettings request to the device
+func (s *Session) ApplySettings(usePassphrase *bool, label string, language string) (wire.Message, error) {
+	return s.ApplySettingsContext(context.Background(), usePassphrase, label, language)
+}
+
+// ApplySettingsContext is like ApplySettings but the request is cancelled if ctx is done
+func (s *Session) ApplySettingsContext(ctx context.Context, usePassphrase *bool, label string, language string) (wire.Message, error) {
+	applySettingsChunks, err := MessageApplySettings(usePassphrase, label, language)
+	if err != nil {
+		return wire.Message{}, err
+	}
+
+	return s.send(ctx, applySettingsChunks)
+}
+
+// Backup ask the device to perform the seed backup
+func (s *Session) Backup() (wire.Message, error) {
+	return s.BackupContext(context.Background())
+}
+
+// BackupContext is like Backup but the request is cancelled if ctx is done
+func (s *Session) BackupContext(ctx context.Context) (wire.Message, error) {
+	backupChunks, err := MessageBackup()
+	if err != nil {
+		return wire.Message{}, err
+	}
+
+	return s.send(ctx, backupChunks)
+}
+
+// Cancel sends a Cancel request, the device answers it with a Failure of code
+// Failure_ActionCancelled which is returned without error
+func (s *Session) Cancel() (wire.Message, error) {
+	return s.CancelContext(context.Background())
+}
+
+// CancelContext is like Cancel but the request is cancelled if ctx is done
+func (s *Session) CancelContext(ctx context.Context) (wire.Message, error) {
+	cancelChunks, err := MessageCancel()
+	if err != nil {
+		return wire.Message{}, err
+	}
+
+	msg, err := s.exchange(ctx, cancelChunks, nil)
+	if err != nil {
+		return wire.Message{}, err
+	}
+
+	if err := checkFailure(msg, messages.MessageType_MessageType_Cancel); err != nil && !errors.Is(err, ErrActionCancelled) {
+		return wire.Message{}, err
+	}
+
+	return msg, nil
+}
+
+// CheckMessageSignature Check a message signature matches the given address.
+func (s *Session) CheckMessageSignature(message, signature, address string) (wire.Message, error) {
+	return s.CheckMessageSignatureContext(context.Background(), message, signature, address)
+}
+
+// CheckMessageSignatureContext is like CheckMessageSignature but the request is cancelled if ctx is done
+func (s *Session) CheckMessageSignatureContext(ctx context.Context, message, signature, address string) (wire.Message, error) {
+	checkMessageSignatureChunks, err := MessageCheckMessageSignature(message, signature, address)
+	if err != nil {
+		return wire.Message{}, err
+	}
+
+	return s.send(ctx, checkMessageSignatureChunks)
+}
+
+// ChangePin changes device's PIN code, see Device.ChangePin
+func (s *Session) ChangePin(removePin *bool) (wire.Message, error) {
+	return s.ChangePinContext(context.Background(), removePin)
+}
+
+// ChangePinContext is like ChangePin but the request is cancelled if ctx is done
+func (s *Session) ChangePinContext(ctx context.Context, removePin *bool) (wire.Message, error) {
+	if removePin == nil {
+		return wire.Message{}, ErrRemovePinNil
+	}
+
+	changePinChunks, err := MessageChangePin(removePin)
+	if err != nil {
+		return wire.Message{}, err
+	}
+
+	return s.send(ctx, changePinChunks)
+}
+
+// Connected checks if we can communicate with the device
+func (s *Session) Connected() bool {
+	if s.dev == nil {
+		return false
+	}
+
+	return connected(s.dev, s.device.entropySource())
+}
+
+// FirmwareUpload Updates device's firmware
+func (s *Session) FirmwareUpload(payload []byte, hash [32]byte) error {
+	return s.FirmwareUploadContext(context.Background(), payload, hash)
+}
+
+// FirmwareUploadContext is like FirmwareUpload but the request is cancelled if ctx is done
+func (s *Session) FirmwareUploadContext(ctx context.Context, payload []byte, hash [32]byte) error {
+	if s.device.Driver.DeviceType() != DeviceTypeUSB {
+		return ErrDeviceTypeEmulator
+	}
+
+	if s.dev == nil {
+		return ErrSessionClosed
+	}
+
+	if err := initialize(s.dev, s.device.entropySource()); err != nil {
+		return err
+	}
+
+	log.Printf("Length of firmware %d", uint32(len(payload)))
+
+	stage := FirmwareStageErase
+	if err := s.firmwareUpload(ctx, payload, hash, &stage); err != nil {
+		return &FirmwareUploadError{
+			Stage: stage,
+			Err:   err,
+		}
+	}
+	return nil
+}
+
+// firmwareUpload runs the firmware upload stages, stage is set to the running one
+func (s *Session) firmwareUpload(ctx context.Context, payload []byte, hash [32]byte, stage *FirmwareStage) error {
+	progress := func(p FirmwareProgress) {
+		*stage = p.Stage
+		s.device.reportFirmwareProgress(p)
+	}
+
+	progress(FirmwareProgress{Stage: FirmwareStageErase})
+	chunks, err := MessageFirmwareErase(payload)
+	if err != nil {
+		return err
+	}
+	erasemsg, err := s.send(ctx, chunks)
+	if err != nil {
+		return err
+	}
+
+	if erasemsg.Kind != uint16(messages.MessageType_MessageType_Success) {
+		return fmt.Errorf("received unexpected message type: %s", messages.MessageType(erasemsg.Kind))
+	}
+	log.Printf("Success %d! FirmwareErase %s\n", erasemsg.Kind, erasemsg.Data)
+
+	log.Printf("Hash: %x\n", hash)
+
+	chunks, err = MessageFirmwareUpload(payload, hash)
+	if err != nil {
+		return err
+	}
+	total := len(payload)
+	progress(FirmwareProgress{Stage: FirmwareStageTransfer, Total: total})
+	uploadmsg, err := s.sendProgress(ctx, chunks, func(written int) {
+		progress(FirmwareProgress{
+			Stage:   FirmwareStageTransfer,
+			Written: total * written / len(chunks),
+			Total:   total,
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	if uploadmsg.Kind != uint16(messages.MessageType_MessageType_ButtonRequest) {
+		return fmt.Errorf("received unexpected message type: %s", messages.MessageType(uploadmsg.Kind))
+	}
+
+	progress(FirmwareProgress{Stage: FirmwareStageConfirm})
+	log.Println("Please confirm in the device if fingerprints match")
+	// Send ButtonAck
+	chunks, err = MessageButtonAck()
+	if err != nil {
+		return err
+	}
+	resp, err := s.send(ctx, chunks)
+	if err != nil {
+		return err
+	}
+
+	if err := expectMessage(resp, messages.MessageType_MessageType_Success); err != nil {
+		return err
+	}
+
+	progress(FirmwareProgress{Stage: FirmwareStageReboot})
+	return nil
+}
+
+// GetFeatures send Features message to the device
+func (s *Session) GetFeatures() (wire.Message, error) {
+	return s.GetFeaturesContext(context.Background())
+}
+
+// GetFeaturesContext is like GetFeatures but the request is cancelled if ctx is done
+func (s *Session) GetFeaturesContext(ctx context.Context) (wire.Message, error) {
+	getFeaturesChunks, err := MessageGetFeatures()
+	if err != nil {
+		return wire.Message{}, err
+	}
+
+	return s.send(ctx, getFeaturesChunks)
+}
+
+// GenerateMnemonic Ask the device to generate a mnemonic and configure itself with it.
+func (s *Session) GenerateMnemonic(wordCount uint32, usePassphrase bool) (wire.Message, error) {
+	return s.GenerateMnemonicContext(context.Background(), wordCount, usePassphrase)
+}
+
+// GenerateMnemonicContext is like GenerateMnemonic but the request is cancelled if ctx is done
+func (s *Session) GenerateMnemonicContext(ctx context.Context, wordCount uint32, usePassphrase bool) (wire.Message, error) {
+	if wordCount != 12 && wordCount != 24 {
+		return wire.Message{}, ErrInvalidWordCount
+	}
+
+	generateMnemonicChunks, err := MessageGenerateMnemonic(wordCount, usePassphrase)
+	if err != nil {
+		return wire.Message{}, err
+	}
+
+	return s.send(ctx, generateMnemonicChunks)
+}
+
+// Recovery ask the device to perform the seed backup
+func (s *Session) Recovery(wordCount uint32, usePassphrase *bool, dryRun bool) (wire.Message, error) {
+	return s.RecoveryContext(context.Background(), wordCount, usePassphrase, dryRun)
+}
+
+// RecoveryContext is like Recovery but the request is cancelled if ctx is done
+func (s *Session) RecoveryContext(ctx context.Context, wordCount uint32, usePassphrase *bool, dryRun bool) (wire.Message, error) {
+	if wordCount != 12 && wordCount != 24 {
+		return wire.Message{}, ErrInvalidWordCount
+	}
+
+	if usePassphrase != nil {
+		log.Printf("Using passphrase %t\n", *usePassphrase)
+	}
+	recoveryChunks, err := MessageRecovery(wordCount, usePassphrase, dryRun)
+	if err != nil {
+		return wire.Message{}, err
+	}
+
+	msg, err := s.send(ctx, recoveryChunks)
+	if err != nil {
+		return wire.Message{}, err
+	}
+	log.Printf("Recovery device response kind is: %d\n", msg.Kind)
+
+	return msg, nil
+}
+
+// SetMnemonic Configure the device with a mnemonic.
+func (s *Session) SetMnemonic(mnemonic string) (wire.Message, error) {
+	return s.SetMnemonicContext(context.Background(), mnemonic)
+}
+
+// SetMnemonicContext is like SetMnemonic but the request is cancelled if ctx is done
+func (s *Session) SetMnemonicContext(ctx context.Context, mnemonic string) (wire.Message, error) {
+	setMnemonicChunks, err := MessageSetMnemonic(mnemonic)
+	if err != nil {
+		return wire.Message{}, err
+	}
+
+	return s.send(ctx, setMnemonicChunks)
+}
+
+// SignMessage Ask the device to sign a message using the secret key at given index.
+func (s *Session) SignMessage(addressIndex int, message string) (wire.Message, error) {
+	return s.SignMessageContext(context.Background(), addressIndex, message)
+}
+
+// SignMessageContext is like SignMessage but the request is cancelled if ctx is done
+func (s *Session) SignMessageContext(ctx context.Context, addressIndex int, message string) (wire.Message, error) {
+	signMessageChunks, err := MessageSignMessage(addressIndex, message)
+	if err != nil {
+		return wire.Message{}, err
+	}
+
+	s.expectAnswer(messages.MessageType_MessageType_ResponseSkycoinSignMessage, checkMessageSignature(addressIndex, message))
+	return s.send(ctx, signMessageChunks)
+}
+
+// TransactionSign Ask the device to sign a transaction using the given information.
+func (s *Session) TransactionSign(inputs []*messages.SkycoinTransactionInput, outputs []*messages.SkycoinTransactionOutput) (wire.Message, error) {
+	return s.TransactionSignContext(context.Background(), inputs, outputs)
+}
+
+// TransactionSignContext is like TransactionSign but the request is cancelled if ctx is done
+func (s *Session) TransactionSignContext(ctx context.Context, inputs []*messages.SkycoinTransactionInput, outputs []*messages.SkycoinTransactionOutput) (wire.Message, error) {
+	transactionSignChunks, err := MessageTransactionSign(inputs, outputs)
+	if err != nil {
+		return wire.Message{}, err
+	}
+
+	s.expectAnswer(messages.MessageType_MessageType_ResponseTransactionSign, checkTransactionSignatures(inputs, outputs))
+	return s.send(ctx, transactionSignChunks)
+}
+
+// Wipe wipes out device configuration
+func (s *Session) Wipe() (wire.Message, error) {
+	return s.WipeContext(context.Background())
+}
+
+// WipeContext is like Wipe but the request is cancelled if ctx is done
+func (s *Session) WipeContext(ctx context.Context) (wire.Message, error) {
+	wipeChunks, err := MessageWipe()
+	if err != nil {
+		return wire.Message{}, err
+	}
+
+	return s.send(ctx, wipeChunks)
+}
+
+// ButtonAck when the device is waiting for the user to press a button
+// the PC need to acknowledge, showing it knows we are waiting for a user action
+func (s *Session) ButtonAck() (wire.Message, error) {
+	return s.ButtonAckContext(context.Background())
+}
+
+// ButtonAckContext is like ButtonAck but the request is cancelled if ctx is done
+func (s *Session) ButtonAckContext(ctx context.Context) (wire.Message, error) {
+	if s.dev == nil {
+		return wire.Message{}, ErrSessionClosed
+	}
+
+	// Send ButtonAck
+	buttonChunks, err := MessageButtonAck()
+	if err != nil {
+		return wire.Message{}, err
+	}
+
+	msg, err := withContext(ctx, s.dev, func(dev usb.Device) (wire.Message, error) {
+		return s.buttonAck(dev, buttonChunks)
+	})
+	if err := s.checkContext(ctx, err); err != nil {
+		return wire.Message{}, err
+	}
+
+	if err := s.checkAnswer(ctx, msg); err != nil {
+		return wire.Message{}, err
+	}
+
+	if err := checkFailure(msg, messages.MessageType_MessageType_ButtonAck); err != nil {
+		return wire.Message{}, err
+	}
+
+	return msg, nil
+}
+
+func (s *Session) buttonAck(dev usb.Device, buttonChunks [][64]byte) (wire.Message, error) {
+	err := sendToDeviceNoAnswer(dev, buttonChunks)
+	if err != nil {
+		return wire.Message{}, err
+	}
+
+	// simulate button press
+	if s.device.simulateButtonPress {
+		if err := s.device.simulateButtonPressOn(dev); err != nil {
+			return wire.Message{}, err
+		}
+	}
+
+	return readAnswer(dev, s.device.entropySource())
+}
+
+// PassphraseAck send this message when the device is waiting for the user to input a passphrase
+func (s *Session) PassphraseAck(passphrase string) (wire.Message, error) {
+	return s.PassphraseAckContext(context.Background(), passphrase)
+}
+
+// PassphraseAckContext is like PassphraseAck but the request is cancelled if ctx is done
+func (s *Session) PassphraseAckContext(ctx context.Context, passphrase string) (wire.Message, error) {
+	passphraseChunks, err := MessagePassphraseAck(passphrase)
+	if err != nil {
+		return wire.Message{}, err
+	}
+
+	return s.send(ctx, passphraseChunks)
+}
+
+// WordAck send a word to the device during device "recovery procedure"
+func (s *Session) WordAck(word string) (wire.Message, error) {
+	return s.WordAckContext(context.Background(), word)
+}
+
+// WordAckContext is like WordAck but the request is cancelled if ctx is done
+func (s *Session) WordAckContext(ctx context.Context, word string) (wire.Message, error) {
+	wordAckChunks, err := MessageWordAck(word)
+	if err != nil {
+		return wire.Message{}, err
+	}
+
+	return s.send(ctx, wordAckChunks)
+}
+
+// PinMatrixAck during PIN code setting use this message to send user input to device
+func (s *Session) PinMatrixAck(p string) (wire.Message, error) {
+	return s.PinMatrixAckContext(context.Background(), p)
+}
+
+// PinMatrixAckContext is like PinMatrixAck but the request is cancelled if ctx is done
+func (s *Session) PinMatrixAckContext(ctx context.Context, p string) (wire.Message, error) {
+	log.Printf("Setting pin: %s\n", Sensitive(p))
+
+	pinMatrixChunks, err := MessagePinMatrixAck(p)
+	if err != nil {
+		return wire.Message{}, err
+	}
+
+	return s.send(ctx, pinMatrixChunks)
+}
+
+// SimulateButtonPress simulates a button press on emulator
+func (s *Session) SimulateButtonPress() error {
+	if s.dev == nil {
+		return ErrSessionClosed
+	}
+
+	return s.device.simulateButtonPressOn(s.dev)
+}
+
+func (s *Session) runFlow(ctx context.Context, msg wire.Message) (wire.Message, error) {
+	prompter := s.device.prompter
+	if prompter == nil {
+		prompter = noPrompter{}
+	}
+
+	return RunFlowContext(ctx, s, msg, prompter)
+}
+
+// GetAddresses Ask the device to generate addresses, user input is requested through the device Prompter
+func (s *Session) GetAddresses(addressN, startIndex uint32, confirmAddress bool) ([]cipher.Address, error) {
+	return s.GetAddressesContext(context.Background(), addressN, startIndex, confirmAddress)
+}
+
+// GetAddressesContext is like GetAddresses but the request is cancelled if ctx is done
+func (s *Session) GetAddressesContext(ctx context.Context, addressN, startIndex uint32, confirmAddress bool) ([]cipher.Address, error) {
+	msg, err := s.AddressGenContext(ctx, addressN, startIndex, confirmAddress)
+	if err != nil {
+		return nil, err
+	}
+
+	msg, err = s.runFlow(ctx, msg)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := expectMessage(msg, messages.MessageType_MessageType_ResponseSkycoinAddress); err != nil {
+		return nil, err
+	}
+
+	addrs, err := DecodeResponseSkycoinAddress(msg)
+	if err != nil {
+		return nil, err
+	}
+
+	addresses := make([]cipher.Address, len(addrs))
+	for i, addr := range addrs {
+		addresses[i], err = cipher.DecodeBase58Address(addr)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return addresses, nil
+}
+
+// SignTransaction Ask the device to sign a transaction, user input is requested through the device Prompter
+func (s *Session) SignTransaction(inputs []*messages.SkycoinTransactionInput, outputs []*messages.SkycoinTransactionOutput) ([]cipher.Sig, error) {
+	return s.SignTransactionContext(context.Background(), inputs, outputs)
+}
+
+// SignTransactionContext is like SignTransaction but the request is cancelled if ctx is done
+func (s *Session) SignTransactionContext(ctx context.Context, inputs []*messages.SkycoinTransactionInput, outputs []*messages.SkycoinTransactionOutput) ([]cipher.Sig, error) {
+	msg, err := s.TransactionSignContext(ctx, inputs, outputs)
+	if err != nil {
+		return nil, err
+	}
+
+	msg, err = s.runFlow(ctx, msg)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := expectMessage(msg, messages.MessageType_MessageType_ResponseTransactionSign); err != nil {
+		return nil, err
+	}
+
+	sigs, err := DecodeResponseTransactionSign(msg)
+	if err != nil {
+		return nil, err
+	}
+	if len(sigs) != len(inputs) {
+		return nil, fmt.Errorf("device returned %d signatures for %d inputs", len(sigs), len(inputs))
+	}
+
+	signatures := make([]cipher.Sig, len(sigs))
+	for i, sig := range sigs {
+		signatures[i], err = cipher.SigFromHex(sig)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return signatures, nil
+}
+
+// GetMessageSignature Ask the device to sign a message using the secret key at given index,
+// user input is requested through the device Prompter
+func (s *Session) GetMessageSignature(addressIndex int, message string) (cipher.Sig, error) {
+	return s.GetMessageSignatureContext(context.Background(), addressIndex, message)
+}
+
+// GetMessageSignatureContext is like GetMessageSignature but the request is cancelled if ctx is done
+func (s *Session) GetMessageSignatureContext(ctx context.Context, addressIndex int, message string) (cipher.Sig, error) {
+	msg, err := s.SignMessageContext(ctx, addressIndex, message)
+	if err != nil {
+		return cipher.Sig{}, err
+	}
+
+	msg, err = s.runFlow(ctx, msg)
+	if err != nil {
+		return cipher.Sig{}, err
+	}
+
+	if err := expectMessage(msg, messages.MessageType_MessageType_ResponseSkycoinSignMessage); err != nil {
+		return cipher.Sig{}, err
+	}
+
+	signature, err := DecodeResponseSkycoinSignMessage(msg)
+	if err != nil {
+		return cipher.Sig{}, err
+	}
+
+	sig, err := cipher.SigFromHex(signature)
+	if err != nil {
+		return cipher.Sig{}, err
+	}
+
+	return sig, nil
+}
+
+// Features returns the device features
+func (s *Session) Features() (*messages.Features, error) {
+	return s.FeaturesContext(context.Background())
+}
+
+// FeaturesContext is like Features but the request is cancelled if ctx is done
+func (s *Session) FeaturesContext(ctx context.Context) (*messages.Features, error) {
+	msg, err := s.GetFeaturesContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := expectMessage(msg, messages.MessageType_MessageType_Features); err != nil {
+		return nil, err
+	}
+
+	return DecodeFeatures(msg)
+}
+
+// SaveDeviceEntropyInFile Ask the device to generate entropy and save it in a file
+// if `outFile` is the "-" string, the raw entropy is written to stdout
+func (s *Session) SaveDeviceEntropyInFile(outFile string, entropyBytes uint32, getEntropyMsgBuilder func(entropyBytes uint32) ([][64]byte, error)) error {
+	return s.SaveDeviceEntropyInFileContext(context.Background(), outFile, entropyBytes, getEntropyMsgBuilder)
+}
+
+// SaveDeviceEntropyInFileContext is like SaveDeviceEntropyInFile but the request is cancelled if ctx is done
+func (s *Session) SaveDeviceEntropyInFileContext(ctx context.Context, outFile string, entropyBytes uint32, getEntropyMsgBuilder func(entropyBytes uint32) ([][64]byte, error)) error {
+	if s.dev == nil {
+		return ErrSessionClosed
+	}
+
+	if outFile == "-" {
+		return s.SaveDeviceEntropyContext(ctx, os.Stdout, entropyBytes, getEntropyMsgBuilder)
+	}
+
+	log.Infoln("Saving entropy to", outFile)
+	if _, err := os.Stat(outFile); err == nil {
+		// nolint: gosec
+		if err = os.Chmod(outFile, 0777); err != nil {
+			log.Errorf("error with %s %s", outFile, err)
+		}
+	}
+	file, err := os.Create(outFile)
+	if err != nil {
+		log.Errorf("error creating output file %s", err)
+		return err
+	}
+	defer func() {
+		if err := os.Chmod(outFile, 0444); err != nil {
+			log.Error(err)
+		}
+	}()
+	defer file.Close()
+
+	if err := s.SaveDeviceEntropyContext(ctx, file, entropyBytes, getEntropyMsgBuilder); err != nil {
+		return err
+	}
+
+	fileInfo, err := os.Stat(outFile)
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+	if fileInfo.Size() != int64(entropyBytes) {
+		return fmt.Errorf(
+			"no engout bytes saved in the file %s\n current: %d\nrequired: %d",
+			outFile, fileInfo.Size(), entropyBytes)
+	}
+	return nil
+}
+
+// SaveDeviceEntropy Ask the device to generate entropy and write it to w, the progress is printed to stderr.
+// The entropy is written to the analyzer set by Device.SetEntropyAnalyzer too.
+func (s *Session) SaveDeviceEntropy(w io.Writer, entropyBytes uint32, getEntropyMsgBuilder func(entropyBytes uint32) ([][64]byte, error)) error {
+	return s.SaveDeviceEntropyContext(context.Background(), w, entropyBytes, getEntropyMsgBuilder)
+}
+
+// SaveDeviceEntropyContext is like SaveDeviceEntropy but the request is cancelled if ctx is done
+func (s *Session) SaveDeviceEntropyContext(ctx context.Context, w io.Writer, entropyBytes uint32, getEntropyMsgBuilder func(entropyBytes uint32) ([][64]byte, error)) error {
+	if s.dev == nil {
+		return ErrSessionClosed
+	}
+
+	pb := NewProgbar(int(entropyBytes))
+	w = &progressWriter{
+		w:       w,
+		progbar: pb,
+	}
+	if analyzer := s.device.entropyAnalyzer; analyzer != nil {
+		w = io.MultiWriter(analyzer, w)
+	}
+
+	if _, err := io.CopyN(w, s.NewEntropyReader(ctx, getEntropyMsgBuilder), int64(entropyBytes)); err != nil {
+		log.Error(err)
+		return err
+	}
+
+	pb.PrintComplete()
+	return nil
+}
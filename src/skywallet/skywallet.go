@@ -1,14 +1,18 @@
 package skywallet
 
 import (
+	"context"
 	"errors"
 	"fmt"
-	"os"
+	"io"
 	"sync"
 	"time"
 
+	"github.com/skycoin/hardware-wallet-go/src/skywallet/entropy"
+	"github.com/skycoin/hardware-wallet-go/src/skywallet/firmware"
 	"github.com/skycoin/hardware-wallet-go/src/skywallet/usb"
 
+	"github.com/skycoin/skycoin/src/cipher"
 	"github.com/skycoin/skycoin/src/util/logging"
 
 	messages "github.com/skycoin/hardware-wallet-protob/go"
@@ -22,6 +26,11 @@ var (
 
 const (
 	entropyBufferSize int = 32
+
+	// MaxTransactionInputs is the firmware limit of inputs per TransactionSign message
+	MaxTransactionInputs = 8
+	// MaxTransactionOutputs is the firmware limit of outputs per TransactionSign message
+	MaxTransactionOutputs = 8
 )
 
 // ButtonType is emulator button press simulation type
@@ -48,6 +57,11 @@ var (
 	ErrInvalidWordCount = errors.New("word count must be 12 or 24")
 	// ErrNoDeviceConnected is returned if no device is connected to the system
 	ErrNoDeviceConnected = errors.New("no device connected")
+	// ErrDeviceNotFound is returned if no connected device matches the selection
+	ErrDeviceNotFound = errors.New("no connected device matches the selection")
+	// ErrTransactionTooLarge is returned if a transaction exceeds the inputs or outputs the firmware signs at once.
+	// The firmware signs the hash of the whole transaction, so it can't be split in several requests.
+	ErrTransactionTooLarge = fmt.Errorf("transactions can't have more than %d inputs or %d outputs", MaxTransactionInputs, MaxTransactionOutputs)
 )
 
 //go:generate mockery -name Devicer -case underscore -inpkg -testonly
@@ -62,6 +76,7 @@ type Devicer interface {
 	ChangePin(removePin *bool) (wire.Message, error)
 	Connected() bool
 	Available() bool
+	GetUsbInfo() ([]usb.Info, error)
 	FirmwareUpload(payload []byte, hash [32]byte) error
 	GetFeatures() (wire.Message, error)
 	GenerateMnemonic(wordCount uint32, usePassphrase bool) (wire.Message, error)
@@ -75,6 +90,43 @@ type Devicer interface {
 	PassphraseAck(passphrase string) (wire.Message, error)
 	ButtonAck() (wire.Message, error)
 	SetAutoPressButton(simulateButtonPress bool, simulateButtonType ButtonType) error
+	OpenSession() (*Session, error)
+	SetPrompter(prompter Prompter)
+	SetVerify(verify bool)
+	SetExpectedAddresses(addresses []cipher.Address)
+	SetFirmwareProgress(progress FirmwareProgressFunc)
+	SetEntropyAnalyzer(analyzer *entropy.Analyzer)
+	SetEntropySource(source io.Reader)
+	GetAddresses(addressN, startIndex uint32, confirmAddress bool) ([]cipher.Address, error)
+	SignTransaction(inputs []*messages.SkycoinTransactionInput, outputs []*messages.SkycoinTransactionOutput) ([]cipher.Sig, error)
+	GetMessageSignature(addressIndex int, message string) (cipher.Sig, error)
+	Features() (*messages.Features, error)
+	Mode() (DeviceMode, error)
+	Reflash(img *firmware.Image, policy firmware.Policy) ([]string, error)
+	AddressGenContext(ctx context.Context, addressN, startIndex uint32, confirmAddress bool) (wire.Message, error)
+	ApplySettingsContext(ctx context.Context, usePassphrase *bool, label string, language string) (wire.Message, error)
+	BackupContext(ctx context.Context) (wire.Message, error)
+	CancelContext(ctx context.Context) (wire.Message, error)
+	CheckMessageSignatureContext(ctx context.Context, message, signature, address string) (wire.Message, error)
+	ChangePinContext(ctx context.Context, removePin *bool) (wire.Message, error)
+	FirmwareUploadContext(ctx context.Context, payload []byte, hash [32]byte) error
+	GetFeaturesContext(ctx context.Context) (wire.Message, error)
+	GenerateMnemonicContext(ctx context.Context, wordCount uint32, usePassphrase bool) (wire.Message, error)
+	RecoveryContext(ctx context.Context, wordCount uint32, usePassphrase *bool, dryRun bool) (wire.Message, error)
+	SetMnemonicContext(ctx context.Context, mnemonic string) (wire.Message, error)
+	TransactionSignContext(ctx context.Context, inputs []*messages.SkycoinTransactionInput, outputs []*messages.SkycoinTransactionOutput) (wire.Message, error)
+	SignMessageContext(ctx context.Context, addressIndex int, message string) (wire.Message, error)
+	WipeContext(ctx context.Context) (wire.Message, error)
+	PinMatrixAckContext(ctx context.Context, p string) (wire.Message, error)
+	WordAckContext(ctx context.Context, word string) (wire.Message, error)
+	PassphraseAckContext(ctx context.Context, passphrase string) (wire.Message, error)
+	ButtonAckContext(ctx context.Context) (wire.Message, error)
+	GetAddressesContext(ctx context.Context, addressN, startIndex uint32, confirmAddress bool) ([]cipher.Address, error)
+	SignTransactionContext(ctx context.Context, inputs []*messages.SkycoinTransactionInput, outputs []*messages.SkycoinTransactionOutput) ([]cipher.Sig, error)
+	GetMessageSignatureContext(ctx context.Context, addressIndex int, message string) (cipher.Sig, error)
+	FeaturesContext(ctx context.Context) (*messages.Features, error)
+	ModeContext(ctx context.Context) (DeviceMode, error)
+	ReflashContext(ctx context.Context, img *firmware.Image, policy firmware.Policy) ([]string, error)
 	Close()
 }
 
@@ -88,6 +140,17 @@ type Device struct {
 
 	simulateButtonPress bool
 	simulateButtonType  ButtonType
+
+	prompter Prompter
+	// verify enables the host side verification of the device responses
+	verify bool
+	// expectedAddresses are the addresses of the wallet trusted by the caller, from index 0
+	expectedAddresses []cipher.Address
+	// pendingCheck verifies the answer to the request waiting for user input
+	pendingCheck *answerCheck
+
+	firmwareProgress FirmwareProgressFunc
+	entropyAnalyzer  *entropy.Analyzer
 }
 
 // DeviceTypeFromString returns device type from string
@@ -107,19 +170,31 @@ func DeviceTypeFromString(deviceType string) DeviceType {
 	return dtRet
 }
 
-// NewDevice returns a new device instance
-func NewDevice(deviceType DeviceType) *Device {
-	driver, err := NewDriver(deviceType)
+// NewDevice returns a new device instance, options select the wallet to use
+// when several are attached, e.g. NewDevice(DeviceTypeUSB, WithLabel("signer"))
+func NewDevice(deviceType DeviceType, options ...Option) *Device {
+	driver, err := NewDriver(deviceType, options...)
 	if err != nil {
 		log.Fatalf("failed to create driver: %s", err)
 	}
 
+	return NewDeviceWithDriver(driver)
+}
+
+// NewDeviceWithDriver returns a new device instance using the given driver
+func NewDeviceWithDriver(driver DeviceDriver) *Device {
 	return &Device{
 		driver,
 		sync.Mutex{},
 		nil,
 		false,
 		ButtonType(-1),
+		nil,
+		false,
+		nil,
+		nil,
+		nil,
+		nil,
 	}
 }
 
@@ -165,10 +240,11 @@ func (d *Device) Disconnect() error {
 // GetUsbInfo returns information from the attached usb
 func (d *Device) GetUsbInfo() ([]usb.Info, error) {
 	if d.Driver.DeviceType() == DeviceTypeUSB {
-		if err := d.Connect(); err != nil {
+		s, err := d.OpenSession()
+		if err != nil {
 			return nil, err
 		}
-		if err := d.Disconnect(); err != nil {
+		if err := s.Close(); err != nil {
 			return nil, err
 		}
 	}
@@ -181,259 +257,125 @@ func (d *Device) GetUsbInfo() ([]usb.Info, error) {
 
 // AddressGen Ask the device to generate an address
 func (d *Device) AddressGen(addressN, startIndex uint32, confirmAddress bool) (wire.Message, error) {
-	if err := d.Connect(); err != nil {
-		return wire.Message{}, err
-	}
-	defer d.Disconnect()
-
-	if addressN == 0 {
-		return wire.Message{}, ErrAddressNZero
-	}
+	return d.AddressGenContext(context.Background(), addressN, startIndex, confirmAddress)
+}
 
-	addressGenChunks, err := MessageAddressGen(addressN, startIndex, confirmAddress)
+// AddressGenContext is like AddressGen but the request is cancelled if ctx is done
+func (d *Device) AddressGenContext(ctx context.Context, addressN, startIndex uint32, confirmAddress bool) (wire.Message, error) {
+	s, err := d.OpenSession()
 	if err != nil {
 		return wire.Message{}, err
 	}
+	defer s.Close()
 
-	return d.Driver.SendToDevice(d.dev, addressGenChunks)
+	return s.AddressGenContext(ctx, addressN, startIndex, confirmAddress)
 }
 
 // SaveDeviceEntropyInFile Ask the device to generate entropy and save it in a file
-// if `outFile` is the "-" string, the output file is considered stdout
+// if `outFile` is the "-" string, the raw entropy is written to stdout
 func (d *Device) SaveDeviceEntropyInFile(outFile string, entropyBytes uint32, getEntropyMsgBuilder func(entropyBytes uint32) ([][64]byte, error)) error {
-	usingStdout := false
-	if outFile == "-" {
-		usingStdout = true
-	}
-	if !usingStdout {
-		log.Infoln("Saving entropy to", outFile)
-	}
-	var receivedEntropyBytes uint32
-	var processBytes func(buf []byte) error
-	var err error
-	var processGetEntropyResponse func(msg wire.Message) (*messages.Entropy, error)
-	processGetEntropyResponse = func(msg wire.Message) (*messages.Entropy, error) {
-		if err != nil || msg.Kind != uint16(messages.MessageType_MessageType_Entropy) {
-			if err != nil {
-				return &messages.Entropy{}, err
-			}
-
-			if msg.Kind == uint16(messages.MessageType_MessageType_ButtonRequest) {
-				// Send ButtonAck
-				chunks, err := MessageButtonAck()
-				if err != nil {
-					return &messages.Entropy{}, err
-				}
-				if err = sendToDeviceNoAnswer(d.dev, chunks); err != nil {
-					return &messages.Entropy{}, err
-				}
-				// simulate button press
-				if d.simulateButtonPress {
-					if err := d.SimulateButtonPress(); err != nil {
-						return &messages.Entropy{}, err
-					}
-				}
-
-				msg, err := wire.ReadFrom(d.dev)
-				if err != nil {
-					return nil, err
-				}
-				return processGetEntropyResponse(*msg)
-			}
-			var msgStr string
-			msgStr, err = DecodeFailMsg(msg)
-			if err != nil {
-				log.Errorf("Error decoding device response as fails msg %s", err)
-				return &messages.Entropy{}, err
-			}
-			err = errors.New(msgStr)
-			log.Errorf("Error getting entropy from device %s", err)
-			return &messages.Entropy{}, err
-		}
-		entropy, err := DecodeResponseEntropyMessage(msg)
-		if err != nil {
-			log.Errorf("Error decoding device response %s", err)
-			return &messages.Entropy{}, err
-		}
-		return entropy, nil
-	}
-
-	getEntropy := func(bytes uint32) (*messages.Entropy, error) {
-		chunks, err := getEntropyMsgBuilder(bytes)
-		if err != nil {
-			return &messages.Entropy{}, err
-		}
-		resp, err := d.Driver.SendToDevice(d.dev, chunks)
-		if err != nil {
-			return &messages.Entropy{}, err
-		}
-		return processGetEntropyResponse(resp)
-	}
-
-	checkProducedFile := func() error {
-		if !usingStdout {
-			fileInfo, err := os.Stat(outFile)
-			if err != nil {
-				log.Error(err)
-				return err
-			}
-			if fileInfo.Size() != int64(entropyBytes) {
-				return fmt.Errorf(
-					"no engout bytes saved in the file %s\n current: %d\nrequired: %d",
-					outFile, fileInfo.Size(), entropyBytes)
-			}
-		}
-		return nil
-	}
-
-	if usingStdout {
-		processBytes = func(buf []byte) error {
-			fmt.Print(buf)
-			return nil
-		}
-	} else {
-		pb := Progbar{total: int(entropyBytes)}
-		defer func() {
-			if checkProducedFile() == nil {
-				pb.PrintComplete()
-			}
-		}()
-		if _, err := os.Stat(outFile); err == nil {
-			// nolint: gosec
-			if err = os.Chmod(outFile, 0777); err != nil {
-				log.Errorf("error with %s %s", outFile, err)
-			}
-		}
-		file, err := os.Create(outFile)
-		if err != nil {
-			log.Errorf("error creating output file %s", err)
-			return err
-		}
-		defer func() {
-			if err := os.Chmod(outFile, 0444); err != nil {
-				log.Error(err)
-			}
-		}()
-		defer file.Close()
-
-		processBytes = func(buf []byte) error {
-			var wroteBytes = 0
-			for wroteBytes < len(buf) {
-				var res int
-				if res, err = file.Write(buf[wroteBytes:]); err != nil {
-					return err
-				}
-				wroteBytes += res
-			}
-			if wroteBytes != len(buf) {
-				return errors.New("invalid bytes amount wrote")
-			}
-			pb.PrintProg(int(receivedEntropyBytes))
-			return nil
-		}
-	}
+	return d.SaveDeviceEntropyInFileContext(context.Background(), outFile, entropyBytes, getEntropyMsgBuilder)
+}
 
-	if err := d.Connect(); err != nil {
+// SaveDeviceEntropyInFileContext is like SaveDeviceEntropyInFile but the request is cancelled if ctx is done
+func (d *Device) SaveDeviceEntropyInFileContext(ctx context.Context, outFile string, entropyBytes uint32, getEntropyMsgBuilder func(entropyBytes uint32) ([][64]byte, error)) error {
+	s, err := d.OpenSession()
+	if err != nil {
 		return err
 	}
 
 	defer func() {
-		if err := d.Disconnect(); err != nil {
+		if err := s.Close(); err != nil {
 			log.Error(err)
 		}
 	}()
 
-	entropy, err := getEntropy(entropyBytes)
-	if err != nil {
-		log.Error(err)
-		return err
-	}
+	return s.SaveDeviceEntropyInFileContext(ctx, outFile, entropyBytes, getEntropyMsgBuilder)
+}
 
-	receivedEntropyBytes = uint32(len(entropy.GetEntropy()))
-	if err := processBytes(entropy.GetEntropy()); err != nil {
-		log.Errorf("error writing file %s.\n %s", outFile, err.Error())
+// SaveDeviceEntropy Ask the device to generate entropy and write it to w, the progress is printed to stderr
+func (d *Device) SaveDeviceEntropy(w io.Writer, entropyBytes uint32, getEntropyMsgBuilder func(entropyBytes uint32) ([][64]byte, error)) error {
+	return d.SaveDeviceEntropyContext(context.Background(), w, entropyBytes, getEntropyMsgBuilder)
+}
+
+// SaveDeviceEntropyContext is like SaveDeviceEntropy but the request is cancelled if ctx is done
+func (d *Device) SaveDeviceEntropyContext(ctx context.Context, w io.Writer, entropyBytes uint32, getEntropyMsgBuilder func(entropyBytes uint32) ([][64]byte, error)) error {
+	s, err := d.OpenSession()
+	if err != nil {
 		return err
 	}
 
-	for receivedEntropyBytes < entropyBytes {
-		entropy, err := getEntropy(entropyBytes - receivedEntropyBytes)
-		if err != nil {
+	defer func() {
+		if err := s.Close(); err != nil {
 			log.Error(err)
-			return err
-		}
-		receivedEntropyBytes += uint32(len(entropy.GetEntropy()))
-		if err := processBytes(entropy.GetEntropy()); err != nil {
-			log.Errorf("error writing file %s.\n %s", outFile, err.Error())
-			return err
 		}
-	}
-	return checkProducedFile()
+	}()
+
+	return s.SaveDeviceEntropyContext(ctx, w, entropyBytes, getEntropyMsgBuilder)
 }
 
 // ApplySettings send ApplySettings request to the device
 func (d *Device) ApplySettings(usePassphrase *bool, label string, language string) (wire.Message, error) {
-	if err := d.Connect(); err != nil {
-		return wire.Message{}, err
-	}
-	defer d.Disconnect()
+	return d.ApplySettingsContext(context.Background(), usePassphrase, label, language)
+}
 
-	applySettingsChunks, err := MessageApplySettings(usePassphrase, label, language)
+// ApplySettingsContext is like ApplySettings but the request is cancelled if ctx is done
+func (d *Device) ApplySettingsContext(ctx context.Context, usePassphrase *bool, label string, language string) (wire.Message, error) {
+	s, err := d.OpenSession()
 	if err != nil {
 		return wire.Message{}, err
 	}
+	defer s.Close()
 
-	return d.Driver.SendToDevice(d.dev, applySettingsChunks)
+	return s.ApplySettingsContext(ctx, usePassphrase, label, language)
 }
 
 // Backup ask the device to perform the seed backup
 func (d *Device) Backup() (wire.Message, error) {
-	if err := d.Connect(); err != nil {
-		return wire.Message{}, err
-	}
-	defer d.Disconnect()
-
-	backupChunks, err := MessageBackup()
-	if err != nil {
-		return wire.Message{}, err
-	}
+	return d.BackupContext(context.Background())
+}
 
-	msg, err := d.Driver.SendToDevice(d.dev, backupChunks)
+// BackupContext is like Backup but the request is cancelled if ctx is done
+func (d *Device) BackupContext(ctx context.Context) (wire.Message, error) {
+	s, err := d.OpenSession()
 	if err != nil {
 		return wire.Message{}, err
 	}
+	defer s.Close()
 
-	return msg, err
+	return s.BackupContext(ctx)
 }
 
 // Cancel sends a Cancel request
 func (d *Device) Cancel() (wire.Message, error) {
-	if err := d.Connect(); err != nil {
-		return wire.Message{}, err
-	}
-	defer d.Disconnect()
+	return d.CancelContext(context.Background())
+}
 
-	cancelChunks, err := MessageCancel()
+// CancelContext is like Cancel but the request is cancelled if ctx is done
+func (d *Device) CancelContext(ctx context.Context) (wire.Message, error) {
+	s, err := d.OpenSession()
 	if err != nil {
 		return wire.Message{}, err
 	}
+	defer s.Close()
 
-	return d.Driver.SendToDevice(d.dev, cancelChunks)
+	return s.CancelContext(ctx)
 }
 
 // CheckMessageSignature Check a message signature matches the given address.
 func (d *Device) CheckMessageSignature(message, signature, address string) (wire.Message, error) {
-	if err := d.Connect(); err != nil {
-		return wire.Message{}, err
-	}
-	defer d.Disconnect()
+	return d.CheckMessageSignatureContext(context.Background(), message, signature, address)
+}
 
-	// Send CheckMessageSignature
-	checkMessageSignatureChunks, err := MessageCheckMessageSignature(message, signature, address)
+// CheckMessageSignatureContext is like CheckMessageSignature but the request is cancelled if ctx is done
+func (d *Device) CheckMessageSignatureContext(ctx context.Context, message, signature, address string) (wire.Message, error) {
+	s, err := d.OpenSession()
 	if err != nil {
 		return wire.Message{}, err
 	}
+	defer s.Close()
 
-	return d.Driver.SendToDevice(d.dev, checkMessageSignatureChunks)
+	return s.CheckMessageSignatureContext(ctx, message, signature, address)
 }
 
 // ChangePin changes device's PIN code
@@ -453,83 +395,54 @@ func (d *Device) CheckMessageSignature(message, signature, address string) (wire
 // top, bottom-right, top-left, right, top-right
 // so you must send "83769".
 func (d *Device) ChangePin(removePin *bool) (wire.Message, error) {
-	if err := d.Connect(); err != nil {
-		return wire.Message{}, err
-	}
-	defer d.Disconnect()
-
-	if removePin == nil {
-		return wire.Message{}, ErrRemovePinNil
-	}
-
-	changePinChunks, err := MessageChangePin(removePin)
-	if err != nil {
-		return wire.Message{}, err
-	}
+	return d.ChangePinContext(context.Background(), removePin)
+}
 
-	msg, err := d.Driver.SendToDevice(d.dev, changePinChunks)
+// ChangePinContext is like ChangePin but the request is cancelled if ctx is done
+func (d *Device) ChangePinContext(ctx context.Context, removePin *bool) (wire.Message, error) {
+	s, err := d.OpenSession()
 	if err != nil {
 		return wire.Message{}, err
 	}
+	defer s.Close()
 
-	return msg, nil
+	return s.ChangePinContext(ctx, removePin)
 }
 
 // Connected checks if we can communicate with a connected skycoin wallet
 func (d *Device) Connected() bool {
-	var msg *wire.Message
-	var err error
 	if d.dev == nil {
 		return false
 	}
 
+	return connected(d.dev, d.entropySource())
+}
+
+func connected(dev usb.Device, entropySource io.Reader) bool {
 	chunks, err := MessageConnected()
 	if err != nil {
 		log.Error(err)
 		return false
 	}
 
-	for _, element := range chunks {
-		_, err = d.dev.Write(element[:])
-		if err != nil {
-			return false
-		}
-	}
-
-	msg, err = wire.ReadFrom(d.dev)
+	msg, err := sendToDevice(dev, chunks, entropySource)
 	if err != nil {
 		return false
 	}
 
-	for msg.Kind == uint16(messages.MessageType_MessageType_EntropyRequest) {
-		var wg sync.WaitGroup
-		wg.Add(1)
-
-		go func() {
-			defer wg.Done()
-			entropyChunks, err := MessageEntropyAck(entropyBufferSize)
-			if err != nil {
-				log.Errorf("failed to create entropy ack msg: %v", err)
-				return
-			}
-
-			for _, element := range entropyChunks {
-				_, err := d.dev.Write(element[:])
-				if err != nil {
-					log.Errorf("entropy ack error: %v", err)
-					return
-				}
-			}
-		}()
+	return msg.Kind == uint16(messages.MessageType_MessageType_Success)
+}
 
-		msg, err = wire.ReadFrom(d.dev)
-		if err != nil {
-			return false
-		}
-		wg.Wait()
-	}
+// SetEntropySource sets the source of the host entropy sent to the wallet when it requests some,
+// e.g. while generating a mnemonic. source may be a hardware RNG file, an audited mixer or a
+// deterministic reader in tests, by default the entropy comes from the operating system.
+func (d *Device) SetEntropySource(source io.Reader) {
+	d.Driver.SetEntropySource(source)
+}
 
-	return msg.Kind == uint16(messages.MessageType_MessageType_Success)
+// entropySource returns the host entropy source set with SetEntropySource, nil for the default one
+func (d *Device) entropySource() io.Reader {
+	return d.Driver.EntropySource()
 }
 
 // Available checks if a skycoin wallet is connected to the system
@@ -549,342 +462,317 @@ func (d *Device) Available() bool {
 
 // FirmwareUpload Updates device's firmware
 func (d *Device) FirmwareUpload(payload []byte, hash [32]byte) error {
+	return d.FirmwareUploadContext(context.Background(), payload, hash)
+}
+
+// FirmwareUploadContext is like FirmwareUpload but the request is cancelled if ctx is done
+func (d *Device) FirmwareUploadContext(ctx context.Context, payload []byte, hash [32]byte) error {
 	if d.Driver.DeviceType() != DeviceTypeUSB {
 		return ErrDeviceTypeEmulator
 	}
 
-	if err := d.Connect(); err != nil {
-		return err
-	}
-	defer d.Disconnect()
-
-	if err := Initialize(d.dev); err != nil {
-		return err
-	}
-
-	log.Printf("Length of firmware %d", uint32(len(payload)))
-
-	chunks, err := MessageFirmwareErase(payload)
+	s, err := d.OpenSession()
 	if err != nil {
 		return err
 	}
-	erasemsg, err := d.Driver.SendToDevice(d.dev, chunks)
-	if err != nil {
-		return err
-	}
-
-	switch erasemsg.Kind {
-	case uint16(messages.MessageType_MessageType_Success):
-		log.Printf("Success %d! FirmwareErase %s\n", erasemsg.Kind, erasemsg.Data)
-	case uint16(messages.MessageType_MessageType_Failure):
-		msg, err := DecodeFailMsg(erasemsg)
-		if err != nil {
-			return err
-		}
-
-		return errors.New(msg)
-	default:
-		return fmt.Errorf("received unexpected message type: %s", messages.MessageType(erasemsg.Kind))
-	}
-
-	log.Printf("Hash: %x\n", hash)
+	defer s.Close()
 
-	chunks, err = MessageFirmwareUpload(payload, hash)
-	if err != nil {
-		return err
-	}
-	uploadmsg, err := d.Driver.SendToDevice(d.dev, chunks)
-	if err != nil {
-		return err
-	}
-
-	switch uploadmsg.Kind {
-	case uint16(messages.MessageType_MessageType_ButtonRequest):
-		log.Println("Please confirm in the device if fingerprints match")
-		// Send ButtonAck
-		chunks, err = MessageButtonAck()
-		if err != nil {
-			return err
-		}
-		resp, err := d.Driver.SendToDevice(d.dev, chunks)
-		if err != nil {
-			return err
-		}
-		switch resp.Kind {
-		case uint16(messages.MessageType_MessageType_Success):
-			return nil
-		case uint16(messages.MessageType_MessageType_Failure):
-			var msgStr string
-			if msgStr, err = DecodeFailMsg(resp); err != nil {
-				return err
-			}
-			return errors.New(msgStr)
-		default:
-			return errors.New("unknown response")
-		}
-	case uint16(messages.MessageType_MessageType_Failure):
-		msg, err := DecodeFailMsg(erasemsg)
-		if err != nil {
-			return err
-		}
-
-		return errors.New(msg)
-	default:
-		return fmt.Errorf("received unexpected message type: %s", messages.MessageType(erasemsg.Kind))
-	}
+	return s.FirmwareUploadContext(ctx, payload, hash)
 }
 
 // GetFeatures send Features message to the device
 func (d *Device) GetFeatures() (wire.Message, error) {
-	if err := d.Connect(); err != nil {
-		return wire.Message{}, err
-	}
-	defer d.Disconnect()
+	return d.GetFeaturesContext(context.Background())
+}
 
-	getFeaturesChunks, err := MessageGetFeatures()
+// GetFeaturesContext is like GetFeatures but the request is cancelled if ctx is done
+func (d *Device) GetFeaturesContext(ctx context.Context) (wire.Message, error) {
+	s, err := d.OpenSession()
 	if err != nil {
 		return wire.Message{}, err
 	}
+	defer s.Close()
 
-	return d.Driver.SendToDevice(d.dev, getFeaturesChunks)
+	return s.GetFeaturesContext(ctx)
 }
 
 // GenerateMnemonic Ask the device to generate a mnemonic and configure itself with it.
 func (d *Device) GenerateMnemonic(wordCount uint32, usePassphrase bool) (wire.Message, error) {
-	if err := d.Connect(); err != nil {
-		return wire.Message{}, err
-	}
-	defer d.Disconnect()
-
-	if wordCount != 12 && wordCount != 24 {
-		return wire.Message{}, ErrInvalidWordCount
-	}
+	return d.GenerateMnemonicContext(context.Background(), wordCount, usePassphrase)
+}
 
-	generateMnemonicChunks, err := MessageGenerateMnemonic(wordCount, usePassphrase)
+// GenerateMnemonicContext is like GenerateMnemonic but the request is cancelled if ctx is done
+func (d *Device) GenerateMnemonicContext(ctx context.Context, wordCount uint32, usePassphrase bool) (wire.Message, error) {
+	s, err := d.OpenSession()
 	if err != nil {
 		return wire.Message{}, err
 	}
+	defer s.Close()
 
-	msg, err := d.Driver.SendToDevice(d.dev, generateMnemonicChunks)
-	if err != nil {
-		return msg, err
-	}
-
-	return msg, err
+	return s.GenerateMnemonicContext(ctx, wordCount, usePassphrase)
 }
 
 // Recovery ask the device to perform the seed backup
 func (d *Device) Recovery(wordCount uint32, usePassphrase *bool, dryRun bool) (wire.Message, error) {
-	if err := d.Connect(); err != nil {
-		return wire.Message{}, err
-	}
-	defer d.Disconnect()
-
-	if wordCount != 12 && wordCount != 24 {
-		return wire.Message{}, ErrInvalidWordCount
-	}
-
-	log.Printf("Using passphrase %t\n", usePassphrase)
-	recoveryChunks, err := MessageRecovery(wordCount, usePassphrase, dryRun)
-	if err != nil {
-		return wire.Message{}, err
-	}
+	return d.RecoveryContext(context.Background(), wordCount, usePassphrase, dryRun)
+}
 
-	msg, err := d.Driver.SendToDevice(d.dev, recoveryChunks)
+// RecoveryContext is like Recovery but the request is cancelled if ctx is done
+func (d *Device) RecoveryContext(ctx context.Context, wordCount uint32, usePassphrase *bool, dryRun bool) (wire.Message, error) {
+	s, err := d.OpenSession()
 	if err != nil {
 		return wire.Message{}, err
 	}
-	log.Printf("Recovery device response kind is: %d\n", msg.Kind)
+	defer s.Close()
 
-	return msg, nil
+	return s.RecoveryContext(ctx, wordCount, usePassphrase, dryRun)
 }
 
 // SetMnemonic Configure the device with a mnemonic.
 func (d *Device) SetMnemonic(mnemonic string) (wire.Message, error) {
-	if err := d.Connect(); err != nil {
-		return wire.Message{}, err
-	}
-	defer d.Disconnect()
+	return d.SetMnemonicContext(context.Background(), mnemonic)
+}
 
-	// Send SetMnemonic
-	setMnemonicChunks, err := MessageSetMnemonic(mnemonic)
-	if err != nil {
-		return wire.Message{}, err
-	}
-	msg, err := d.Driver.SendToDevice(d.dev, setMnemonicChunks)
+// SetMnemonicContext is like SetMnemonic but the request is cancelled if ctx is done
+func (d *Device) SetMnemonicContext(ctx context.Context, mnemonic string) (wire.Message, error) {
+	s, err := d.OpenSession()
 	if err != nil {
 		return wire.Message{}, err
 	}
+	defer s.Close()
 
-	return msg, err
+	return s.SetMnemonicContext(ctx, mnemonic)
 }
 
 // SignMessage Ask the device to sign a message using the secret key at given index.
 func (d *Device) SignMessage(addressIndex int, message string) (wire.Message, error) {
-	if err := d.Connect(); err != nil {
-		return wire.Message{}, err
-	}
-	defer d.Disconnect()
-
-	signMessageChunks, err := MessageSignMessage(addressIndex, message)
-	if err != nil {
-		return wire.Message{}, err
-	}
+	return d.SignMessageContext(context.Background(), addressIndex, message)
+}
 
-	msg, err := d.Driver.SendToDevice(d.dev, signMessageChunks)
+// SignMessageContext is like SignMessage but the request is cancelled if ctx is done
+func (d *Device) SignMessageContext(ctx context.Context, addressIndex int, message string) (wire.Message, error) {
+	s, err := d.OpenSession()
 	if err != nil {
 		return wire.Message{}, err
 	}
+	defer s.Close()
 
-	return msg, err
+	return s.SignMessageContext(ctx, addressIndex, message)
 }
 
 // TransactionSign Ask the device to sign a transaction using the given information.
 func (d *Device) TransactionSign(inputs []*messages.SkycoinTransactionInput, outputs []*messages.SkycoinTransactionOutput) (wire.Message, error) {
-	if err := d.Connect(); err != nil {
-		return wire.Message{}, err
-	}
-	defer d.Disconnect()
+	return d.TransactionSignContext(context.Background(), inputs, outputs)
+}
 
-	transactionSignChunks, err := MessageTransactionSign(inputs, outputs)
+// TransactionSignContext is like TransactionSign but the request is cancelled if ctx is done
+func (d *Device) TransactionSignContext(ctx context.Context, inputs []*messages.SkycoinTransactionInput, outputs []*messages.SkycoinTransactionOutput) (wire.Message, error) {
+	s, err := d.OpenSession()
 	if err != nil {
 		return wire.Message{}, err
 	}
+	defer s.Close()
 
-	return d.Driver.SendToDevice(d.dev, transactionSignChunks)
+	return s.TransactionSignContext(ctx, inputs, outputs)
 }
 
 // Wipe wipes out device configuration
 func (d *Device) Wipe() (wire.Message, error) {
-	if err := d.Connect(); err != nil {
-		return wire.Message{}, err
-	}
-	defer d.Disconnect()
-
-	wipeChunks, err := MessageWipe()
-	if err != nil {
-		return wire.Message{}, err
-	}
+	return d.WipeContext(context.Background())
+}
 
-	msg, err := d.Driver.SendToDevice(d.dev, wipeChunks)
+// WipeContext is like Wipe but the request is cancelled if ctx is done
+func (d *Device) WipeContext(ctx context.Context) (wire.Message, error) {
+	s, err := d.OpenSession()
 	if err != nil {
 		return wire.Message{}, err
 	}
+	defer s.Close()
 
-	return msg, err
+	return s.WipeContext(ctx)
 }
 
 // ButtonAck when the device is waiting for the user to press a button
 // the PC need to acknowledge, showing it knows we are waiting for a user action
 func (d *Device) ButtonAck() (wire.Message, error) {
-	if err := d.Connect(); err != nil {
+	return d.ButtonAckContext(context.Background())
+}
+
+// ButtonAckContext is like ButtonAck but the request is cancelled if ctx is done
+func (d *Device) ButtonAckContext(ctx context.Context) (wire.Message, error) {
+	s, err := d.OpenSession()
+	if err != nil {
 		return wire.Message{}, err
 	}
-	defer d.Disconnect()
+	defer s.Close()
+
+	return s.ButtonAckContext(ctx)
+}
 
-	// Send ButtonAck
-	buttonChunks, err := MessageButtonAck()
+// PassphraseAck send this message when the device is waiting for the user to input a passphrase
+func (d *Device) PassphraseAck(passphrase string) (wire.Message, error) {
+	return d.PassphraseAckContext(context.Background(), passphrase)
+}
+
+// PassphraseAckContext is like PassphraseAck but the request is cancelled if ctx is done
+func (d *Device) PassphraseAckContext(ctx context.Context, passphrase string) (wire.Message, error) {
+	s, err := d.OpenSession()
 	if err != nil {
 		return wire.Message{}, err
 	}
+	defer s.Close()
+
+	return s.PassphraseAckContext(ctx, passphrase)
+}
+
+// WordAck send a word to the device during device "recovery procedure"
+func (d *Device) WordAck(word string) (wire.Message, error) {
+	return d.WordAckContext(context.Background(), word)
+}
 
-	err = sendToDeviceNoAnswer(d.dev, buttonChunks)
+// WordAckContext is like WordAck but the request is cancelled if ctx is done
+func (d *Device) WordAckContext(ctx context.Context, word string) (wire.Message, error) {
+	s, err := d.OpenSession()
 	if err != nil {
 		return wire.Message{}, err
 	}
+	defer s.Close()
 
-	// simulate button press
-	if d.simulateButtonPress {
-		if err := d.SimulateButtonPress(); err != nil {
-			return wire.Message{}, err
-		}
-	}
+	return s.WordAckContext(ctx, word)
+}
+
+// PinMatrixAck during PIN code setting use this message to send user input to device
+func (d *Device) PinMatrixAck(p string) (wire.Message, error) {
+	return d.PinMatrixAckContext(context.Background(), p)
+}
 
-	msg, err := wire.ReadFrom(d.dev)
+// PinMatrixAckContext is like PinMatrixAck but the request is cancelled if ctx is done
+func (d *Device) PinMatrixAckContext(ctx context.Context, p string) (wire.Message, error) {
+	time.Sleep(1 * time.Second)
+	s, err := d.OpenSession()
 	if err != nil {
 		return wire.Message{}, err
 	}
-	for msg.Kind == uint16(messages.MessageType_MessageType_EntropyRequest) {
-		var wg sync.WaitGroup
-		wg.Add(1)
+	defer s.Close()
 
-		go func() {
-			defer wg.Done()
-			entropyChunks, err := MessageEntropyAck(entropyBufferSize)
-			if err != nil {
-				log.Errorf("failed to create entropy ack msg: %v", err)
-				return
-			}
+	return s.PinMatrixAckContext(ctx, p)
+}
 
-			for _, element := range entropyChunks {
-				_, err := d.dev.Write(element[:])
-				if err != nil {
-					log.Errorf("entropy ack error: %v", err)
-					return
-				}
-			}
-		}()
+// SetPrompter sets the Prompter used by GetAddresses, SignTransaction, GetMessageSignature
+// and Features to answer the device requests
+func (d *Device) SetPrompter(prompter Prompter) {
+	d.prompter = prompter
+}
 
-		msg, err = wire.ReadFrom(d.dev)
-		if err != nil {
-			return wire.Message{}, err
-		}
-		wg.Wait()
-	}
+// SetVerify enables the host side verification of the answers to AddressGen, SignMessage
+// and TransactionSign, including through GetAddresses, GetMessageSignature and SignTransaction.
+// Addresses are checked against SetExpectedAddresses and signatures against the addresses
+// owning the signing keys, which are requested from the device if they were not set.
+func (d *Device) SetVerify(verify bool) {
+	d.verify = verify
+}
 
-	return *msg, err
+// SetExpectedAddresses sets the addresses of the wallet from index 0 that the verification
+// enabled by SetVerify trusts, e.g. the addresses of a watch-only wallet or of an AddressCache
+func (d *Device) SetExpectedAddresses(addresses []cipher.Address) {
+	d.expectedAddresses = addresses
 }
 
-// PassphraseAck send this message when the device is waiting for the user to input a passphrase
-func (d *Device) PassphraseAck(passphrase string) (wire.Message, error) {
-	if err := d.Connect(); err != nil {
-		return wire.Message{}, err
-	}
-	defer d.Disconnect()
+// SetFirmwareProgress sets the function receiving the progress events of FirmwareUpload
+func (d *Device) SetFirmwareProgress(progress FirmwareProgressFunc) {
+	d.firmwareProgress = progress
+}
 
-	passphraseChunks, err := MessagePassphraseAck(passphrase)
+// SetEntropyAnalyzer sets the analyzer written the entropy received by SaveDeviceEntropyInFile
+func (d *Device) SetEntropyAnalyzer(analyzer *entropy.Analyzer) {
+	d.entropyAnalyzer = analyzer
+}
+
+// GetAddresses Ask the device to generate addresses
+func (d *Device) GetAddresses(addressN, startIndex uint32, confirmAddress bool) ([]cipher.Address, error) {
+	return d.GetAddressesContext(context.Background(), addressN, startIndex, confirmAddress)
+}
+
+// GetAddressesContext is like GetAddresses but the request is cancelled if ctx is done
+func (d *Device) GetAddressesContext(ctx context.Context, addressN, startIndex uint32, confirmAddress bool) ([]cipher.Address, error) {
+	s, err := d.OpenSession()
 	if err != nil {
-		return wire.Message{}, err
+		return nil, err
 	}
+	defer s.Close()
 
-	return d.Driver.SendToDevice(d.dev, passphraseChunks)
+	return s.GetAddressesContext(ctx, addressN, startIndex, confirmAddress)
 }
 
-// WordAck send a word to the device during device "recovery procedure"
-func (d *Device) WordAck(word string) (wire.Message, error) {
-	if err := d.Connect(); err != nil {
-		return wire.Message{}, err
+// SignTransaction Ask the device to sign a transaction using the given information
+func (d *Device) SignTransaction(inputs []*messages.SkycoinTransactionInput, outputs []*messages.SkycoinTransactionOutput) ([]cipher.Sig, error) {
+	return d.SignTransactionContext(context.Background(), inputs, outputs)
+}
+
+// SignTransactionContext is like SignTransaction but the request is cancelled if ctx is done
+func (d *Device) SignTransactionContext(ctx context.Context, inputs []*messages.SkycoinTransactionInput, outputs []*messages.SkycoinTransactionOutput) ([]cipher.Sig, error) {
+	s, err := d.OpenSession()
+	if err != nil {
+		return nil, err
 	}
-	defer d.Disconnect()
+	defer s.Close()
+
+	return s.SignTransactionContext(ctx, inputs, outputs)
+}
+
+// GetMessageSignature Ask the device to sign a message using the secret key at given index
+func (d *Device) GetMessageSignature(addressIndex int, message string) (cipher.Sig, error) {
+	return d.GetMessageSignatureContext(context.Background(), addressIndex, message)
+}
 
-	wordAckChunks, err := MessageWordAck(word)
+// GetMessageSignatureContext is like GetMessageSignature but the request is cancelled if ctx is done
+func (d *Device) GetMessageSignatureContext(ctx context.Context, addressIndex int, message string) (cipher.Sig, error) {
+	s, err := d.OpenSession()
 	if err != nil {
-		return wire.Message{}, err
+		return cipher.Sig{}, err
 	}
+	defer s.Close()
 
-	return d.Driver.SendToDevice(d.dev, wordAckChunks)
+	return s.GetMessageSignatureContext(ctx, addressIndex, message)
 }
 
-// PinMatrixAck during PIN code setting use this message to send user input to device
-func (d *Device) PinMatrixAck(p string) (wire.Message, error) {
-	time.Sleep(1 * time.Second)
-	if err := d.Connect(); err != nil {
-		return wire.Message{}, err
+// Features returns the device features
+func (d *Device) Features() (*messages.Features, error) {
+	return d.FeaturesContext(context.Background())
+}
+
+// FeaturesContext is like Features but the request is cancelled if ctx is done
+func (d *Device) FeaturesContext(ctx context.Context) (*messages.Features, error) {
+	s, err := d.OpenSession()
+	if err != nil {
+		return nil, err
 	}
-	defer d.Disconnect()
+	defer s.Close()
+
+	return s.FeaturesContext(ctx)
+}
 
-	log.Printf("Setting pin: %s\n", p)
+// Mode returns the mode the device is running in
+func (d *Device) Mode() (DeviceMode, error) {
+	return d.ModeContext(context.Background())
+}
 
-	pinMatrixChunks, err := MessagePinMatrixAck(p)
+// ModeContext is like Mode but the request is cancelled if ctx is done
+func (d *Device) ModeContext(ctx context.Context) (DeviceMode, error) {
+	features, err := d.FeaturesContext(ctx)
 	if err != nil {
-		return wire.Message{}, err
+		return DeviceModeUnknown, err
 	}
 
-	return d.Driver.SendToDevice(d.dev, pinMatrixChunks)
+	return FeaturesMode(features), nil
 }
 
 // SimulateButtonPress simulates a button press on emulator
 func (d *Device) SimulateButtonPress() error {
+	return d.simulateButtonPressOn(d.dev)
+}
+
+func (d *Device) simulateButtonPressOn(dev usb.Device) error {
 	if d.Driver.DeviceType() != DeviceTypeEmulator {
 		return fmt.Errorf("wrong device type: %s", d.Driver.DeviceType())
 	}
@@ -894,7 +782,7 @@ func (d *Device) SimulateButtonPress() error {
 		return err
 	}
 
-	_, err = d.dev.Write(simulateMsg.Bytes())
+	_, err = dev.Write(simulateMsg.Bytes())
 	if err != nil {
 		return err
 	}
@@ -2,11 +2,17 @@ package skywallet
 
 import (
 	"bytes"
+	"encoding/binary"
+	"errors"
 	"sync"
 	"testing"
+	"time"
+
+	"github.com/gogo/protobuf/proto"
 
 	messages "github.com/skycoin/hardware-wallet-protob/go"
 
+	"github.com/skycoin/hardware-wallet-go/src/skywallet/firmware"
 	"github.com/skycoin/hardware-wallet-go/src/skywallet/wire"
 
 	"github.com/stretchr/testify/require"
@@ -137,8 +143,13 @@ func (suite *devicerSuit) TestCancel() {
 	// NOTE(denisacostaq@gmail.com): Giving
 	driverMock := &MockDeviceDriver{}
 	driverMock.On("GetDevice").Return(&testHelperCloseableBuffer{}, nil)
+	data, err := proto.Marshal(&messages.Failure{
+		Code:    messages.FailureType_Failure_ActionCancelled.Enum(),
+		Message: proto.String("Action cancelled by user"),
+	})
+	suite.Nil(err)
 	driverMock.On("SendToDevice", mock.Anything, mock.Anything).Return(
-		wire.Message{Kind: uint16(messages.MessageType_MessageType_Success), Data: nil}, nil)
+		wire.Message{Kind: uint16(messages.MessageType_MessageType_Failure), Data: data}, nil)
 	device := getMockDevice(driverMock)
 
 	// NOTE(denisacostaq@gmail.com): When
@@ -149,7 +160,10 @@ func (suite *devicerSuit) TestCancel() {
 	driverMock.AssertCalled(suite.T(), "GetDevice")
 	driverMock.AssertNumberOfCalls(suite.T(), "SendToDevice", 1)
 	mock.AssertExpectationsForObjects(suite.T(), driverMock)
-	require.Equal(suite.T(), msg.Kind, uint16(messages.MessageType_MessageType_Success))
+	require.Equal(suite.T(), msg.Kind, uint16(messages.MessageType_MessageType_Failure))
+	message, err := DecodeFailMsg(msg)
+	suite.Nil(err)
+	suite.Equal("Action cancelled by user", message)
 }
 
 func (suite *devicerSuit) TestCheckMessageSignature() {
@@ -197,6 +211,143 @@ func (suite *devicerSuit) TestFirmwareUpload() {
 	driverMock.AssertCalled(suite.T(), "DeviceType")
 }
 
+// testHelperReplyDevice answers every read with reply
+type testHelperReplyDevice struct {
+	reply [][64]byte
+	read  int
+}
+
+func (d *testHelperReplyDevice) Read(p []byte) (n int, err error) {
+	n = copy(p, d.reply[d.read%len(d.reply)][:])
+	d.read++
+	return n, nil
+}
+func (d *testHelperReplyDevice) Write(p []byte) (n int, err error) {
+	return len(p), nil
+}
+func (d *testHelperReplyDevice) Close(disconnect bool) error {
+	return nil
+}
+
+func (suite *devicerSuit) TestFirmwareUploadProgress() {
+	driverMock := &MockDeviceDriver{}
+	driverMock.On("GetDevice").Return(&testHelperReplyDevice{
+		reply: makeSkyWalletMessage(nil, messages.MessageType_MessageType_Success),
+	}, nil)
+	driverMock.On("DeviceType").Return(DeviceTypeUSB)
+	driverMock.On("EntropySource").Return(nil)
+	driverMock.On("SendToDeviceNoAnswer", mock.Anything, mock.Anything).Return(nil)
+	driverMock.On("SendToDevice", mock.Anything, mock.Anything).Return(
+		wire.Message{Kind: uint16(messages.MessageType_MessageType_Success)}, nil).Once()
+	driverMock.On("SendToDevice", mock.Anything, mock.Anything).Return(
+		wire.Message{Kind: uint16(messages.MessageType_MessageType_ButtonRequest)}, nil).Once()
+	driverMock.On("SendToDevice", mock.Anything, mock.Anything).Return(
+		wire.Message{Kind: uint16(messages.MessageType_MessageType_Success)}, nil).Once()
+	device := getMockDevice(driverMock)
+
+	var events []FirmwareProgress
+	device.SetFirmwareProgress(func(p FirmwareProgress) {
+		events = append(events, p)
+	})
+
+	payload := make([]byte, 10000)
+	suite.Nil(device.FirmwareUpload(payload, [32]byte{}))
+
+	suite.Equal(FirmwareStageErase, events[0].Stage)
+	suite.Equal(FirmwareProgress{Stage: FirmwareStageTransfer, Total: len(payload)}, events[1])
+	transfer := events[1 : len(events)-2]
+	suite.True(len(transfer) > 2)
+	for i := 1; i < len(transfer); i++ {
+		suite.Equal(FirmwareStageTransfer, transfer[i].Stage)
+		suite.True(transfer[i].Written > transfer[i-1].Written)
+	}
+	suite.Equal(len(payload), transfer[len(transfer)-1].Written)
+	suite.Equal(FirmwareStageConfirm, events[len(events)-2].Stage)
+	suite.Equal(FirmwareStageReboot, events[len(events)-1].Stage)
+
+	// a failure reports the stage the upload stopped at
+	driverMock.On("SendToDevice", mock.Anything, mock.Anything).Return(
+		wire.Message{Kind: uint16(messages.MessageType_MessageType_Features)}, nil).Once()
+	err := device.FirmwareUpload(payload, [32]byte{})
+	var uploadErr *FirmwareUploadError
+	suite.True(errors.As(err, &uploadErr))
+	suite.Equal(FirmwareStageErase, uploadErr.Stage)
+}
+
+func testHelperFeaturesMessage(features *messages.Features) wire.Message {
+	data, err := proto.Marshal(features)
+	if err != nil {
+		panic(err)
+	}
+	return wire.Message{Kind: uint16(messages.MessageType_MessageType_Features), Data: data}
+}
+
+func (suite *devicerSuit) TestReflash() {
+	defer func(interval time.Duration) {
+		modePollInterval = interval
+	}(modePollInterval)
+	modePollInterval = time.Millisecond
+
+	data := make([]byte, firmware.HeaderSize+1000)
+	copy(data, firmware.Magic)
+	binary.LittleEndian.PutUint32(data[0x04:], 1000)
+	img, err := firmware.Parse(data)
+	suite.Nil(err)
+
+	firmwareMode := testHelperFeaturesMessage(&messages.Features{BootloaderMode: proto.Bool(false)})
+	bootloaderMode := testHelperFeaturesMessage(&messages.Features{BootloaderMode: proto.Bool(true)})
+	dev := &testHelperReplyDevice{
+		reply: makeSkyWalletMessage(nil, messages.MessageType_MessageType_Success),
+	}
+
+	driverMock := &MockDeviceDriver{}
+	driverMock.On("DeviceType").Return(DeviceTypeUSB)
+	driverMock.On("EntropySource").Return(nil)
+	// the device is unplugged after answering in firmware mode, then plugged in bootloader mode
+	driverMock.On("GetDevice").Return(dev, nil).Once()
+	driverMock.On("GetDevice").Return(nil, ErrNoDeviceConnected).Once()
+	driverMock.On("GetDevice").Return(dev, nil)
+	driverMock.On("SendToDevice", mock.Anything, mock.Anything).Return(firmwareMode, nil).Once()
+	driverMock.On("SendToDevice", mock.Anything, mock.Anything).Return(bootloaderMode, nil).Once()
+	device := getMockDevice(driverMock)
+
+	var stages []FirmwareStage
+	device.SetFirmwareProgress(func(p FirmwareProgress) {
+		if len(stages) == 0 || stages[len(stages)-1] != p.Stage {
+			stages = append(stages, p.Stage)
+		}
+	})
+
+	_, err = device.Reflash(img, firmware.Policy{})
+	suite.Equal(firmware.ErrUnsigned, err)
+	suite.Equal([]FirmwareStage{FirmwareStageReplug}, stages)
+
+	// a device in bootloader mode is flashed right away
+	stages = nil
+	for _, msg := range []wire.Message{
+		bootloaderMode,
+		{Kind: uint16(messages.MessageType_MessageType_Success)},
+		{Kind: uint16(messages.MessageType_MessageType_ButtonRequest)},
+		{Kind: uint16(messages.MessageType_MessageType_Success)},
+		// the device has not restarted yet
+		bootloaderMode,
+		firmwareMode,
+	} {
+		driverMock.On("SendToDevice", mock.Anything, mock.Anything).Return(msg, nil).Once()
+	}
+	warnings, err := device.Reflash(img, firmware.Policy{AllowUnsigned: true})
+	suite.Nil(err)
+	suite.Equal([]string{"firmware image is not signed"}, warnings)
+	suite.Equal([]FirmwareStage{
+		FirmwareStageErase,
+		FirmwareStageTransfer,
+		FirmwareStageConfirm,
+		FirmwareStageReboot,
+		FirmwareStageReconnect,
+	}, stages)
+	mock.AssertExpectationsForObjects(suite.T(), driverMock)
+}
+
 func (suite *devicerSuit) TestGenerateMnemonic() {
 	driverMock := &MockDeviceDriver{}
 	driverMock.On("GetDevice").Return(&testHelperCloseableBuffer{}, nil)
@@ -369,5 +520,89 @@ func (suite *devicerSuit) TestWipe() {
 }
 
 func getMockDevice(mock *MockDeviceDriver) Device {
-	return Device{mock, sync.Mutex{}, nil, false, ButtonType(-1)}
+	return Device{mock, sync.Mutex{}, nil, false, ButtonType(-1), nil, false, nil, nil, nil, nil}
+}
+
+func (suite *devicerSuit) TestSession() {
+	driverMock := &MockDeviceDriver{}
+	driverMock.On("GetDevice").Return(&testHelperCloseableBuffer{}, nil)
+	driverMock.On("SendToDevice", mock.Anything, mock.Anything).Return(
+		wire.Message{Kind: uint16(messages.MessageType_MessageType_Success), Data: nil}, nil)
+	device := getMockDevice(driverMock)
+
+	s, err := device.OpenSession()
+	suite.Nil(err)
+
+	_, err = s.GetFeatures()
+	suite.Nil(err)
+	_, err = s.AddressGen(1, 0, false)
+	suite.Nil(err)
+	_, err = s.AddressGen(0, 0, false)
+	suite.Equal(ErrAddressNZero, err)
+
+	suite.Nil(s.Close())
+	suite.Equal(ErrSessionClosed, s.Close())
+	_, err = s.GetFeatures()
+	suite.Equal(ErrSessionClosed, err)
+
+	driverMock.AssertNumberOfCalls(suite.T(), "GetDevice", 1)
+	driverMock.AssertNumberOfCalls(suite.T(), "SendToDevice", 2)
+
+	// the device is released once the session is closed
+	_, err = device.Wipe()
+	suite.Nil(err)
+	driverMock.AssertNumberOfCalls(suite.T(), "GetDevice", 2)
+}
+
+func (suite *devicerSuit) TestFailureError() {
+	data, err := proto.Marshal(&messages.Failure{
+		Code:    messages.FailureType_Failure_PinInvalid.Enum(),
+		Message: proto.String("PIN invalid"),
+	})
+	suite.Nil(err)
+
+	failure, err := DecodeFailureError(wire.Message{Kind: uint16(messages.MessageType_MessageType_Failure), Data: data})
+	suite.Nil(err)
+	suite.Equal("PIN invalid", failure.Error())
+	suite.True(errors.Is(failure, ErrPinInvalid))
+	suite.False(errors.Is(failure, ErrPinMismatch))
+
+	err = expectMessage(wire.Message{Kind: uint16(messages.MessageType_MessageType_Failure), Data: data}, messages.MessageType_MessageType_Success)
+	suite.True(errors.Is(err, ErrPinInvalid))
+	suite.Nil(expectMessage(wire.Message{Kind: uint16(messages.MessageType_MessageType_Success)}, messages.MessageType_MessageType_Success))
+	suite.NotNil(expectMessage(wire.Message{Kind: uint16(messages.MessageType_MessageType_Success)}, messages.MessageType_MessageType_Features))
+
+	_, err = DecodeFailureError(wire.Message{Kind: uint16(messages.MessageType_MessageType_Success)})
+	suite.NotNil(err)
+}
+
+func (suite *devicerSuit) TestDecodeMessage() {
+	// host messages have the key of their first field replaced by makeSkyWalletMessage
+	chunks, err := MessageGenerateMnemonic(24, true)
+	suite.Nil(err)
+	var buf bytes.Buffer
+	for _, chunk := range chunks {
+		buf.Write(chunk[:])
+	}
+	msg, err := wire.ReadFrom(&buf)
+	suite.Nil(err)
+
+	m, err := DecodeMessage(*msg)
+	suite.Nil(err)
+	generateMnemonic, ok := m.(*messages.GenerateMnemonic)
+	suite.True(ok)
+	suite.Equal(uint32(24), generateMnemonic.GetWordCount())
+	suite.True(generateMnemonic.GetPassphraseProtection())
+
+	data, err := proto.Marshal(&messages.Success{Message: proto.String("done")})
+	suite.Nil(err)
+	m, err = DecodeMessage(wire.Message{Kind: uint16(messages.MessageType_MessageType_Success), Data: data})
+	suite.Nil(err)
+	suite.Equal("done", m.(*messages.Success).GetMessage())
+
+	_, err = DecodeMessage(wire.Message{Kind: 0xffff})
+	suite.NotNil(err)
+
+	suite.Equal([]string{"pin"}, SecretFields(messages.MessageType_MessageType_PinMatrixAck))
+	suite.Empty(SecretFields(messages.MessageType_MessageType_Success))
 }
@@ -0,0 +1,275 @@
+/*
+Package transaction implements the Skycoin transaction binary encoding so that
+transactions can be read, signed by the device and written back on the host.
+*/
+package transaction
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/skycoin/skycoin/src/cipher"
+
+	messages "github.com/skycoin/hardware-wallet-protob/go"
+)
+
+const (
+	// headerSize is the size of the Length, Type and InnerHash fields
+	headerSize = 4 + 1 + 32
+	sigSize    = 65
+	hashSize   = 32
+	outputSize = 1 + 20 + 8 + 8
+
+	// maxItems bounds the number of signatures, inputs and outputs decoded
+	maxItems = 1 << 16
+)
+
+var (
+	// ErrInvalidLength is returned if the transaction length prefix does not match its encoding
+	ErrInvalidLength = errors.New("transaction length does not match its encoding")
+	// ErrTooManyItems is returned if a transaction has too many signatures, inputs or outputs
+	ErrTooManyItems = errors.New("too many transaction items")
+	// ErrSignatureCount is returned if the number of signatures does not match the number of inputs
+	ErrSignatureCount = errors.New("number of signatures does not match the number of inputs")
+)
+
+// Output is a transaction output
+type Output struct {
+	Address cipher.Address
+	Coins   uint64
+	Hours   uint64
+}
+
+// Transaction is a Skycoin transaction
+type Transaction struct {
+	Length    uint32
+	Type      uint8
+	InnerHash cipher.SHA256
+	Sigs      []cipher.Sig
+	In        []cipher.SHA256
+	Out       []Output
+}
+
+// FromMessages returns the unsigned transaction made of the inputs and outputs of a TransactionSign message
+func FromMessages(inputs []*messages.SkycoinTransactionInput, outputs []*messages.SkycoinTransactionOutput) (*Transaction, error) {
+	t := &Transaction{
+		In:  make([]cipher.SHA256, len(inputs)),
+		Out: make([]Output, len(outputs)),
+	}
+
+	for i, in := range inputs {
+		hash, err := cipher.SHA256FromHex(in.GetHashIn())
+		if err != nil {
+			return nil, fmt.Errorf("invalid input hash %q: %v", in.GetHashIn(), err)
+		}
+		t.In[i] = hash
+	}
+
+	for i, out := range outputs {
+		address, err := cipher.DecodeBase58Address(out.GetAddress())
+		if err != nil {
+			return nil, fmt.Errorf("invalid output address %q: %v", out.GetAddress(), err)
+		}
+		t.Out[i] = Output{
+			Address: address,
+			Coins:   out.GetCoin(),
+			Hours:   out.GetHour(),
+		}
+	}
+
+	t.UpdateHeader()
+	return t, nil
+}
+
+// Size returns the size of the encoded transaction
+func (t *Transaction) Size() int {
+	return headerSize + 4 + len(t.Sigs)*sigSize + 4 + len(t.In)*hashSize + 4 + len(t.Out)*outputSize
+}
+
+// HashInner returns the hash of the inputs and outputs, the hash signed for every input
+func (t *Transaction) HashInner() cipher.SHA256 {
+	var buf bytes.Buffer
+	t.writeInputs(&buf)
+	t.writeOutputs(&buf)
+	return cipher.SumSHA256(buf.Bytes())
+}
+
+// SignedHash returns the hash signed for the input i, InnerHash must be up to date
+func (t *Transaction) SignedHash(i int) cipher.SHA256 {
+	return cipher.AddSHA256(t.InnerHash, t.In[i])
+}
+
+// Hash returns the transaction id
+func (t *Transaction) Hash() cipher.SHA256 {
+	return cipher.SumSHA256(t.Serialize())
+}
+
+// UpdateHeader sets the Length and InnerHash fields from the transaction contents
+func (t *Transaction) UpdateHeader() {
+	t.Length = uint32(t.Size())
+	t.InnerHash = t.HashInner()
+}
+
+// SetSignatures sets a signature per input and updates the header
+func (t *Transaction) SetSignatures(sigs []cipher.Sig) error {
+	if len(sigs) != len(t.In) {
+		return ErrSignatureCount
+	}
+
+	t.Sigs = append([]cipher.Sig(nil), sigs...)
+	t.UpdateHeader()
+	return nil
+}
+
+// Serialize encodes the transaction
+func (t *Transaction) Serialize() []byte {
+	var buf bytes.Buffer
+	buf.Grow(t.Size())
+
+	writeUint32(&buf, t.Length)
+	buf.WriteByte(t.Type)
+	buf.Write(t.InnerHash[:])
+
+	writeUint32(&buf, uint32(len(t.Sigs)))
+	for _, sig := range t.Sigs {
+		buf.Write(sig[:])
+	}
+
+	t.writeInputs(&buf)
+	t.writeOutputs(&buf)
+
+	return buf.Bytes()
+}
+
+// SerializeHex encodes the transaction as a hex string
+func (t *Transaction) SerializeHex() string {
+	return hex.EncodeToString(t.Serialize())
+}
+
+func (t *Transaction) writeInputs(buf *bytes.Buffer) {
+	writeUint32(buf, uint32(len(t.In)))
+	for _, in := range t.In {
+		buf.Write(in[:])
+	}
+}
+
+func (t *Transaction) writeOutputs(buf *bytes.Buffer) {
+	writeUint32(buf, uint32(len(t.Out)))
+	for _, out := range t.Out {
+		buf.WriteByte(out.Address.Version)
+		buf.Write(out.Address.Key[:])
+		writeUint64(buf, out.Coins)
+		writeUint64(buf, out.Hours)
+	}
+}
+
+// Deserialize decodes a transaction
+func Deserialize(b []byte) (*Transaction, error) {
+	r := bytes.NewReader(b)
+	var t Transaction
+
+	if err := binary.Read(r, binary.LittleEndian, &t.Length); err != nil {
+		return nil, decodeError(err)
+	}
+	if int(t.Length) != len(b) {
+		return nil, ErrInvalidLength
+	}
+	if err := binary.Read(r, binary.LittleEndian, &t.Type); err != nil {
+		return nil, decodeError(err)
+	}
+	if _, err := io.ReadFull(r, t.InnerHash[:]); err != nil {
+		return nil, decodeError(err)
+	}
+
+	n, err := readCount(r)
+	if err != nil {
+		return nil, err
+	}
+	t.Sigs = make([]cipher.Sig, n)
+	for i := range t.Sigs {
+		if _, err := io.ReadFull(r, t.Sigs[i][:]); err != nil {
+			return nil, decodeError(err)
+		}
+	}
+
+	n, err = readCount(r)
+	if err != nil {
+		return nil, err
+	}
+	t.In = make([]cipher.SHA256, n)
+	for i := range t.In {
+		if _, err := io.ReadFull(r, t.In[i][:]); err != nil {
+			return nil, decodeError(err)
+		}
+	}
+
+	n, err = readCount(r)
+	if err != nil {
+		return nil, err
+	}
+	t.Out = make([]Output, n)
+	for i := range t.Out {
+		out := &t.Out[i]
+		if err := binary.Read(r, binary.LittleEndian, &out.Address.Version); err != nil {
+			return nil, decodeError(err)
+		}
+		if _, err := io.ReadFull(r, out.Address.Key[:]); err != nil {
+			return nil, decodeError(err)
+		}
+		if err := binary.Read(r, binary.LittleEndian, &out.Coins); err != nil {
+			return nil, decodeError(err)
+		}
+		if err := binary.Read(r, binary.LittleEndian, &out.Hours); err != nil {
+			return nil, decodeError(err)
+		}
+	}
+
+	if r.Len() != 0 {
+		return nil, ErrInvalidLength
+	}
+
+	return &t, nil
+}
+
+// DeserializeHex decodes a hex encoded transaction
+func DeserializeHex(s string) (*Transaction, error) {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	return Deserialize(b)
+}
+
+func readCount(r *bytes.Reader) (int, error) {
+	var n uint32
+	if err := binary.Read(r, binary.LittleEndian, &n); err != nil {
+		return 0, decodeError(err)
+	}
+	if n > maxItems {
+		return 0, ErrTooManyItems
+	}
+	return int(n), nil
+}
+
+func decodeError(err error) error {
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return ErrInvalidLength
+	}
+	return fmt.Errorf("failed to decode transaction: %v", err)
+}
+
+func writeUint32(buf *bytes.Buffer, v uint32) {
+	var b [4]byte
+	binary.LittleEndian.PutUint32(b[:], v)
+	buf.Write(b[:])
+}
+
+func writeUint64(buf *bytes.Buffer, v uint64) {
+	var b [8]byte
+	binary.LittleEndian.PutUint64(b[:], v)
+	buf.Write(b[:])
+}
@@ -0,0 +1,89 @@
+package transaction
+
+import (
+	"testing"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/stretchr/testify/require"
+
+	messages "github.com/skycoin/hardware-wallet-protob/go"
+)
+
+func testTransaction() *Transaction {
+	tx := &Transaction{
+		In: []cipher.SHA256{
+			cipher.MustSHA256FromHex("181bd5656115172fe81451fae4fb56498a97744d89702e73da75ba91ed5200f9"),
+		},
+		Out: []Output{
+			{
+				Address: cipher.MustDecodeBase58Address("K9TzLrgqz7uXn3QJHGxmzdRByAzH33J2ot"),
+				Coins:   100000,
+				Hours:   2,
+			},
+		},
+		Sigs: make([]cipher.Sig, 1),
+	}
+	tx.UpdateHeader()
+	return tx
+}
+
+func TestHashInner(t *testing.T) {
+	tx := testTransaction()
+
+	// hash signed by the device for the first input
+	hash := cipher.AddSHA256(tx.HashInner(), tx.In[0])
+	require.Equal(t, "d11c62b1e0e9abf629b1f5f4699cef9fbc504b45ceedf0047ead686979498218", hash.Hex())
+}
+
+func TestFromMessages(t *testing.T) {
+	tx, err := FromMessages([]*messages.SkycoinTransactionInput{
+		{
+			HashIn: proto.String("181bd5656115172fe81451fae4fb56498a97744d89702e73da75ba91ed5200f9"),
+			Index:  proto.Uint32(0),
+		},
+	}, []*messages.SkycoinTransactionOutput{
+		{
+			Address: proto.String("K9TzLrgqz7uXn3QJHGxmzdRByAzH33J2ot"),
+			Coin:    proto.Uint64(100000),
+			Hour:    proto.Uint64(2),
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, testTransaction().InnerHash, tx.InnerHash)
+	require.Equal(t, "d11c62b1e0e9abf629b1f5f4699cef9fbc504b45ceedf0047ead686979498218", tx.SignedHash(0).Hex())
+
+	_, err = FromMessages([]*messages.SkycoinTransactionInput{{HashIn: proto.String("00")}}, nil)
+	require.Error(t, err)
+}
+
+func TestSerialize(t *testing.T) {
+	tx := testTransaction()
+	require.Equal(t, int(tx.Length), len(tx.Serialize()))
+
+	decoded, err := DeserializeHex(tx.SerializeHex())
+	require.NoError(t, err)
+	require.Equal(t, tx, decoded)
+	require.Equal(t, tx.Hash(), decoded.Hash())
+
+	b := tx.Serialize()
+	_, err = Deserialize(b[:len(b)-1])
+	require.Equal(t, ErrInvalidLength, err)
+
+	_, err = Deserialize(append(b, 0))
+	require.Equal(t, ErrInvalidLength, err)
+}
+
+func TestSetSignatures(t *testing.T) {
+	tx := testTransaction()
+	unsigned := tx.Hash()
+
+	require.Equal(t, ErrSignatureCount, tx.SetSignatures(nil))
+
+	var sig cipher.Sig
+	sig[0] = 1
+	require.NoError(t, tx.SetSignatures([]cipher.Sig{sig}))
+	require.Equal(t, sig, tx.Sigs[0])
+	require.NotEqual(t, unsigned, tx.Hash())
+	require.Equal(t, tx.HashInner(), tx.InnerHash)
+}
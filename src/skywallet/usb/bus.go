@@ -44,6 +44,15 @@ type Info struct {
 	Type      DeviceType
 }
 
+// Bootloader reports whether the usb ids tell that the device runs its bootloader
+func (i Info) Bootloader() bool {
+	switch i.Type {
+	case TypeT1WebusbBoot, TypeT2Boot:
+		return true
+	}
+	return i.VendorID == VendorT1 && i.ProductID == ProductT1Bootloader
+}
+
 type Device interface {
 	io.ReadWriter
 	Close(disconnected bool) error
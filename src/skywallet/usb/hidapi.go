@@ -74,7 +74,7 @@ func (b *HIDAPI) Connect(path string) (Device, error) {
 func (b *HIDAPI) match(d *lowlevel.HidDeviceInfo) bool {
 	vid := d.VendorID
 	pid := d.ProductID
-	wallet1 := vid == VendorT1 && (pid == ProductT1Firmware)
+	wallet1 := vid == VendorT1 && (pid == ProductT1Firmware || pid == ProductT1Bootloader)
 	wallet2 := vid == VendorT2 && (pid == ProductT2Firmware || pid == ProductT2Bootloader)
 	return (wallet1 || wallet2) && (d.Interface == int(normalIface.number) || d.UsagePage == hidUsagePage)
 }
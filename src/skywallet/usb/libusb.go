@@ -268,6 +268,10 @@ func (b *LibUSB) connect(dev lowlevel.Device) (*LibUSBDevice, error) {
 }
 
 func matchType(dd *lowlevel.Device_Descriptor) DeviceType {
+	if dd.IdVendor == VendorT1 && dd.IdProduct == ProductT1Bootloader {
+		return TypeT1WebusbBoot
+	}
+
 	if dd.IdProduct == ProductT1Firmware {
 		// this is HID, in platforms where we don't use hidapi (linux, bsd)
 		return TypeT1Hid
@@ -333,7 +337,7 @@ func (b *LibUSB) matchVidPid(vid uint16, pid uint16) bool {
 	trezor2 := vid == VendorT2 && (pid == ProductT2Firmware || pid == ProductT2Bootloader)
 
 	if b.only {
-		trezor1 := vid == VendorT1 && (pid == ProductT1Firmware)
+		trezor1 := vid == VendorT1 && (pid == ProductT1Firmware || pid == ProductT1Bootloader)
 		return trezor1 || trezor2
 	}
 
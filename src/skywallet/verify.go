@@ -0,0 +1,226 @@
+package skywallet
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/skycoin/skycoin/src/cipher"
+
+	messages "github.com/skycoin/hardware-wallet-protob/go"
+
+	"github.com/skycoin/hardware-wallet-go/src/skywallet/transaction"
+	"github.com/skycoin/hardware-wallet-go/src/skywallet/wire"
+)
+
+var (
+	// ErrSignatureCount is returned if the device did not return a signature per input
+	ErrSignatureCount = transaction.ErrSignatureCount
+	// ErrUnexpectedAddress is returned if the device returned another address than the one set by SetExpectedAddresses
+	ErrUnexpectedAddress = errors.New("address does not match the expected address")
+)
+
+// VerificationError is returned if an address or a signature returned by the device
+// does not pass the host side verification
+type VerificationError struct {
+	// Index is the index of the address or signature in the device response
+	Index int
+	Err   error
+}
+
+func (e *VerificationError) Error() string {
+	return fmt.Sprintf("verification of item %d returned by the device failed: %v", e.Index, e.Err)
+}
+
+// Unwrap returns the verification error, e.g. cipher.ErrInvalidAddressForSig
+func (e *VerificationError) Unwrap() error {
+	return e.Err
+}
+
+// VerifyAddresses decodes the base58 addresses returned by the device, checking their checksum and version
+func VerifyAddresses(addresses []string) ([]cipher.Address, error) {
+	addrs := make([]cipher.Address, len(addresses))
+	for i, address := range addresses {
+		addr, err := cipher.DecodeBase58Address(address)
+		if err != nil {
+			return nil, &VerificationError{Index: i, Err: err}
+		}
+		addrs[i] = addr
+	}
+	return addrs, nil
+}
+
+// MessageHash returns the hash signed by the device for message,
+// a hex encoded SHA256 is signed as is, any other message is hashed first
+func MessageHash(message string) cipher.SHA256 {
+	if h, err := cipher.SHA256FromHex(message); err == nil {
+		return h
+	}
+	return cipher.SumSHA256([]byte(message))
+}
+
+// VerifyMessageSignature checks that signature is the signature of message by address
+func VerifyMessageSignature(address cipher.Address, message string, signature cipher.Sig) error {
+	if err := cipher.VerifyAddressSignedHash(address, signature, MessageHash(message)); err != nil {
+		return &VerificationError{Err: err}
+	}
+	return nil
+}
+
+// VerifyTransactionSignatures checks that signatures[i] is the signature of the input i
+// of the transaction by addresses[i], the address owning the spent output
+func VerifyTransactionSignatures(inputs []*messages.SkycoinTransactionInput, outputs []*messages.SkycoinTransactionOutput, addresses []cipher.Address, signatures []cipher.Sig) error {
+	if len(signatures) != len(inputs) || len(addresses) != len(inputs) {
+		return ErrSignatureCount
+	}
+
+	tx, err := transaction.FromMessages(inputs, outputs)
+	if err != nil {
+		return err
+	}
+
+	for i := range tx.In {
+		if err := cipher.VerifyAddressSignedHash(addresses[i], signatures[i], tx.SignedHash(i)); err != nil {
+			return &VerificationError{Index: i, Err: err}
+		}
+	}
+
+	return nil
+}
+
+// answerCheck verifies the answer to a request once the device stops asking for user input
+type answerCheck struct {
+	kind  messages.MessageType
+	check func(ctx context.Context, s *Session, msg wire.Message) error
+}
+
+// expectAnswer sets the verification of the answer to the request being sent if verification is enabled.
+// The check of a previous request not answered yet is dropped.
+func (s *Session) expectAnswer(kind messages.MessageType, check func(ctx context.Context, s *Session, msg wire.Message) error) {
+	s.device.pendingCheck = nil
+	if s.device.verify {
+		s.device.pendingCheck = &answerCheck{
+			kind:  kind,
+			check: check,
+		}
+	}
+}
+
+// checkAnswer runs the verification set by expectAnswer once msg ends the request,
+// the answer may come after several PinMatrixAck, PassphraseAck or ButtonAck
+func (s *Session) checkAnswer(ctx context.Context, msg wire.Message) error {
+	pending := s.device.pendingCheck
+	if pending == nil {
+		return nil
+	}
+
+	switch messages.MessageType(msg.Kind) {
+	case messages.MessageType_MessageType_ButtonRequest,
+		messages.MessageType_MessageType_PinMatrixRequest,
+		messages.MessageType_MessageType_PassphraseRequest:
+		return nil
+	}
+
+	// the check may send requests of its own
+	s.device.pendingCheck = nil
+	if msg.Kind != uint16(pending.kind) {
+		return nil
+	}
+	return pending.check(ctx, s, msg)
+}
+
+// expectedAddress returns the address at index set by SetExpectedAddresses, the device is asked for it
+// if the caller did not set it, which only detects the responses corrupted on their way to the host
+func (s *Session) expectedAddress(ctx context.Context, index uint32) (cipher.Address, error) {
+	if int(index) < len(s.device.expectedAddresses) {
+		return s.device.expectedAddresses[index], nil
+	}
+
+	addresses, err := s.GetAddressesContext(ctx, 1, index, false)
+	if err != nil {
+		return cipher.Address{}, err
+	}
+	return addresses[0], nil
+}
+
+// checkAddresses returns the check of the addresses answered to an AddressGen request
+func checkAddresses(addressN, startIndex uint32) func(ctx context.Context, s *Session, msg wire.Message) error {
+	return func(ctx context.Context, s *Session, msg wire.Message) error {
+		addrs, err := DecodeResponseSkycoinAddress(msg)
+		if err != nil {
+			return err
+		}
+		if len(addrs) != int(addressN) {
+			return fmt.Errorf("device returned %d addresses, %d were requested", len(addrs), addressN)
+		}
+
+		addresses, err := VerifyAddresses(addrs)
+		if err != nil {
+			return err
+		}
+
+		for i, address := range addresses {
+			index := int(startIndex) + i
+			if index < len(s.device.expectedAddresses) && address != s.device.expectedAddresses[index] {
+				return &VerificationError{Index: i, Err: ErrUnexpectedAddress}
+			}
+		}
+		return nil
+	}
+}
+
+// checkMessageSignature returns the check of the signature answered to a SignMessage request
+func checkMessageSignature(addressIndex int, message string) func(ctx context.Context, s *Session, msg wire.Message) error {
+	return func(ctx context.Context, s *Session, msg wire.Message) error {
+		signature, err := DecodeResponseSkycoinSignMessage(msg)
+		if err != nil {
+			return err
+		}
+		sig, err := cipher.SigFromHex(signature)
+		if err != nil {
+			return &VerificationError{Err: err}
+		}
+
+		address, err := s.expectedAddress(ctx, uint32(addressIndex))
+		if err != nil {
+			return err
+		}
+		return VerifyMessageSignature(address, message, sig)
+	}
+}
+
+// checkTransactionSignatures returns the check of the signatures answered to a TransactionSign request
+func checkTransactionSignatures(inputs []*messages.SkycoinTransactionInput, outputs []*messages.SkycoinTransactionOutput) func(ctx context.Context, s *Session, msg wire.Message) error {
+	return func(ctx context.Context, s *Session, msg wire.Message) error {
+		sigs, err := DecodeResponseTransactionSign(msg)
+		if err != nil {
+			return err
+		}
+		if len(sigs) != len(inputs) {
+			return ErrSignatureCount
+		}
+
+		signatures := make([]cipher.Sig, len(sigs))
+		addresses := make([]cipher.Address, len(inputs))
+		byIndex := make(map[uint32]cipher.Address)
+		for i, sig := range sigs {
+			signatures[i], err = cipher.SigFromHex(sig)
+			if err != nil {
+				return &VerificationError{Index: i, Err: err}
+			}
+
+			index := inputs[i].GetIndex()
+			address, ok := byIndex[index]
+			if !ok {
+				address, err = s.expectedAddress(ctx, index)
+				if err != nil {
+					return err
+				}
+				byIndex[index] = address
+			}
+			addresses[i] = address
+		}
+
+		return VerifyTransactionSignatures(inputs, outputs, addresses, signatures)
+	}
+}
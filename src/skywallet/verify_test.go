@@ -0,0 +1,79 @@
+package skywallet
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/cipher/base58"
+	"github.com/stretchr/testify/require"
+
+	messages "github.com/skycoin/hardware-wallet-protob/go"
+)
+
+func TestVerifyAddresses(t *testing.T) {
+	pubKey, _ := cipher.GenerateKeyPair()
+	address := cipher.AddressFromPubKey(pubKey)
+
+	addresses, err := VerifyAddresses([]string{address.String()})
+	require.NoError(t, err)
+	require.Equal(t, []cipher.Address{address}, addresses)
+
+	// corrupt the checksum of the second address
+	b := address.Bytes()
+	b[len(b)-1]++
+	_, err = VerifyAddresses([]string{address.String(), base58.Encode(b)})
+	require.True(t, errors.Is(err, cipher.ErrAddressInvalidChecksum))
+	var verr *VerificationError
+	require.True(t, errors.As(err, &verr))
+	require.Equal(t, 1, verr.Index)
+}
+
+func TestVerifyMessageSignature(t *testing.T) {
+	pubKey, secKey := cipher.GenerateKeyPair()
+	address := cipher.AddressFromPubKey(pubKey)
+
+	sig := cipher.MustSignHash(cipher.SumSHA256([]byte("Hello World")), secKey)
+	require.NoError(t, VerifyMessageSignature(address, "Hello World", sig))
+
+	err := VerifyMessageSignature(address, "Hello World!", sig)
+	require.True(t, errors.Is(err, cipher.ErrInvalidAddressForSig))
+
+	// hex encoded hashes are signed as is
+	hash := cipher.SumSHA256([]byte("hash"))
+	sig = cipher.MustSignHash(hash, secKey)
+	require.NoError(t, VerifyMessageSignature(address, hash.Hex(), sig))
+}
+
+func TestVerifyTransactionSignatures(t *testing.T) {
+	pubKey, secKey := cipher.GenerateKeyPair()
+	address := cipher.AddressFromPubKey(pubKey)
+
+	inputs := []*messages.SkycoinTransactionInput{
+		{
+			HashIn: proto.String("181bd5656115172fe81451fae4fb56498a97744d89702e73da75ba91ed5200f9"),
+			Index:  proto.Uint32(0),
+		},
+	}
+	outputs := []*messages.SkycoinTransactionOutput{
+		{
+			Address: proto.String("K9TzLrgqz7uXn3QJHGxmzdRByAzH33J2ot"),
+			Coin:    proto.Uint64(100000),
+			Hour:    proto.Uint64(2),
+		},
+	}
+
+	// hash signed for the first input, see the transaction package tests
+	hash := cipher.MustSHA256FromHex("d11c62b1e0e9abf629b1f5f4699cef9fbc504b45ceedf0047ead686979498218")
+	sigs := []cipher.Sig{cipher.MustSignHash(hash, secKey)}
+	require.NoError(t, VerifyTransactionSignatures(inputs, outputs, []cipher.Address{address}, sigs))
+
+	require.Equal(t, ErrSignatureCount, VerifyTransactionSignatures(inputs, outputs, []cipher.Address{address}, nil))
+
+	outputs[0].Coin = proto.Uint64(200000)
+	err := VerifyTransactionSignatures(inputs, outputs, []cipher.Address{address}, sigs)
+	var verr *VerificationError
+	require.True(t, errors.As(err, &verr))
+	require.Equal(t, 0, verr.Index)
+}
@@ -0,0 +1,248 @@
+package skywallet
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	messages "github.com/skycoin/hardware-wallet-protob/go"
+
+	"github.com/skycoin/hardware-wallet-go/src/skywallet/usb"
+)
+
+const defaultWatchInterval = 500 * time.Millisecond
+
+// DeviceEventType is the kind of a DeviceEvent
+type DeviceEventType int
+
+const (
+	// DeviceArrived a wallet was plugged in
+	DeviceArrived DeviceEventType = iota
+	// DeviceRemoved a wallet was removed
+	DeviceRemoved
+)
+
+func (t DeviceEventType) String() string {
+	switch t {
+	case DeviceArrived:
+		return "ARRIVED"
+	case DeviceRemoved:
+		return "REMOVED"
+	default:
+		return "INVALID"
+	}
+}
+
+// DeviceEvent reports a wallet being plugged in or removed
+type DeviceEvent struct {
+	Type DeviceEventType
+	Info usb.Info
+	// Mode of an arrived wallet, from its features if fetched
+	Mode DeviceMode
+	// Features of an arrived wallet if WatchOptions.Features is set,
+	// nil if they could not be fetched
+	Features *messages.Features
+}
+
+// WatchOptions configure Driver.Watch
+type WatchOptions struct {
+	// Interval between two enumerations of the attached wallets, defaults to 500ms
+	Interval time.Duration
+	// Features fetches the features of the arrived wallets. The wallets connected
+	// through the driver, e.g. by an open Session, are not probed and have no features.
+	Features bool
+}
+
+// Watch emits an event each time a wallet is plugged in or removed until ctx is done,
+// then the returned channel is closed. Wallets attached when Watch is called are
+// reported as arrived. The attached wallets are found by polling the bus: the lowlevel
+// libusb package defines the hotplug constants but does not bind the libusb hotplug
+// callbacks, and the hidapi, UDP and emulator buses have no hotplug notifications.
+func (drv *Driver) Watch(ctx context.Context, options WatchOptions) <-chan DeviceEvent {
+	interval := options.Interval
+	if interval <= 0 {
+		interval = defaultWatchInterval
+	}
+
+	events := make(chan DeviceEvent)
+	go func() {
+		defer close(events)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		attached := make(map[string]usb.Info)
+		for {
+			infos, err := drv.enumerate()
+			if err != nil {
+				log.Errorf("failed to enumerate devices: %v", err)
+			}
+
+			if err == nil {
+				for _, event := range drv.changes(attached, infos, options.Features) {
+					select {
+					case events <- event:
+					case <-ctx.Done():
+						return
+					}
+				}
+			}
+
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return events
+}
+
+// changes updates attached with infos and returns the events describing the update
+func (drv *Driver) changes(attached map[string]usb.Info, infos []usb.Info, withFeatures bool) []DeviceEvent {
+	var events []DeviceEvent
+
+	present := make(map[string]bool, len(infos))
+	for _, info := range infos {
+		present[info.Path] = true
+	}
+
+	var removed []string
+	for path := range attached {
+		if !present[path] {
+			removed = append(removed, path)
+		}
+	}
+	sort.Strings(removed)
+	for _, path := range removed {
+		events = append(events, DeviceEvent{
+			Type: DeviceRemoved,
+			Info: attached[path],
+		})
+		delete(attached, path)
+	}
+
+	for _, info := range infos {
+		if _, ok := attached[info.Path]; ok {
+			continue
+		}
+		attached[info.Path] = info
+
+		event := DeviceEvent{
+			Type: DeviceArrived,
+			Info: info,
+		}
+		if withFeatures {
+			event.Features = drv.features(info.Path)
+		}
+		event.Mode = deviceMode(info, event.Features)
+		events = append(events, event)
+	}
+
+	return events
+}
+
+// features returns the features of the wallet at path, nil if they could not be fetched
+// or if the wallet is connected through the driver
+func (drv *Driver) features(path string) *messages.Features {
+	if !drv.connections.startProbe(path) {
+		log.Debugf("device %s is in use, its features are not fetched", path)
+		return nil
+	}
+	defer drv.connections.endProbe(path)
+
+	dev, err := drv.bus.Connect(path)
+	if err != nil {
+		log.Errorf("failed to connect to device %s: %v", path, err)
+		return nil
+	}
+
+	defer func() {
+		if err := dev.Close(false); err != nil {
+			log.Errorf("failed to close device %s: %v", path, err)
+		}
+	}()
+
+	features, err := getFeatures(dev, drv.entropySource)
+	if err != nil {
+		log.Errorf("failed to get features of device %s: %v", path, err)
+		return nil
+	}
+
+	return features
+}
+
+// connections counts the open connections of a driver by wallet path, so that
+// Watch does not talk to a wallet while a Session does and the reverse
+type connections struct {
+	mu sync.Mutex
+	// probeDone is signaled when a probe ends
+	probeDone *sync.Cond
+	open      map[string]int
+	probing   map[string]bool
+}
+
+func newConnections() *connections {
+	c := &connections{
+		open:    make(map[string]int),
+		probing: make(map[string]bool),
+	}
+	c.probeDone = sync.NewCond(&c.mu)
+	return c
+}
+
+// add registers a connection to the wallet at path, once its probe by Watch is over
+func (c *connections) add(path string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for c.probing[path] {
+		c.probeDone.Wait()
+	}
+	c.open[path]++
+}
+
+// remove unregisters a connection to the wallet at path
+func (c *connections) remove(path string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.open[path]--
+	if c.open[path] <= 0 {
+		delete(c.open, path)
+	}
+}
+
+// startProbe registers a probe of the wallet at path, false if it is connected
+func (c *connections) startProbe(path string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.open[path] > 0 || c.probing[path] {
+		return false
+	}
+	c.probing[path] = true
+	return true
+}
+
+// endProbe unregisters the probe of the wallet at path
+func (c *connections) endProbe(path string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.probing, path)
+	c.probeDone.Broadcast()
+}
+
+// connection is a device connection registered in connections until closed
+type connection struct {
+	usb.Device
+	connections *connections
+	path        string
+	closeOnce   sync.Once
+}
+
+func (c *connection) Close(disconnected bool) error {
+	c.closeOnce.Do(func() {
+		c.connections.remove(c.path)
+	})
+	return c.Device.Close(disconnected)
+}